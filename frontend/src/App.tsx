@@ -11,6 +11,8 @@ import {
 import { AnimatePresence, motion } from "framer-motion";
 import { Check, Copy, Loader2, AlertTriangle } from "lucide-react";
 import { shortenUrl as shortenUrlApi } from "@/api/urlApi";
+import { LinkList } from "@/components/LinkList";
+import { addRecentLink, getRecentLinks } from "@/lib/recentLinks";
 
 function App() {
 	const [longUrl, setLongUrl] = useState("");
@@ -18,6 +20,7 @@ function App() {
 	const [loading, setLoading] = useState(false);
 	const [copied, setCopied] = useState(false);
 	const [error, setError] = useState<string | null>(null);
+	const [recentLinks, setRecentLinks] = useState<string[]>(getRecentLinks);
 
 	const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
 		e.preventDefault();
@@ -30,6 +33,9 @@ function App() {
 			if (data.short_url) {
 				setShortUrl(data.short_url);
 			}
+			if (data.short_code) {
+				setRecentLinks(addRecentLink(data.short_code));
+			}
 		} catch (err: unknown) {
 			if (err instanceof Error) {
 				setError(err.message || "An unexpected error occurred.");
@@ -180,6 +186,9 @@ function App() {
 						</motion.div>
 					)}
 				</AnimatePresence>
+				<div className="mt-8">
+					<LinkList shortCodes={recentLinks} />
+				</div>
 			</div>
 		</main>
 	);
@@ -1,4 +1,5 @@
 import type {
+	LinkDetails,
 	ShortenUrlRequest,
 	ShortenUrlResponse,
 } from "@/types/api";
@@ -24,4 +25,18 @@ export const shortenUrl = async (
 	}
 
 	return data;
-}; 
\ No newline at end of file
+};
+
+export const getLinkDetails = async (shortCode: string): Promise<LinkDetails> => {
+	const response = await fetch(
+		`${API_BASE_URL}/links/${encodeURIComponent(shortCode)}`,
+	);
+
+	const data = await response.json();
+
+	if (!response.ok || data.error) {
+		throw new Error(data.error || "Could not load link details.");
+	}
+
+	return data;
+};
@@ -0,0 +1,107 @@
+import { useEffect, useState } from "react";
+import { ExternalLink, Globe } from "lucide-react";
+import { getLinkDetails } from "@/api/urlApi";
+import type { LinkDetails } from "@/types/api";
+
+// Metadata is fetched in the background after a link is created, so links
+// without it yet are polled a few more times.
+const RETRY_DELAY_MS = 3000;
+const MAX_RETRIES = 5;
+
+function LinkCard({ shortCode }: { shortCode: string }) {
+	const [link, setLink] = useState<LinkDetails | null>(null);
+	const [iconFailed, setIconFailed] = useState(false);
+
+	useEffect(() => {
+		let cancelled = false;
+		let timer: ReturnType<typeof setTimeout>;
+
+		const load = async (attempt: number) => {
+			try {
+				const details = await getLinkDetails(shortCode);
+				if (cancelled) return;
+				setLink(details);
+				if (!details.metadata_fetched_at && attempt < MAX_RETRIES) {
+					timer = setTimeout(() => load(attempt + 1), RETRY_DELAY_MS);
+				}
+			} catch {
+				// Deleted or unreachable links simply stay hidden.
+			}
+		};
+		load(0);
+
+		return () => {
+			cancelled = true;
+			clearTimeout(timer);
+		};
+	}, [shortCode]);
+
+	if (!link) return null;
+
+	const meta = link.metadata;
+	const title = link.title || meta?.og_title || meta?.title || link.original_url;
+	const description = meta?.og_description || meta?.twitter_description || meta?.description;
+	const image = meta?.og_image || meta?.twitter_image;
+
+	return (
+		<li className="flex gap-3 rounded-md border border-slate-700 bg-slate-800 p-3">
+			{image && (
+				<img
+					src={image}
+					alt=""
+					loading="lazy"
+					referrerPolicy="no-referrer"
+					className="h-16 w-16 flex-shrink-0 rounded object-cover"
+				/>
+			)}
+			<div className="min-w-0 flex-1 space-y-1">
+				<div className="flex items-center gap-2">
+					{meta?.favicon_url && !iconFailed ? (
+						<img
+							src={meta.favicon_url}
+							alt=""
+							referrerPolicy="no-referrer"
+							onError={() => setIconFailed(true)}
+							className="h-4 w-4 flex-shrink-0"
+						/>
+					) : (
+						<Globe className="h-4 w-4 flex-shrink-0 text-slate-500" />
+					)}
+					<p className="truncate font-semibold">{title}</p>
+				</div>
+				{description && (
+					<p className="line-clamp-2 text-sm text-slate-400">{description}</p>
+				)}
+				<a
+					href={link.short_url}
+					target="_blank"
+					rel="noopener noreferrer"
+					className="inline-flex items-center gap-1 font-mono text-sm text-indigo-400 hover:underline"
+				>
+					{link.short_url}
+					<ExternalLink className="h-3 w-3" />
+				</a>
+				{link.status === "disabled" && (
+					<p className="text-xs text-red-400">This link has been disabled.</p>
+				)}
+			</div>
+		</li>
+	);
+}
+
+export function LinkList({ shortCodes }: { shortCodes: string[] }) {
+	if (shortCodes.length === 0) return null;
+
+	return (
+		<section className="space-y-3">
+			<h2 className="text-sm font-semibold uppercase tracking-wide text-slate-400">
+				Your recent links
+			</h2>
+			<ul className="space-y-2">
+				{shortCodes.map((code) => (
+					<LinkCard key={code} shortCode={code} />
+				))}
+			</ul>
+		</section>
+	);
+}
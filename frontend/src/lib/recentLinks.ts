@@ -0,0 +1,19 @@
+// Links created in this browser are remembered locally so the link list can
+// show them without an account.
+const STORAGE_KEY = "veritas.recentLinks";
+const MAX_LINKS = 20;
+
+export const getRecentLinks = (): string[] => {
+	try {
+		const stored = JSON.parse(localStorage.getItem(STORAGE_KEY) ?? "[]");
+		return Array.isArray(stored) ? stored.filter((c) => typeof c === "string") : [];
+	} catch {
+		return [];
+	}
+};
+
+export const addRecentLink = (shortCode: string): string[] => {
+	const links = [shortCode, ...getRecentLinks().filter((c) => c !== shortCode)].slice(0, MAX_LINKS);
+	localStorage.setItem(STORAGE_KEY, JSON.stringify(links));
+	return links;
+};
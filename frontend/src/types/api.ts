@@ -1,9 +1,50 @@
 export interface ShortenUrlRequest {
   original_url: string;
+  interstitial?: boolean;
+  title?: string;
+  workspace?: string;
+  domain?: string;
 }
 
 export interface ShortenUrlResponse {
   short_url: string;
+  short_code: string;
+}
+
+export interface LinkMetadata {
+  content_type?: string;
+  title?: string;
+  description?: string;
+  favicon_url?: string;
+  og_title?: string;
+  og_description?: string;
+  og_image?: string;
+  og_site_name?: string;
+  twitter_title?: string;
+  twitter_description?: string;
+  twitter_image?: string;
+}
+
+export interface LinkPreview {
+  title?: string;
+  description?: string;
+  image?: string;
+}
+
+export interface LinkDetails {
+  domain?: string;
+  workspace?: string;
+  short_code: string;
+  short_url: string;
+  original_url: string;
+  title?: string;
+  interstitial: boolean;
+  status: "active" | "disabled";
+  created_at: string;
+  preview?: LinkPreview;
+  metadata?: LinkMetadata;
+  metadata_fetched_at?: string;
+  metadata_error?: string;
 }
 
 export interface ErrorResponse {
@@ -0,0 +1,323 @@
+// Package analytics turns the redirector's click events into the numbers
+// the stats API reports: clicks are stored in Postgres and rolled up into
+// minute, hour and day buckets the stats are read from, and unique visitors
+// are counted with HyperLogLog sketches in Redis.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+)
+
+// ErrNoLink is returned for events that do not name their link, which
+// redirectors older than the analytics service publish.
+var ErrNoLink = errors.New("event has no link ID")
+
+// ErrLate is returned for clicks recorded after the buckets they fall in
+// were rolled up for the last time.
+var ErrLate = errors.New("click is older than the rollup lateness")
+
+// ErrDuplicate is returned for clicks already stored, delivered again.
+var ErrDuplicate = errors.New("click was already recorded")
+
+// ErrNotKept is returned for reports whose range is finer than the rollups
+// kept for it, such as minutes older than the minute rollups.
+var ErrNotKept = errors.New("no statistics are kept at the granularity of the range")
+
+// Dimensions clicks can be broken down by: what their User-Agent says
+// about the client, and where they came from.
+const (
+	DimensionBrowser        = "browser"
+	DimensionBrowserVersion = "browser_version"
+	DimensionOS             = "os"
+	DimensionDeviceType     = "device_type"
+	DimensionBot            = "bot"
+	DimensionReferrer       = "referrer"
+	DimensionSource         = "source"
+	DimensionMedium         = "medium"
+	DimensionSourceMedium   = "source_medium"
+	DimensionCampaign       = "campaign"
+	DimensionChannel        = "channel"
+)
+
+// Dimensions lists the dimensions clicks can be broken down by.
+var Dimensions = []string{
+	DimensionBrowser, DimensionBrowserVersion, DimensionOS, DimensionDeviceType, DimensionBot,
+	DimensionReferrer, DimensionSource, DimensionMedium, DimensionSourceMedium, DimensionCampaign, DimensionChannel,
+}
+
+// Unknown is reported for the dimensions of clicks recorded before they
+// were worked out.
+const Unknown = "unknown"
+
+// Recorder stores click events.
+type Recorder struct {
+	querier  sqlc.Querier
+	visitors *Visitors
+	parser   *UserAgentParser
+	lateness time.Duration
+}
+
+// NewRecorder returns a Recorder storing clicks with querier, classifying
+// their User-Agent with parser and counting their visitors in visitors.
+// Clicks that happened more than lateness ago are dropped.
+func NewRecorder(querier sqlc.Querier, visitors *Visitors, parser *UserAgentParser, lateness time.Duration) *Recorder {
+	return &Recorder{querier: querier, visitors: visitors, parser: parser, lateness: lateness}
+}
+
+// Record stores a click and returns it as streamed live. Bots are not
+// counted as visitors. A click already stored, which the redirector's spool
+// or NATS delivered again, is reported with ErrDuplicate and not counted.
+func (r *Recorder) Record(ctx context.Context, ev *eventsv1.RedirectEvent) (Click, error) {
+	if ev.LinkId == 0 {
+		return Click{}, ErrNoLink
+	}
+	click, ua := r.describe(ev)
+	if time.Since(click.At) > r.lateness {
+		return Click{}, ErrLate
+	}
+
+	n, err := r.querier.CreateClick(ctx, sqlc.CreateClickParams{
+		UrlID:          ev.LinkId,
+		WorkspaceID:    pgtype.Int8{Int64: ev.WorkspaceId, Valid: ev.WorkspaceId != 0},
+		ClickedAt:      click.At,
+		IsBot:          ua.IsBot(),
+		RequestID:      pgtype.Text{String: ev.RequestId, Valid: ev.RequestId != ""},
+		Browser:        ua.Browser,
+		BrowserVersion: ua.BrowserVersion,
+		Os:             ua.OS,
+		DeviceType:     ua.DeviceType,
+		Bot:            ua.Bot,
+		Referrer:       click.Referrer,
+		Source:         click.Source,
+		Medium:         click.Medium,
+		Campaign:       click.Campaign,
+		Channel:        click.Channel,
+	})
+	if err != nil {
+		return Click{}, err
+	}
+	if n == 0 {
+		return Click{}, ErrDuplicate
+	}
+	if ua.IsBot() {
+		return click, nil
+	}
+	return click, r.visitors.Add(ctx, ev.LinkId, click.At, ev.IpAddress, ev.UserAgent)
+}
+
+// Click returns the click of an event recorded by Record, as streamed live.
+func (r *Recorder) Click(ev *eventsv1.RedirectEvent) Click {
+	click, _ := r.describe(ev)
+	return click
+}
+
+// describe works out the click of ev and what its User-Agent says.
+func (r *Recorder) describe(ev *eventsv1.RedirectEvent) (Click, UserAgent) {
+	at := time.Now()
+	if ev.OccurredAt != nil {
+		at = ev.OccurredAt.AsTime()
+	}
+	ua := r.parser.Parse(ev.UserAgent)
+	// The redirector knows link preview fetchers our rules may not.
+	if ev.IsBot && !ua.IsBot() {
+		ua.Bot, ua.DeviceType = Other, DeviceBot
+	}
+	source := Attribute(ev.Referer, ev.Utm, ev.OriginalUrl)
+
+	return Click{
+		LinkID:      ev.LinkId,
+		WorkspaceID: ev.WorkspaceId,
+		ShortCode:   ev.ShortCode,
+		Domain:      ev.Domain,
+		At:          at.UTC(),
+		Country:     valueOr(ev.Country, Unknown),
+		Browser:     ua.Browser,
+		OS:          ua.OS,
+		DeviceType:  ua.DeviceType,
+		Bot:         ua.Bot,
+		Referrer:    source.Referrer,
+		Source:      source.Source,
+		Medium:      source.Medium,
+		Campaign:    source.Campaign,
+		Channel:     source.Channel,
+	}, ua
+}
+
+// Query selects the clicks a report covers: those of a link, or of all the
+// links of a workspace.
+type Query struct {
+	LinkID      int64
+	WorkspaceID int64
+	// From and To bound the report, From included and To excluded.
+	From, To time.Time
+	// ExcludeBots leaves out the clicks of bots, including link preview
+	// fetchers. Unique visitors never include bots.
+	ExcludeBots bool
+}
+
+// Summary is the clicks and unique visitors of a link over a range.
+type Summary struct {
+	// Granularity is that of the rollups the clicks were read from.
+	Granularity Granularity `json:"granularity"`
+	Clicks      int64       `json:"clicks"`
+	// UniqueVisitors counts a visitor once per day they clicked, over the
+	// whole UTC days the range touches.
+	UniqueVisitors int64        `json:"unique_visitors"`
+	Days           []DaySummary `json:"days"`
+}
+
+// DaySummary is the clicks and unique visitors of a link on one UTC day.
+type DaySummary struct {
+	Date           string `json:"date"`
+	Clicks         int64  `json:"clicks"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
+// Reporter reads the statistics of links from the click rollups, so they
+// lag the clicks by up to the rollup interval.
+type Reporter struct {
+	querier   sqlc.Querier
+	visitors  *Visitors
+	retention Retention
+}
+
+// NewReporter returns a Reporter reading what a Recorder with the same
+// querier and visitors stored, once rolled up by Rollups keeping them for
+// retention.
+func NewReporter(querier sqlc.Querier, visitors *Visitors, retention Retention) *Reporter {
+	return &Reporter{querier: querier, visitors: visitors, retention: retention}
+}
+
+// Granularity returns the coarsest granularity whose buckets start and end
+// the range of q and are still kept for its start.
+func (r *Reporter) Granularity(q Query) (Granularity, error) {
+	for _, g := range slices.Backward(granularities) {
+		if !g.Truncate(q.From).Equal(q.From) || !g.Truncate(q.To).Equal(q.To) {
+			continue
+		}
+		if retention := r.retention.Of(g); retention > 0 && time.Since(q.From) > retention {
+			continue
+		}
+		return g, nil
+	}
+	return "", ErrNotKept
+}
+
+// Link summarizes the clicks of q.LinkID in the range of q.
+func (r *Reporter) Link(ctx context.Context, q Query) (Summary, error) {
+	g, err := r.Granularity(q)
+	if err != nil {
+		return Summary{}, err
+	}
+	if !q.From.Before(q.To) {
+		return Summary{Granularity: g, Days: []DaySummary{}}, nil
+	}
+	linkID := q.LinkID
+
+	rows, err := r.querier.ListClickTotals(ctx, sqlc.ListClickTotalsParams{
+		Granularity: string(g),
+		UrlID:       linkID,
+		FromTime:    q.From,
+		ToTime:      q.To,
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+	clicks := make(map[time.Time]int64)
+	for _, row := range rows {
+		n := row.Clicks
+		if q.ExcludeBots {
+			n -= row.BotClicks
+		}
+		clicks[Day(row.Bucket)] += n
+	}
+
+	days := Days(q.From, q.To.Add(-time.Nanosecond))
+	daily, err := r.visitors.CountByDay(ctx, linkID, days)
+	if err != nil {
+		return Summary{}, err
+	}
+	unique, err := r.visitors.Count(ctx, linkID, days)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	s := Summary{Granularity: g, UniqueVisitors: unique, Days: make([]DaySummary, len(days))}
+	for i, d := range days {
+		s.Days[i] = DaySummary{Date: d.Format(time.DateOnly), Clicks: clicks[d], UniqueVisitors: daily[i]}
+		s.Clicks += clicks[d]
+	}
+	return s, nil
+}
+
+// Count is the clicks with one value of a dimension.
+type Count struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Breakdown returns the limit values of dimension with the most clicks in
+// the range of q, from the link of q or, without one, from the links of its
+// workspace. Breakdowns by DimensionBot only count bots.
+func (r *Reporter) Breakdown(ctx context.Context, q Query, dimension string, limit int) ([]Count, error) {
+	if !slices.Contains(Dimensions, dimension) {
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+	g, err := r.Granularity(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []Count
+	if q.LinkID != 0 {
+		rows, err := r.querier.SumClicksByValue(ctx, sqlc.SumClicksByValueParams{
+			ExcludeBots: q.ExcludeBots,
+			Granularity: string(g),
+			UrlID:       q.LinkID,
+			Dimension:   dimension,
+			FromTime:    q.From,
+			ToTime:      q.To,
+			MaxCount:    int32(limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts = append(counts, Count{Value: row.Value, Clicks: row.Clicks})
+		}
+	} else {
+		rows, err := r.querier.SumWorkspaceClicksByValue(ctx, sqlc.SumWorkspaceClicksByValueParams{
+			ExcludeBots: q.ExcludeBots,
+			Granularity: string(g),
+			WorkspaceID: pgtype.Int8{Int64: q.WorkspaceID, Valid: true},
+			Dimension:   dimension,
+			FromTime:    q.From,
+			ToTime:      q.To,
+			MaxCount:    int32(limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts = append(counts, Count{Value: row.Value, Clicks: row.Clicks})
+		}
+	}
+
+	for i := range counts {
+		if counts[i].Value == "" {
+			counts[i].Value = Unknown
+		}
+	}
+	if counts == nil {
+		counts = []Count{}
+	}
+	return counts, nil
+}
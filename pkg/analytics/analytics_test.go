@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestVisitorHash(t *testing.T) {
+	salt := []byte("salt of today")
+	hash := VisitorHash(salt, "203.0.113.7", "Mozilla/5.0")
+
+	testCases := []struct {
+		name      string
+		salt      []byte
+		ip        string
+		userAgent string
+		same      bool
+	}{
+		{name: "Test the same visitor", salt: salt, ip: "203.0.113.7", userAgent: "Mozilla/5.0", same: true},
+		{name: "Test another day", salt: []byte("salt of tomorrow"), ip: "203.0.113.7", userAgent: "Mozilla/5.0"},
+		{name: "Test another IP address", salt: salt, ip: "203.0.113.8", userAgent: "Mozilla/5.0"},
+		{name: "Test another User-Agent", salt: salt, ip: "203.0.113.7", userAgent: "curl/8.0"},
+		{name: "Test a shifted separator", salt: salt, ip: "203.0.113.7M", userAgent: "ozilla/5.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := VisitorHash(tc.salt, tc.ip, tc.userAgent)
+			assert.Len(t, got, 32)
+			assert.NotContains(t, got, "203.0.113")
+			assert.Equal(t, tc.same, got == hash)
+		})
+	}
+}
+
+func TestDays(t *testing.T) {
+	from := time.Date(2026, 2, 27, 18, 30, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	var got []string
+	for _, d := range Days(from, to) {
+		got = append(got, d.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-02"}, got)
+	assert.Empty(t, Days(to, from))
+}
+
+// fakeQuerier records the clicks it is asked to store, once per request ID
+// and time like the clicks table.
+type fakeQuerier struct {
+	sqlc.Querier
+	clicks []sqlc.CreateClickParams
+}
+
+func (q *fakeQuerier) CreateClick(_ context.Context, arg sqlc.CreateClickParams) (int64, error) {
+	for _, c := range q.clicks {
+		if arg.RequestID.Valid && c.RequestID == arg.RequestID && c.ClickedAt.Equal(arg.ClickedAt) {
+			return 0, nil
+		}
+	}
+	q.clicks = append(q.clicks, arg)
+	return 1, nil
+}
+
+func TestRecord(t *testing.T) {
+	q := &fakeQuerier{}
+	// Bots are not counted as visitors, so no Redis is needed here.
+	r := NewRecorder(q, nil, DefaultUserAgentParser(), time.Hour)
+	at := time.Now().UTC().Add(-time.Minute)
+
+	click, err := r.Record(context.Background(), &eventsv1.RedirectEvent{LinkId: 42, WorkspaceId: 7, ShortCode: "abc", IsBot: true, RequestId: "req-1", Country: "FR", OccurredAt: timestamppb.New(at)})
+	require.NoError(t, err)
+	assert.Equal(t, Click{LinkID: 42, WorkspaceID: 7, ShortCode: "abc", At: at, Country: "FR", Browser: Other, OS: Other, DeviceType: DeviceBot,
+		Bot: Other, Referrer: SourceDirect, Source: SourceDirect, Medium: None, Campaign: None, Channel: ChannelDirect}, click)
+	require.Len(t, q.clicks, 1)
+	assert.Equal(t, int64(42), q.clicks[0].UrlID)
+	assert.Equal(t, int64(7), q.clicks[0].WorkspaceID.Int64)
+	assert.True(t, q.clicks[0].IsBot)
+	assert.Equal(t, Other, q.clicks[0].Bot)
+	assert.Equal(t, at, q.clicks[0].ClickedAt)
+
+	// The same click delivered again is not stored twice.
+	_, err = r.Record(context.Background(), &eventsv1.RedirectEvent{LinkId: 42, WorkspaceId: 7, ShortCode: "abc", IsBot: true, RequestId: "req-1", Country: "FR", OccurredAt: timestamppb.New(at)})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	require.Len(t, q.clicks, 1)
+
+	// Bots the redirector let through are recognized by their User-Agent.
+	_, err = r.Record(context.Background(), &eventsv1.RedirectEvent{LinkId: 42, UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"})
+	require.NoError(t, err)
+	require.Len(t, q.clicks, 2)
+	assert.True(t, q.clicks[1].IsBot)
+	assert.Equal(t, "Googlebot", q.clicks[1].Bot)
+	assert.Equal(t, DeviceBot, q.clicks[1].DeviceType)
+
+	_, err = r.Record(context.Background(), &eventsv1.RedirectEvent{ShortCode: "abc"})
+	assert.ErrorIs(t, err, ErrNoLink)
+	assert.Len(t, q.clicks, 2)
+
+	// Clicks older than the lateness would never be rolled up.
+	_, err = r.Record(context.Background(), &eventsv1.RedirectEvent{LinkId: 42, IsBot: true, OccurredAt: timestamppb.New(at.Add(-2 * time.Hour))})
+	assert.ErrorIs(t, err, ErrLate)
+	assert.Len(t, q.clicks, 2)
+}
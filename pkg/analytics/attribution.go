@@ -0,0 +1,271 @@
+package analytics
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Channels a click can be attributed to.
+const (
+	ChannelDirect   = "direct"
+	ChannelSearch   = "search"
+	ChannelSocial   = "social"
+	ChannelEmail    = "email"
+	ChannelReferral = "referral"
+	ChannelOther    = "other"
+)
+
+// Values reported for clicks without a referrer or UTM parameters, named
+// the way web analytics tools usually name them.
+const (
+	SourceDirect = "(direct)"
+	NotSet       = "(not set)"
+	None         = "(none)"
+)
+
+// UTM parameter names.
+const (
+	UTMSource   = "utm_source"
+	UTMMedium   = "utm_medium"
+	UTMCampaign = "utm_campaign"
+	UTMTerm     = "utm_term"
+	UTMContent  = "utm_content"
+)
+
+var utmParams = []string{UTMSource, UTMMedium, UTMCampaign, UTMTerm, UTMContent}
+
+// UTM returns the UTM parameters in query, or nil if it has none.
+func UTM(query url.Values) map[string]string {
+	var utm map[string]string
+	for _, name := range utmParams {
+		if v := strings.TrimSpace(query.Get(name)); v != "" {
+			if utm == nil {
+				utm = make(map[string]string, len(utmParams))
+			}
+			utm[name] = v
+		}
+	}
+	return utm
+}
+
+// Attribution is where a click came from.
+type Attribution struct {
+	// Referrer is the domain of the site the click came from.
+	Referrer string
+	Source   string
+	Medium   string
+	Campaign string
+	Channel  string
+}
+
+// sourceAliases maps hosts to the site they belong to: URL shorteners,
+// mobile apps that send android-app:// referrers, and alternative domains.
+var sourceAliases = map[string]string{
+	"t.co":                         "x.com",
+	"twitter.com":                  "x.com",
+	"fb.me":                        "facebook.com",
+	"lnkd.in":                      "linkedin.com",
+	"youtu.be":                     "youtube.com",
+	"redd.it":                      "reddit.com",
+	"com.google.android.gm":        "mail.google.com",
+	"com.google.android.gm.lite":   "mail.google.com",
+	"com.microsoft.office.outlook": "outlook.live.com",
+	"com.slack":                    "slack.com",
+	"com.linkedin.android":         "linkedin.com",
+	"com.reddit.frontpage":         "reddit.com",
+	"org.telegram.messenger":       "t.me",
+	"com.google.android.googlequicksearchbox": "google.com",
+}
+
+// sourceChannels are the channels of well-known sites. A host also belongs
+// to the channel of its parent domains.
+var sourceChannels = map[string]string{
+	"google.com":            ChannelSearch,
+	"bing.com":              ChannelSearch,
+	"duckduckgo.com":        ChannelSearch,
+	"yahoo.com":             ChannelSearch,
+	"yandex.com":            ChannelSearch,
+	"yandex.ru":             ChannelSearch,
+	"baidu.com":             ChannelSearch,
+	"ecosia.org":            ChannelSearch,
+	"search.brave.com":      ChannelSearch,
+	"startpage.com":         ChannelSearch,
+	"facebook.com":          ChannelSocial,
+	"instagram.com":         ChannelSocial,
+	"x.com":                 ChannelSocial,
+	"linkedin.com":          ChannelSocial,
+	"reddit.com":            ChannelSocial,
+	"youtube.com":           ChannelSocial,
+	"tiktok.com":            ChannelSocial,
+	"pinterest.com":         ChannelSocial,
+	"threads.net":           ChannelSocial,
+	"bsky.app":              ChannelSocial,
+	"mastodon.social":       ChannelSocial,
+	"news.ycombinator.com":  ChannelSocial,
+	"quora.com":             ChannelSocial,
+	"discord.com":           ChannelSocial,
+	"slack.com":             ChannelSocial,
+	"t.me":                  ChannelSocial,
+	"whatsapp.com":          ChannelSocial,
+	"mail.google.com":       ChannelEmail,
+	"outlook.live.com":      ChannelEmail,
+	"outlook.office.com":    ChannelEmail,
+	"outlook.office365.com": ChannelEmail,
+	"mail.yahoo.com":        ChannelEmail,
+	"mail.proton.me":        ChannelEmail,
+	"app.fastmail.com":      ChannelEmail,
+}
+
+// sourceNames are the channels of utm_source values that name a site or
+// channel rather than a domain.
+var sourceNames = map[string]string{
+	"google":     ChannelSearch,
+	"bing":       ChannelSearch,
+	"duckduckgo": ChannelSearch,
+	"facebook":   ChannelSocial,
+	"fb":         ChannelSocial,
+	"instagram":  ChannelSocial,
+	"ig":         ChannelSocial,
+	"twitter":    ChannelSocial,
+	"x":          ChannelSocial,
+	"linkedin":   ChannelSocial,
+	"reddit":     ChannelSocial,
+	"youtube":    ChannelSocial,
+	"tiktok":     ChannelSocial,
+	"mastodon":   ChannelSocial,
+	"bluesky":    ChannelSocial,
+	"newsletter": ChannelEmail,
+	"email":      ChannelEmail,
+	"mailchimp":  ChannelEmail,
+}
+
+// mediumChannels are the channels of common utm_medium values.
+var mediumChannels = map[string]string{
+	"email":          ChannelEmail,
+	"e-mail":         ChannelEmail,
+	"e_mail":         ChannelEmail,
+	"newsletter":     ChannelEmail,
+	"social":         ChannelSocial,
+	"social-media":   ChannelSocial,
+	"social_media":   ChannelSocial,
+	"social-network": ChannelSocial,
+	"sm":             ChannelSocial,
+	"organic_social": ChannelSocial,
+	"paid_social":    ChannelSocial,
+	"cpc":            ChannelSearch,
+	"ppc":            ChannelSearch,
+	"paidsearch":     ChannelSearch,
+	"paid_search":    ChannelSearch,
+	"organic":        ChannelSearch,
+	"search":         ChannelSearch,
+	"referral":       ChannelReferral,
+}
+
+// referrerMediums are the mediums of clicks attributed by their referrer
+// alone, per channel.
+var referrerMediums = map[string]string{
+	ChannelSearch:   "organic",
+	ChannelSocial:   "social",
+	ChannelEmail:    "email",
+	ChannelReferral: "referral",
+}
+
+// Attribute works out where a click came from. The UTM parameters of the
+// request that followed the link win over those of its destination, which
+// win over the Referer header; a click with none of them is direct.
+func Attribute(referer string, requestUTM map[string]string, destination string) Attribution {
+	a := Attribution{Referrer: referrerDomain(referer)}
+	utm := requestUTM
+	if len(utm) == 0 {
+		if u, err := url.Parse(destination); err == nil {
+			utm = UTM(u.Query())
+		}
+	}
+
+	switch {
+	case len(utm) > 0:
+		a.Source = valueOr(strings.ToLower(utm[UTMSource]), NotSet)
+		a.Medium = valueOr(strings.ToLower(utm[UTMMedium]), NotSet)
+		a.Campaign = valueOr(utm[UTMCampaign], NotSet)
+		a.Channel = utmChannel(a)
+	case a.Referrer != "":
+		_, a.Channel = knownSource(a.Referrer)
+		if a.Channel == "" {
+			a.Channel = ChannelReferral
+		}
+		a.Source, a.Medium, a.Campaign = a.Referrer, referrerMediums[a.Channel], None
+	default:
+		a.Source, a.Medium, a.Campaign, a.Channel = SourceDirect, None, None, ChannelDirect
+	}
+	if a.Referrer == "" {
+		a.Referrer = SourceDirect
+	}
+	return a
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
+// utmChannel classifies a click with UTM parameters by its medium, then its
+// source, then its referrer.
+func utmChannel(a Attribution) string {
+	if c, ok := mediumChannels[a.Medium]; ok {
+		return c
+	}
+	if c, ok := sourceNames[a.Source]; ok {
+		return c
+	}
+	if _, c := knownSource(a.Source); c != "" {
+		return c
+	}
+	if a.Referrer == "" {
+		return ChannelOther
+	}
+	if _, c := knownSource(a.Referrer); c != "" {
+		return c
+	}
+	return ChannelReferral
+}
+
+// referrerDomain returns the domain of the site a Referer header names, or
+// an empty string if it names none.
+func referrerDomain(referer string) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, prefix := range []string{"www.", "m.", "mobile.", "l.", "lm."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if alias, ok := sourceAliases[host]; ok {
+		host = alias
+	}
+	// Google serves its search from a domain per country.
+	if strings.HasPrefix(host, "google.") {
+		host = "google.com"
+	}
+	if domain, c := knownSource(host); c != "" {
+		return domain
+	}
+	return host
+}
+
+// knownSource returns the well-known site host belongs to and its channel.
+func knownSource(host string) (string, string) {
+	for h := host; h != ""; {
+		if c, ok := sourceChannels[h]; ok {
+			return h, c
+		}
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			break
+		}
+		h = parent
+	}
+	return "", ""
+}
@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttribute(t *testing.T) {
+	testCases := []struct {
+		name        string
+		referer     string
+		utm         map[string]string
+		destination string
+		expected    Attribution
+	}{
+		{
+			name:        "Test a direct click",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: SourceDirect, Source: SourceDirect, Medium: None, Campaign: None, Channel: ChannelDirect},
+		},
+		{
+			name:        "Test a search engine of another country",
+			referer:     "https://www.google.co.uk/",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: "google.com", Source: "google.com", Medium: "organic", Campaign: None, Channel: ChannelSearch},
+		},
+		{
+			name:        "Test a link shortener of a social network",
+			referer:     "https://t.co/abc",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: "x.com", Source: "x.com", Medium: "social", Campaign: None, Channel: ChannelSocial},
+		},
+		{
+			name:        "Test a subdomain of a social network",
+			referer:     "https://old.reddit.com/r/golang/",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: "reddit.com", Source: "reddit.com", Medium: "social", Campaign: None, Channel: ChannelSocial},
+		},
+		{
+			name:        "Test a mail app",
+			referer:     "android-app://com.google.android.gm/",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: "mail.google.com", Source: "mail.google.com", Medium: "email", Campaign: None, Channel: ChannelEmail},
+		},
+		{
+			name:        "Test an unknown site",
+			referer:     "https://blog.example.org/post",
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: "blog.example.org", Source: "blog.example.org", Medium: "referral", Campaign: None, Channel: ChannelReferral},
+		},
+		{
+			name:        "Test the UTM parameters of the destination",
+			destination: "https://example.com/?utm_source=Newsletter&utm_medium=email&utm_campaign=spring",
+			expected:    Attribution{Referrer: SourceDirect, Source: "newsletter", Medium: "email", Campaign: "spring", Channel: ChannelEmail},
+		},
+		{
+			name:        "Test the UTM parameters of the request win",
+			referer:     "https://www.facebook.com/",
+			utm:         map[string]string{UTMSource: "facebook", UTMCampaign: "launch"},
+			destination: "https://example.com/?utm_source=newsletter&utm_medium=email",
+			expected:    Attribution{Referrer: "facebook.com", Source: "facebook", Medium: NotSet, Campaign: "launch", Channel: ChannelSocial},
+		},
+		{
+			name:        "Test a paid search campaign",
+			utm:         map[string]string{UTMSource: "partner", UTMMedium: "CPC"},
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: SourceDirect, Source: "partner", Medium: "cpc", Campaign: NotSet, Channel: ChannelSearch},
+		},
+		{
+			name:        "Test an unknown campaign",
+			utm:         map[string]string{UTMSource: "flyer"},
+			destination: "https://example.com/",
+			expected:    Attribution{Referrer: SourceDirect, Source: "flyer", Medium: NotSet, Campaign: NotSet, Channel: ChannelOther},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Attribute(tc.referer, tc.utm, tc.destination))
+		})
+	}
+}
+
+func TestUTM(t *testing.T) {
+	query := url.Values{"utm_source": {"x"}, "utm_medium": {" "}, "ref": {"y"}}
+	assert.Equal(t, map[string]string{UTMSource: "x"}, UTM(query))
+	assert.Nil(t, UTM(url.Values{"ref": {"y"}}))
+}
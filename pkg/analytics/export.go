@@ -0,0 +1,227 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/export"
+)
+
+// exportBatch is the number of rows an export reads at a time.
+const exportBatch = 5000
+
+// Dataset is the data an export holds.
+type Dataset string
+
+// Datasets of exports: the raw clicks, or the clicks of the rollups per
+// bucket and value of every dimension.
+const (
+	DatasetClicks  Dataset = "clicks"
+	DatasetRollups Dataset = "rollups"
+)
+
+var (
+	clickColumns = []export.Column{
+		{Name: "id", Type: export.Int64},
+		{Name: "clicked_at", Type: export.Timestamp},
+		{Name: "link_id", Type: export.Int64},
+		{Name: "short_code", Type: export.String},
+		{Name: "domain", Type: export.String},
+		{Name: "is_bot", Type: export.Bool},
+		{Name: "bot", Type: export.String},
+		{Name: "browser", Type: export.String},
+		{Name: "browser_version", Type: export.String},
+		{Name: "os", Type: export.String},
+		{Name: "device_type", Type: export.String},
+		{Name: "referrer", Type: export.String},
+		{Name: "source", Type: export.String},
+		{Name: "medium", Type: export.String},
+		{Name: "campaign", Type: export.String},
+		{Name: "channel", Type: export.String},
+	}
+	rollupColumns = []export.Column{
+		{Name: "granularity", Type: export.String},
+		{Name: "bucket", Type: export.Timestamp},
+		{Name: "link_id", Type: export.Int64},
+		{Name: "short_code", Type: export.String},
+		{Name: "domain", Type: export.String},
+		{Name: "dimension", Type: export.String},
+		{Name: "value", Type: export.String},
+		{Name: "clicks", Type: export.Int64},
+		{Name: "bot_clicks", Type: export.Int64},
+	}
+)
+
+// Export selects the rows of an export.
+type Export struct {
+	Dataset Dataset
+	// Granularity is that of the rollups exported.
+	Granularity Granularity
+	// LinkIDs are the links exported or, when empty, all the links of
+	// WorkspaceID.
+	LinkIDs     []int64
+	WorkspaceID int64
+	// From and To bound the clicks, or the buckets of the rollups, exported:
+	// From included and To excluded.
+	From time.Time
+	To   time.Time
+}
+
+// ParseDataset returns the dataset called s.
+func ParseDataset(s string) (Dataset, error) {
+	switch d := Dataset(s); d {
+	case DatasetClicks, DatasetRollups:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unknown dataset %q, expected clicks or rollups", s)
+	}
+}
+
+// Exporter writes the raw clicks and rollups of links as CSV, NDJSON or
+// Parquet. Rows are read and written a batch at a time, so that exports of
+// any size hold little memory.
+type Exporter struct {
+	querier sqlc.Querier
+}
+
+// NewExporter returns an Exporter reading clicks with querier.
+func NewExporter(querier sqlc.Querier) *Exporter {
+	return &Exporter{querier: querier}
+}
+
+// Export writes the rows selected by e to w in format f and returns how
+// many were written. Clicks are written in the order they happened, and
+// rollups per link, dimension and value. When it fails, what was written
+// to w is not a complete file.
+func (x *Exporter) Export(ctx context.Context, w io.Writer, f export.Format, e Export) (int64, error) {
+	columns := clickColumns
+	if e.Dataset == DatasetRollups {
+		columns = rollupColumns
+	}
+	ew, err := export.NewWriter(w, f, columns)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if e.Dataset == DatasetRollups {
+		n, err = x.rollups(ctx, ew, e)
+	} else {
+		n, err = x.clicks(ctx, ew, e)
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, ew.Close()
+}
+
+func (x *Exporter) clicks(ctx context.Context, w export.Writer, e Export) (int64, error) {
+	var n int64
+	afterTime, afterID := e.From, int64(0)
+	for {
+		var rows []sqlc.ExportClicksRow
+		var err error
+		if len(e.LinkIDs) > 0 {
+			rows, err = x.querier.ExportClicks(ctx, sqlc.ExportClicksParams{
+				UrlIds:    e.LinkIDs,
+				FromTime:  e.From,
+				ToTime:    e.To,
+				AfterTime: afterTime,
+				AfterID:   afterID,
+				MaxCount:  exportBatch,
+			})
+		} else {
+			var ws []sqlc.ExportWorkspaceClicksRow
+			ws, err = x.querier.ExportWorkspaceClicks(ctx, sqlc.ExportWorkspaceClicksParams{
+				WorkspaceID: pgtype.Int8{Int64: e.WorkspaceID, Valid: true},
+				FromTime:    e.From,
+				ToTime:      e.To,
+				AfterTime:   afterTime,
+				AfterID:     afterID,
+				MaxCount:    exportBatch,
+			})
+			for _, r := range ws {
+				rows = append(rows, sqlc.ExportClicksRow(r))
+			}
+		}
+		if err != nil {
+			return n, err
+		}
+
+		for _, r := range rows {
+			err := w.Write([]any{
+				r.ID, r.ClickedAt, r.UrlID, r.ShortCode, r.Domain, r.IsBot, r.Bot,
+				r.Browser, r.BrowserVersion, r.Os, r.DeviceType,
+				r.Referrer, r.Source, r.Medium, r.Campaign, r.Channel,
+			})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(rows) < exportBatch {
+			return n, nil
+		}
+		last := rows[len(rows)-1]
+		afterTime, afterID = last.ClickedAt, last.ID
+	}
+}
+
+func (x *Exporter) rollups(ctx context.Context, w export.Writer, e Export) (int64, error) {
+	var n int64
+	var after sqlc.ExportClickRollupsRow
+	for {
+		var rows []sqlc.ExportClickRollupsRow
+		var err error
+		if len(e.LinkIDs) > 0 {
+			rows, err = x.querier.ExportClickRollups(ctx, sqlc.ExportClickRollupsParams{
+				Granularity:    string(e.Granularity),
+				UrlIds:         e.LinkIDs,
+				FromTime:       e.From,
+				ToTime:         e.To,
+				AfterUrlID:     after.UrlID,
+				AfterDimension: after.Dimension,
+				AfterValue:     after.Value,
+				AfterBucket:    after.Bucket,
+				MaxCount:       exportBatch,
+			})
+		} else {
+			var ws []sqlc.ExportWorkspaceClickRollupsRow
+			ws, err = x.querier.ExportWorkspaceClickRollups(ctx, sqlc.ExportWorkspaceClickRollupsParams{
+				Granularity:    string(e.Granularity),
+				WorkspaceID:    pgtype.Int8{Int64: e.WorkspaceID, Valid: true},
+				FromTime:       e.From,
+				ToTime:         e.To,
+				AfterUrlID:     after.UrlID,
+				AfterDimension: after.Dimension,
+				AfterValue:     after.Value,
+				AfterBucket:    after.Bucket,
+				MaxCount:       exportBatch,
+			})
+			for _, r := range ws {
+				rows = append(rows, sqlc.ExportClickRollupsRow(r))
+			}
+		}
+		if err != nil {
+			return n, err
+		}
+
+		for _, r := range rows {
+			err := w.Write([]any{
+				string(e.Granularity), r.Bucket, r.UrlID, r.ShortCode, r.Domain,
+				r.Dimension, r.Value, r.Clicks, r.BotClicks,
+			})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(rows) < exportBatch {
+			return n, nil
+		}
+		after = rows[len(rows)-1]
+	}
+}
@@ -0,0 +1,162 @@
+package analytics
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/metrics"
+)
+
+var (
+	// ErrSlowConsumer ends the subscriptions of clients that fell a whole
+	// buffer behind the clicks.
+	ErrSlowConsumer = errors.New("client fell behind the live clicks")
+	// ErrLiveClosed ends subscriptions when the service shuts down, and is
+	// returned for subscriptions made afterwards.
+	ErrLiveClosed = errors.New("live clicks are shutting down")
+	// ErrTooManySubscribers is returned when the maximum number of
+	// subscriptions is reached.
+	ErrTooManySubscribers = errors.New("too many live subscriptions")
+)
+
+// Click is a recorded click, as streamed live.
+type Click struct {
+	LinkID      int64     `json:"-"`
+	WorkspaceID int64     `json:"-"`
+	ShortCode   string    `json:"short_code"`
+	Domain      string    `json:"domain,omitempty"`
+	At          time.Time `json:"at"`
+	// Country is an ISO 3166-1 alpha-2 code, or Unknown.
+	Country    string `json:"country"`
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+	Bot        string `json:"bot,omitempty"`
+	Referrer   string `json:"referrer"`
+	Source     string `json:"source"`
+	Medium     string `json:"medium"`
+	Campaign   string `json:"campaign"`
+	Channel    string `json:"channel"`
+}
+
+// Live fans out recorded clicks to the clients streaming them. Every
+// replica receives every recorded click, whichever replica stored it, so
+// each can serve any stream.
+type Live struct {
+	buffer           int
+	maxSubscriptions int
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewLive returns a Live buffering up to buffer clicks per subscription and
+// serving up to maxSubscriptions at once.
+func NewLive(buffer, maxSubscriptions int) *Live {
+	return &Live{buffer: buffer, maxSubscriptions: maxSubscriptions, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the clicks of a link, or of all the links of a
+// workspace.
+type Subscription struct {
+	live        *Live
+	linkID      int64
+	workspaceID int64
+	clicks      chan Click
+	err         error
+}
+
+// Subscribe returns a subscription to the clicks of linkID or, if it is 0,
+// of the links of workspaceID. It must be closed once done with.
+func (l *Live) Subscribe(linkID, workspaceID int64) (*Subscription, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, ErrLiveClosed
+	}
+	if len(l.subs) >= l.maxSubscriptions {
+		return nil, ErrTooManySubscribers
+	}
+	s := &Subscription{live: l, linkID: linkID, workspaceID: workspaceID, clicks: make(chan Click, l.buffer)}
+	l.subs[s] = struct{}{}
+	metrics.LiveSubscriptions.Inc()
+	return s, nil
+}
+
+// Publish sends c to the subscriptions of its link and workspace. It never
+// blocks: subscriptions whose buffer is full are ended with ErrSlowConsumer.
+func (l *Live) Publish(c Click) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for s := range l.subs {
+		if !s.matches(c) {
+			continue
+		}
+		select {
+		case s.clicks <- c:
+		default:
+			l.end(s, ErrSlowConsumer)
+		}
+	}
+}
+
+// Close ends all subscriptions with ErrLiveClosed and refuses new ones.
+func (l *Live) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for s := range l.subs {
+		l.end(s, ErrLiveClosed)
+	}
+}
+
+// end removes s and closes its channel. l.mu must be held.
+func (l *Live) end(s *Subscription, err error) {
+	if _, ok := l.subs[s]; !ok {
+		return
+	}
+	delete(l.subs, s)
+	metrics.LiveSubscriptions.Dec()
+	if err != nil {
+		metrics.LiveDisconnects.WithLabelValues(disconnectReason(err)).Inc()
+	}
+	s.err = err
+	close(s.clicks)
+}
+
+func disconnectReason(err error) string {
+	if errors.Is(err, ErrSlowConsumer) {
+		return "slow_consumer"
+	}
+	return "shutdown"
+}
+
+func (s *Subscription) matches(c Click) bool {
+	if s.linkID != 0 {
+		return c.LinkID == s.linkID
+	}
+	return c.WorkspaceID == s.workspaceID
+}
+
+// Clicks returns the clicks of the subscription. It is closed when the
+// subscription is ended, after which Err tells why.
+func (s *Subscription) Clicks() <-chan Click {
+	return s.clicks
+}
+
+// Err returns why the subscription was ended, once Clicks is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.live.mu.Lock()
+	defer s.live.mu.Unlock()
+	s.live.end(s, nil)
+}
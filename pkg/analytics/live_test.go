@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLive(t *testing.T) {
+	live := NewLive(2, 3)
+
+	link, err := live.Subscribe(42, 0)
+	require.NoError(t, err)
+	workspace, err := live.Subscribe(0, 7)
+	require.NoError(t, err)
+	other, err := live.Subscribe(43, 0)
+	require.NoError(t, err)
+	_, err = live.Subscribe(44, 0)
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	live.Publish(Click{LinkID: 42, WorkspaceID: 7, ShortCode: "abc"})
+	assert.Equal(t, "abc", (<-link.Clicks()).ShortCode)
+	assert.Equal(t, "abc", (<-workspace.Clicks()).ShortCode)
+	assert.Empty(t, other.Clicks())
+
+	// A client that does not read is dropped once its buffer is full.
+	for range 3 {
+		live.Publish(Click{LinkID: 43})
+	}
+	assert.Len(t, other.Clicks(), 2)
+	<-other.Clicks()
+	<-other.Clicks()
+	_, ok := <-other.Clicks()
+	assert.False(t, ok)
+	assert.ErrorIs(t, other.Err(), ErrSlowConsumer)
+
+	// Its place is free again, and closing twice is harmless.
+	other.Close()
+	again, err := live.Subscribe(43, 0)
+	require.NoError(t, err)
+	again.Close()
+
+	live.Close()
+	_, ok = <-link.Clicks()
+	assert.False(t, ok)
+	assert.ErrorIs(t, link.Err(), ErrLiveClosed)
+	_, err = live.Subscribe(42, 0)
+	assert.ErrorIs(t, err, ErrLiveClosed)
+	link.Close()
+}
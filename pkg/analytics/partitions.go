@@ -0,0 +1,194 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// partitionsAhead is the number of partitions created ahead of the current
+// one, so that clicks keep being recorded if the rollups stop for a while.
+const partitionsAhead = 3
+
+// partitions describes how a table is partitioned by time: into UTC days or
+// months, named after the table and the day or month they hold.
+type partitions struct {
+	table   string
+	monthly bool
+}
+
+var (
+	rawClicks        = partitions{table: "clicks"}
+	rollupPartitions = map[Granularity]partitions{
+		GranularityMinute: {table: "click_rollups_minute"},
+		GranularityHour:   {table: "click_rollups_hour", monthly: true},
+		GranularityDay:    {table: "click_rollups_day", monthly: true},
+	}
+)
+
+func (p partitions) layout() string {
+	if p.monthly {
+		return "200601"
+	}
+	return "20060102"
+}
+
+// start returns the start of the partition t falls in.
+func (p partitions) start(t time.Time) time.Time {
+	t = t.UTC()
+	if p.monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the partition after the one starting at start.
+func (p partitions) next(start time.Time) time.Time {
+	if p.monthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// name returns the name of the partition starting at start.
+func (p partitions) name(start time.Time) string {
+	return p.table + "_p" + start.Format(p.layout())
+}
+
+// parse returns the start of the partition called name. It reports false
+// for tables that are not partitions of p.
+func (p partitions) parse(name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, p.table+"_p")
+	if !ok {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(p.layout(), suffix)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// keptFrom returns the start of the oldest partition of p that is not yet
+// past retention, or the zero time if they are kept forever.
+func keptFrom(p partitions, retention time.Duration, now time.Time) time.Time {
+	if retention <= 0 {
+		return time.Time{}
+	}
+	return p.start(now.Add(-retention))
+}
+
+// createPartitions creates the partitions that are missing from where
+// clicks are still recorded or rolled up to partitionsAhead after now.
+func (r *Rollups) createPartitions(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	if err := create(ctx, tx, rawClicks, now.Add(-r.cfg.Lateness), now); err != nil {
+		return err
+	}
+	raw, err := oldestRawClicks(ctx, tx, now, r.cfg.Retention.Raw)
+	if err != nil {
+		return err
+	}
+	for _, g := range granularities {
+		if err := create(ctx, tx, rollupPartitions[g], r.rollupFrom(g, raw, now), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func create(ctx context.Context, tx pgx.Tx, p partitions, from, now time.Time) error {
+	existing, err := list(ctx, tx, p)
+	if err != nil {
+		return err
+	}
+	last := p.start(now)
+	for range partitionsAhead {
+		last = p.next(last)
+	}
+	for start := p.start(from); !start.After(last); start = p.next(start) {
+		name := p.name(start)
+		if existing[name] {
+			continue
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			pgx.Identifier{name}.Sanitize(), pgx.Identifier{p.table}.Sanitize(),
+			start.Format(time.RFC3339), p.next(start).Format(time.RFC3339)))
+		if err != nil {
+			return fmt.Errorf("could not create partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// dropPartitions drops the partitions of raw clicks and rollups whose
+// clicks are all past their retention.
+func (r *Rollups) dropPartitions(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	if err := drop(ctx, tx, rawClicks, keptFrom(rawClicks, r.cfg.Retention.Raw, now)); err != nil {
+		return err
+	}
+	for _, g := range granularities {
+		p := rollupPartitions[g]
+		if err := drop(ctx, tx, p, keptFrom(p, r.cfg.Retention.Of(g), now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func drop(ctx context.Context, tx pgx.Tx, p partitions, keptFrom time.Time) error {
+	if keptFrom.IsZero() {
+		return nil
+	}
+	existing, err := list(ctx, tx, p)
+	if err != nil {
+		return err
+	}
+	for name := range existing {
+		start, ok := p.parse(name)
+		if !ok || !start.Before(keptFrom) {
+			continue
+		}
+		if _, err := tx.Exec(ctx, "DROP TABLE "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return fmt.Errorf("could not drop partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// oldestRawClicks returns the start of the oldest partition of raw clicks.
+// It is usually the oldest within retention, but older ones remain until
+// their clicks have been rolled up, such as those created when the clicks
+// table was first partitioned.
+func oldestRawClicks(ctx context.Context, tx pgx.Tx, now time.Time, retention time.Duration) (time.Time, error) {
+	existing, err := list(ctx, tx, rawClicks)
+	if err != nil {
+		return time.Time{}, err
+	}
+	oldest := keptFrom(rawClicks, retention, now)
+	for name := range existing {
+		if start, ok := rawClicks.parse(name); ok && start.Before(oldest) {
+			oldest = start
+		}
+	}
+	return oldest, nil
+}
+
+// list returns the names of the partitions of p.
+func list(ctx context.Context, tx pgx.Tx, p partitions) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = $1::regclass`, p.table)
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, name := range names {
+		existing[name] = true
+	}
+	return existing, nil
+}
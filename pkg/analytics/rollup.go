@@ -0,0 +1,225 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metrics"
+)
+
+// Granularity is the length of the time buckets clicks are rolled up into.
+type Granularity string
+
+// Granularities of the rollups, named like the date_trunc fields Postgres
+// truncates their buckets with.
+const (
+	GranularityMinute Granularity = "minute"
+	GranularityHour   Granularity = "hour"
+	GranularityDay    Granularity = "day"
+)
+
+// granularities lists the granularities from the finest. Each is rolled up
+// from the one before it while that one is still kept.
+var granularities = []Granularity{GranularityMinute, GranularityHour, GranularityDay}
+
+// ParseGranularity returns the granularity called s.
+func ParseGranularity(s string) (Granularity, error) {
+	if g := Granularity(s); slices.Contains(granularities, g) {
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown granularity %q, expected minute, hour or day", s)
+}
+
+// Duration returns the length of the buckets of g.
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case GranularityMinute:
+		return time.Minute
+	case GranularityHour:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// Truncate returns the start of the UTC bucket of g that t falls in.
+func (g Granularity) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Duration())
+}
+
+// Retention is how long raw clicks and the rollups of each granularity are
+// kept. A rollup is kept forever when its retention is 0.
+type Retention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+	Day    time.Duration
+}
+
+// Of returns the retention of the rollups of g.
+func (r Retention) Of(g Granularity) time.Duration {
+	switch g {
+	case GranularityMinute:
+		return r.Minute
+	case GranularityHour:
+		return r.Hour
+	default:
+		return r.Day
+	}
+}
+
+// RollupConfig configures the rollup of clicks.
+type RollupConfig struct {
+	// Interval is how often clicks are rolled up, and so how far behind the
+	// stats may be.
+	Interval time.Duration
+	// Lateness is how late a click may be recorded and still be counted: the
+	// buckets of that window are rolled up again on every run.
+	Lateness  time.Duration
+	Retention Retention
+}
+
+// Rollups periodically rolls up the raw clicks into the buckets of every
+// granularity, creates the partitions the coming clicks and rollups are
+// stored in and drops those past their retention. Replicas take turns with
+// an advisory lock, so only one does the work of a run.
+type Rollups struct {
+	db     *pgxpool.Pool
+	cfg    RollupConfig
+	logger *slog.Logger
+}
+
+// NewRollups returns Rollups maintaining the clicks stored in db.
+func NewRollups(db *pgxpool.Pool, cfg RollupConfig, logger *slog.Logger) *Rollups {
+	return &Rollups{db: db, cfg: cfg, logger: logger}
+}
+
+// Run rolls up clicks every interval until ctx is cancelled. The first run
+// starts right away, so the partitions of today exist before clicks are
+// recorded.
+func (r *Rollups) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("failed to roll up clicks", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates the partitions that will be needed, rolls up the clicks
+// recorded since the last run and drops expired partitions. It does nothing
+// while another replica holds the lock.
+func (r *Rollups) RunOnce(ctx context.Context) error {
+	now := time.Now().UTC()
+	ran, err := r.locked(ctx, func(tx pgx.Tx) error { return r.createPartitions(ctx, tx, now) })
+	if err != nil || !ran {
+		metrics.ClickRollupRuns.WithLabelValues(runResult(ran, err)).Inc()
+		return err
+	}
+	ran, err = r.locked(ctx, func(tx pgx.Tx) error { return r.rollup(ctx, tx, now) })
+	metrics.ClickRollupRuns.WithLabelValues(runResult(ran, err)).Inc()
+	if err != nil || !ran {
+		return err
+	}
+	_, err = r.locked(ctx, func(tx pgx.Tx) error { return r.dropPartitions(ctx, tx, now) })
+	return err
+}
+
+func runResult(ran bool, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case !ran:
+		return "skipped"
+	default:
+		return "ok"
+	}
+}
+
+// locked runs fn in a transaction holding the rollup lock. It reports false
+// without running fn if another replica holds the lock. Partitions are
+// created and dropped in transactions of their own, because doing so locks
+// the partitioned table against the clicks being recorded.
+func (r *Rollups) locked(ctx context.Context, fn func(tx pgx.Tx) error) (bool, error) {
+	var ran bool
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		ok, err := sqlc.New(tx).TryLockClickRollups(ctx)
+		if err != nil || !ok {
+			return err
+		}
+		ran = true
+		return fn(tx)
+	})
+	return ran, err
+}
+
+// rollup counts the clicks of the buckets from the last run, less the
+// lateness, to now, or of all the raw clicks on the first run. A
+// granularity is rolled up from the one before it if that one is kept for
+// the whole window, and from the raw clicks otherwise.
+func (r *Rollups) rollup(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	q := sqlc.New(tx)
+	raw, err := oldestRawClicks(ctx, tx, now, r.cfg.Retention.Raw)
+	if err != nil {
+		return err
+	}
+	from := raw
+	last, err := q.GetClickRollupState(ctx)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return err
+	case last.Add(-r.cfg.Lateness).After(from):
+		from = last.Add(-r.cfg.Lateness)
+	}
+
+	for i, g := range granularities {
+		start := g.Truncate(from)
+		if kept := r.rollupFrom(g, raw, now); start.Before(kept) {
+			start = kept
+		}
+		end := g.Truncate(now).Add(g.Duration())
+
+		var n int64
+		if i > 0 && !start.Before(r.rollupFrom(granularities[i-1], raw, now)) {
+			n, err = q.RollupClickRollups(ctx, sqlc.RollupClickRollupsParams{
+				Granularity:       string(g),
+				SourceGranularity: string(granularities[i-1]),
+				FromTime:          start,
+				ToTime:            end,
+			})
+		} else {
+			n, err = q.RollupClicks(ctx, sqlc.RollupClicksParams{Granularity: string(g), FromTime: start, ToTime: end})
+		}
+		if err != nil {
+			return err
+		}
+		metrics.ClickRollupRows.WithLabelValues(string(g)).Add(float64(n))
+	}
+	return q.SetClickRollupState(ctx, now)
+}
+
+// rollupFrom returns the start of the oldest bucket of g that is rolled up:
+// that of the oldest raw clicks, which start at raw, or of the oldest
+// rollups kept if they are dropped sooner.
+func (r *Rollups) rollupFrom(g Granularity, raw, now time.Time) time.Time {
+	from := raw
+	if kept := keptFrom(rollupPartitions[g], r.cfg.Retention.Of(g), now); kept.After(from) {
+		from = kept
+	}
+	return from
+}
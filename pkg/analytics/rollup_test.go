@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGranularity(t *testing.T) {
+	r := NewReporter(nil, nil, Retention{Raw: 30 * 24 * time.Hour, Minute: 7 * 24 * time.Hour, Hour: 90 * 24 * time.Hour})
+	today := Day(time.Now())
+
+	testCases := []struct {
+		name     string
+		from, to time.Time
+		want     Granularity
+	}{
+		{name: "Test whole days", from: today.AddDate(0, 0, -30), to: today.AddDate(0, 0, 1), want: GranularityDay},
+		{name: "Test whole days kept forever", from: today.AddDate(-3, 0, 0), to: today, want: GranularityDay},
+		{name: "Test whole hours", from: today.Add(-3 * time.Hour), to: today.Add(2 * time.Hour), want: GranularityHour},
+		{name: "Test minutes", from: today.Add(-90 * time.Minute), to: today.Add(15 * time.Minute), want: GranularityMinute},
+		{name: "Test minutes past their retention", from: today.AddDate(0, 0, -8).Add(time.Minute), to: today},
+		{name: "Test hours past their retention", from: today.AddDate(0, 0, -100).Add(time.Hour), to: today},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := r.Granularity(Query{From: tc.from, To: tc.to})
+			if tc.want == "" {
+				assert.ErrorIs(t, err, ErrNotKept)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	at := time.Date(2026, 10, 19, 13, 45, 0, 0, time.UTC)
+
+	day := rawClicks.start(at)
+	assert.Equal(t, "clicks_p20261019", rawClicks.name(day))
+	assert.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), rawClicks.next(day))
+
+	hours := rollupPartitions[GranularityHour]
+	month := hours.start(at)
+	assert.Equal(t, "click_rollups_hour_p202610", hours.name(month))
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), hours.next(month))
+
+	start, ok := hours.parse("click_rollups_hour_p202610")
+	require.True(t, ok)
+	assert.Equal(t, month, start)
+	_, ok = hours.parse("click_rollups_minute_p20261019")
+	assert.False(t, ok)
+
+	assert.True(t, keptFrom(rawClicks, 0, at).IsZero())
+	assert.Equal(t, time.Date(2026, 9, 19, 0, 0, 0, 0, time.UTC), keptFrom(rawClicks, 30*24*time.Hour, at))
+}
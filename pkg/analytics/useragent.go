@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Device types of a UserAgent.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Other names the browser or operating system of a User-Agent no rule matches.
+const Other = "Other"
+
+//go:embed useragents.yaml
+var defaultRules []byte
+
+// UserAgent is what a User-Agent header says about the client of a click.
+type UserAgent struct {
+	Browser string
+	// BrowserVersion is the major version of the browser, if known.
+	BrowserVersion string
+	OS             string
+	DeviceType     string
+	// Bot names the crawler, link preview fetcher or HTTP library that sent
+	// the header, or is empty for people.
+	Bot string
+}
+
+// IsBot reports whether the click came from a bot.
+func (ua UserAgent) IsBot() bool {
+	return ua.Bot != ""
+}
+
+type rule struct {
+	// Name is the browser, operating system or bot the rule identifies.
+	Name string `yaml:"name"`
+	// Type is the device type the rule identifies.
+	Type    string `yaml:"type"`
+	Pattern string `yaml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// userAgentRules is the format of a rules file.
+type userAgentRules struct {
+	Bots     []rule `yaml:"bots"`
+	Browsers []rule `yaml:"browsers"`
+	OS       []rule `yaml:"os"`
+	Devices  []rule `yaml:"devices"`
+}
+
+// UserAgentParser classifies User-Agent headers with ordered lists of rules.
+type UserAgentParser struct {
+	rules userAgentRules
+}
+
+// DefaultUserAgentParser returns a parser using the rules built into the
+// binary.
+func DefaultUserAgentParser() *UserAgentParser {
+	p, err := ParseUserAgentRules(defaultRules)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// OpenUserAgentParser returns a parser using the rules file at path, or the
+// built-in rules when path is empty.
+func OpenUserAgentParser(path string) (*UserAgentParser, error) {
+	if path == "" {
+		return DefaultUserAgentParser(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read user agent rules: %w", err)
+	}
+	p, err := ParseUserAgentRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParseUserAgentRules returns a parser using the YAML rules in data, in the
+// format of the built-in useragents.yaml.
+func ParseUserAgentRules(data []byte) (*UserAgentParser, error) {
+	var rules userAgentRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid user agent rules: %w", err)
+	}
+	lists := map[string][]rule{"bots": rules.Bots, "browsers": rules.Browsers, "os": rules.OS, "devices": rules.Devices}
+	for list, rs := range lists {
+		for i := range rs {
+			r := &rs[i]
+			if r.Pattern == "" || (r.Name == "" && r.Type == "") {
+				return nil, fmt.Errorf("invalid user agent rules: %s rule %d needs a pattern and a name or type", list, i+1)
+			}
+			re, err := regexp.Compile("(?i)" + r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid user agent rules: %s rule %q: %w", list, r.Pattern, err)
+			}
+			r.re = re
+		}
+	}
+	return &UserAgentParser{rules: rules}, nil
+}
+
+// Parse classifies a User-Agent header. Empty headers come from bots.
+func (p *UserAgentParser) Parse(userAgent string) UserAgent {
+	ua := UserAgent{Browser: Other, OS: Other, DeviceType: DeviceDesktop}
+	if userAgent == "" {
+		ua.Bot = Other
+	}
+	for _, r := range p.rules.Bots {
+		if !ua.IsBot() && r.re.MatchString(userAgent) {
+			ua.Bot = r.Name
+		}
+	}
+
+	for _, r := range p.rules.Browsers {
+		if m := r.re.FindStringSubmatch(userAgent); m != nil {
+			ua.Browser = r.Name
+			if len(m) > 1 {
+				ua.BrowserVersion = m[1]
+			}
+			break
+		}
+	}
+	for _, r := range p.rules.OS {
+		if r.re.MatchString(userAgent) {
+			ua.OS = r.Name
+			break
+		}
+	}
+	if ua.IsBot() {
+		ua.DeviceType = DeviceBot
+		return ua
+	}
+	for _, r := range p.rules.Devices {
+		if r.re.MatchString(userAgent) {
+			ua.DeviceType = r.Type
+			break
+		}
+	}
+	return ua
+}
@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	p := DefaultUserAgentParser()
+
+	testCases := []struct {
+		name      string
+		userAgent string
+		expected  UserAgent
+	}{
+		{
+			name:      "Test Chrome on Windows",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			expected:  UserAgent{Browser: "Chrome", BrowserVersion: "124", OS: "Windows", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Test Edge is not Chrome",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			expected:  UserAgent{Browser: "Edge", BrowserVersion: "124", OS: "Windows", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Test Safari on iPhone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			expected:  UserAgent{Browser: "Safari", BrowserVersion: "17", OS: "iOS", DeviceType: DeviceMobile},
+		},
+		{
+			name:      "Test Firefox on an Android tablet",
+			userAgent: "Mozilla/5.0 (Android 14; Tablet; rv:125.0) Gecko/125.0 Firefox/125.0",
+			expected:  UserAgent{Browser: "Firefox", BrowserVersion: "125", OS: "Android", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Test Chrome on an Android tablet",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			expected:  UserAgent{Browser: "Chrome", BrowserVersion: "124", OS: "Android", DeviceType: DeviceTablet},
+		},
+		{
+			name:      "Test Safari on macOS",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
+			expected:  UserAgent{Browser: "Safari", BrowserVersion: "17", OS: "macOS", DeviceType: DeviceDesktop},
+		},
+		{
+			name:      "Test a link preview fetcher",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			expected:  UserAgent{Browser: Other, OS: Other, DeviceType: DeviceBot, Bot: "Slackbot"},
+		},
+		{
+			name:      "Test a crawler posing as a phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected:  UserAgent{Browser: "Chrome", BrowserVersion: "124", OS: "Android", DeviceType: DeviceBot, Bot: "Googlebot"},
+		},
+		{
+			name:      "Test an HTTP library",
+			userAgent: "curl/8.5.0",
+			expected:  UserAgent{Browser: Other, OS: Other, DeviceType: DeviceBot, Bot: "curl"},
+		},
+		{
+			name:      "Test an empty header",
+			userAgent: "",
+			expected:  UserAgent{Browser: Other, OS: Other, DeviceType: DeviceBot, Bot: Other},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, p.Parse(tc.userAgent))
+		})
+	}
+}
+
+func TestParseUserAgentRules(t *testing.T) {
+	p, err := ParseUserAgentRules([]byte("bots:\n  - name: Acme\n    pattern: 'acme'\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", p.Parse("AcmeFetcher/1.0").Bot)
+	assert.False(t, p.Parse("Mozilla/5.0").IsBot())
+
+	_, err = ParseUserAgentRules([]byte("browsers:\n  - name: Broken\n    pattern: '(?!x)'\n"))
+	assert.Error(t, err)
+	_, err = ParseUserAgentRules([]byte("os:\n  - pattern: 'linux'\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// saltTTL is how long a day's salt is kept: long enough for the clicks of
+// that day still in flight, after which its hashes can no longer be
+// recomputed from an IP address and User-Agent.
+const saltTTL = 48 * time.Hour
+
+// Visitors counts the unique visitors of links with HyperLogLog sketches in
+// Redis, one per link and UTC day. A visitor is identified by a hash of
+// their IP address and User-Agent keyed with a random salt that changes
+// every day, so no identity is stored and the same person is counted once
+// per day they visit.
+type Visitors struct {
+	rdb       *redis.Client
+	retention time.Duration
+
+	mu    sync.Mutex
+	salts map[time.Time][]byte
+}
+
+// NewVisitors returns Visitors storing sketches in rdb, each kept for
+// retention after the last visit it counted.
+func NewVisitors(rdb *redis.Client, retention time.Duration) *Visitors {
+	return &Visitors{rdb: rdb, retention: retention, salts: make(map[time.Time][]byte)}
+}
+
+// Day returns the UTC day t falls on.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// Days returns the UTC days from from to to, both included.
+func Days(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d := Day(from); !d.After(to); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+func visitorsKey(linkID int64, day time.Time) string {
+	return fmt.Sprintf("analytics:visitors:%d:%s", linkID, day.Format(time.DateOnly))
+}
+
+func saltKey(day time.Time) string {
+	return "analytics:salt:" + day.Format(time.DateOnly)
+}
+
+// VisitorHash identifies a visitor within the day salt belongs to.
+func VisitorHash(salt []byte, ip, userAgent string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Add counts a visit of a link at t.
+func (v *Visitors) Add(ctx context.Context, linkID int64, at time.Time, ip, userAgent string) error {
+	day := Day(at)
+	salt, err := v.salt(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	key := visitorsKey(linkID, day)
+	pipe := v.rdb.Pipeline()
+	pipe.PFAdd(ctx, key, VisitorHash(salt, ip, userAgent))
+	pipe.Expire(ctx, key, v.retention)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Count returns the unique visitors of a link over days, merging their
+// sketches.
+func (v *Visitors) Count(ctx context.Context, linkID int64, days []time.Time) (int64, error) {
+	if len(days) == 0 {
+		return 0, nil
+	}
+	keys := make([]string, len(days))
+	for i, d := range days {
+		keys[i] = visitorsKey(linkID, d)
+	}
+	return v.rdb.PFCount(ctx, keys...).Result()
+}
+
+// CountByDay returns the unique visitors of a link on each of days.
+func (v *Visitors) CountByDay(ctx context.Context, linkID int64, days []time.Time) ([]int64, error) {
+	pipe := v.rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(days))
+	for i, d := range days {
+		cmds[i] = pipe.PFCount(ctx, visitorsKey(linkID, d))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(days))
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+	return counts, nil
+}
+
+// salt returns the salt of day, which every replica shares through Redis.
+// The first replica to need it creates it.
+func (v *Visitors) salt(ctx context.Context, day time.Time) ([]byte, error) {
+	v.mu.Lock()
+	salt, ok := v.salts[day]
+	v.mu.Unlock()
+	if ok {
+		return salt, nil
+	}
+
+	salt = make([]byte, 32)
+	rand.Read(salt)
+	err := v.rdb.SetArgs(ctx, saltKey(day), salt, redis.SetArgs{Mode: "NX", TTL: saltTTL}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("could not store visitor salt: %w", err)
+	}
+	salt, err = v.rdb.Get(ctx, saltKey(day)).Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("could not load visitor salt: %w", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for d := range v.salts {
+		if d.Before(day.AddDate(0, 0, -1)) {
+			delete(v.salts, d)
+		}
+	}
+	v.salts[day] = salt
+	return salt, nil
+}
@@ -0,0 +1,199 @@
+// Package authz decides what callers may do in a workspace. Every creator
+// API that touches workspace data goes through an Authorizer, either as
+// middleware on /api/workspaces/{workspace} routes or, for links addressed
+// by short code, by checking the workspace a link belongs to.
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+)
+
+// Role is a member's role in a workspace. Each role can do everything the
+// roles below it can.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("role must be one of owner, admin, editor, viewer")
+	}
+	return role, nil
+}
+
+// Action is something a member can be allowed to do in a workspace.
+type Action int
+
+const (
+	// View covers reading the workspace, its links, domains, members and
+	// analytics.
+	View Action = iota
+	// CreateLinks covers creating links in the workspace.
+	CreateLinks
+	// ManageWorkspace covers custom domains and the QR code logo.
+	ManageWorkspace
+	// ManageMembers covers adding, changing and removing members other than owners.
+	ManageMembers
+	// ManageOwners covers granting and revoking the owner role.
+	ManageOwners
+)
+
+// minRoles is the least role allowed to perform each action.
+var minRoles = map[Action]Role{
+	View:            RoleViewer,
+	CreateLinks:     RoleEditor,
+	ManageWorkspace: RoleAdmin,
+	ManageMembers:   RoleAdmin,
+	ManageOwners:    RoleOwner,
+}
+
+// Can reports whether the role allows action.
+func (r Role) Can(action Action) bool {
+	min, ok := minRoles[action]
+	return ok && roleRanks[r] >= roleRanks[min]
+}
+
+var (
+	// ErrUnauthenticated is returned for anonymous callers.
+	ErrUnauthenticated = errors.New("authz: caller is not identified")
+	// ErrNotMember is returned when the workspace does not exist or the
+	// caller is not one of its members. The two are not told apart so that
+	// outsiders cannot probe for workspaces.
+	ErrNotMember = errors.New("authz: not a member of the workspace")
+	// ErrForbidden is returned when the caller's role does not allow the action.
+	ErrForbidden = errors.New("authz: role does not allow the action")
+)
+
+// Membership is a caller's role in a workspace.
+type Membership struct {
+	WorkspaceID int64
+	Slug        string
+	Name        string
+	Member      string
+	Role        Role
+}
+
+// Store is the part of sqlc.Querier that memberships are read from.
+type Store interface {
+	GetMembership(ctx context.Context, arg sqlc.GetMembershipParams) (sqlc.GetMembershipRow, error)
+	GetMembershipByID(ctx context.Context, arg sqlc.GetMembershipByIDParams) (sqlc.GetMembershipByIDRow, error)
+	GetWorkspaceBySlug(ctx context.Context, slug string) (sqlc.Workspace, error)
+	GetWorkspaceByID(ctx context.Context, id int64) (sqlc.Workspace, error)
+}
+
+// Authorizer checks memberships against the database on every call, so role
+// changes take effect immediately. Roles granted by the caller's token, see
+// middleware.Authenticate, count as well; the higher of the two applies.
+type Authorizer struct {
+	store Store
+}
+
+func New(store Store) *Authorizer {
+	return &Authorizer{store: store}
+}
+
+// Workspace returns member's membership of the workspace with slug if it
+// allows action.
+func (a *Authorizer) Workspace(ctx context.Context, member, slug string, action Action) (Membership, error) {
+	if member == "" {
+		return Membership{}, ErrUnauthenticated
+	}
+	row, err := a.store.GetMembership(ctx, sqlc.GetMembershipParams{Slug: slug, Member: member})
+	if err == nil {
+		return check(ctx, Membership{
+			WorkspaceID: row.ID,
+			Slug:        row.Slug,
+			Name:        row.Name,
+			Member:      row.Member,
+			Role:        Role(row.Role),
+		}, action)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) || grantedRole(ctx, slug) == "" {
+		return Membership{}, membershipError(err)
+	}
+
+	ws, err := a.store.GetWorkspaceBySlug(ctx, slug)
+	if err != nil {
+		return Membership{}, membershipError(err)
+	}
+	return check(ctx, Membership{WorkspaceID: ws.ID, Slug: ws.Slug, Name: ws.Name, Member: member}, action)
+}
+
+// WorkspaceByID is Workspace for a workspace known by its ID, such as the
+// workspace of a link.
+func (a *Authorizer) WorkspaceByID(ctx context.Context, member string, id int64, action Action) (Membership, error) {
+	if member == "" {
+		return Membership{}, ErrUnauthenticated
+	}
+	row, err := a.store.GetMembershipByID(ctx, sqlc.GetMembershipByIDParams{ID: id, Member: member})
+	if err == nil {
+		return check(ctx, Membership{
+			WorkspaceID: row.ID,
+			Slug:        row.Slug,
+			Name:        row.Name,
+			Member:      row.Member,
+			Role:        Role(row.Role),
+		}, action)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) || len(middleware.WorkspaceRolesFromContext(ctx)) == 0 {
+		return Membership{}, membershipError(err)
+	}
+
+	ws, err := a.store.GetWorkspaceByID(ctx, id)
+	if err != nil {
+		return Membership{}, membershipError(err)
+	}
+	if grantedRole(ctx, ws.Slug) == "" {
+		return Membership{}, ErrNotMember
+	}
+	return check(ctx, Membership{WorkspaceID: ws.ID, Slug: ws.Slug, Name: ws.Name, Member: member}, action)
+}
+
+// check raises m.Role to the role the caller's token grants, if higher, and
+// checks that it allows action.
+func check(ctx context.Context, m Membership, action Action) (Membership, error) {
+	if granted := grantedRole(ctx, m.Slug); roleRanks[granted] > roleRanks[m.Role] {
+		m.Role = granted
+	}
+	if !m.Role.Can(action) {
+		return m, ErrForbidden
+	}
+	return m, nil
+}
+
+// grantedRole returns the valid role the caller's token grants in the
+// workspace with slug, or "".
+func grantedRole(ctx context.Context, slug string) Role {
+	role, err := ParseRole(middleware.WorkspaceRolesFromContext(ctx)[slug])
+	if err != nil {
+		return ""
+	}
+	return role
+}
+
+func membershipError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotMember
+	}
+	return fmt.Errorf("authz: could not load membership: %w", err)
+}
@@ -0,0 +1,183 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleCan(t *testing.T) {
+	testCases := []struct {
+		role    Role
+		allowed []Action
+		denied  []Action
+	}{
+		{role: RoleViewer, allowed: []Action{View}, denied: []Action{CreateLinks, ManageWorkspace, ManageMembers, ManageOwners}},
+		{role: RoleEditor, allowed: []Action{View, CreateLinks}, denied: []Action{ManageWorkspace, ManageMembers, ManageOwners}},
+		{role: RoleAdmin, allowed: []Action{View, CreateLinks, ManageWorkspace, ManageMembers}, denied: []Action{ManageOwners}},
+		{role: RoleOwner, allowed: []Action{View, CreateLinks, ManageWorkspace, ManageMembers, ManageOwners}},
+		{role: Role("guest"), denied: []Action{View}},
+	}
+
+	for _, tc := range testCases {
+		t.Run("Test the "+string(tc.role)+" role", func(t *testing.T) {
+			for _, a := range tc.allowed {
+				assert.True(t, tc.role.Can(a), "action %d", a)
+			}
+			for _, a := range tc.denied {
+				assert.False(t, tc.role.Can(a), "action %d", a)
+			}
+		})
+	}
+}
+
+// fakeStore holds the roles of members by workspace slug. Workspace IDs are
+// the position of the slug in order, starting at 1.
+type fakeStore struct {
+	order []string
+	roles map[string]map[string]string
+}
+
+func (s fakeStore) GetMembership(_ context.Context, arg sqlc.GetMembershipParams) (sqlc.GetMembershipRow, error) {
+	for i, slug := range s.order {
+		if slug != arg.Slug {
+			continue
+		}
+		role, ok := s.roles[slug][arg.Member]
+		if !ok {
+			break
+		}
+		return sqlc.GetMembershipRow{ID: int64(i + 1), Slug: slug, Name: slug, Member: arg.Member, Role: role}, nil
+	}
+	return sqlc.GetMembershipRow{}, pgx.ErrNoRows
+}
+
+func (s fakeStore) GetMembershipByID(ctx context.Context, arg sqlc.GetMembershipByIDParams) (sqlc.GetMembershipByIDRow, error) {
+	if arg.ID < 1 || int(arg.ID) > len(s.order) {
+		return sqlc.GetMembershipByIDRow{}, pgx.ErrNoRows
+	}
+	row, err := s.GetMembership(ctx, sqlc.GetMembershipParams{Slug: s.order[arg.ID-1], Member: arg.Member})
+	return sqlc.GetMembershipByIDRow(row), err
+}
+
+func (s fakeStore) GetWorkspaceBySlug(_ context.Context, slug string) (sqlc.Workspace, error) {
+	for i, o := range s.order {
+		if o == slug {
+			return sqlc.Workspace{ID: int64(i + 1), Slug: slug, Name: slug}, nil
+		}
+	}
+	return sqlc.Workspace{}, pgx.ErrNoRows
+}
+
+func (s fakeStore) GetWorkspaceByID(ctx context.Context, id int64) (sqlc.Workspace, error) {
+	if id < 1 || int(id) > len(s.order) {
+		return sqlc.Workspace{}, pgx.ErrNoRows
+	}
+	return s.GetWorkspaceBySlug(ctx, s.order[id-1])
+}
+
+func TestRequire(t *testing.T) {
+	a := New(fakeStore{
+		order: []string{"growth", "support"},
+		roles: map[string]map[string]string{
+			"growth":  {"alice": "editor", "bob": "viewer"},
+			"support": {"carol": "owner"},
+		},
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var seen Membership
+	mux := http.NewServeMux()
+	mux.Handle("POST /w/{workspace}/links", a.Require(CreateLinks, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	})))
+
+	testCases := []struct {
+		name      string
+		member    string
+		workspace string
+		expected  int
+	}{
+		{name: "Test an editor creating links", member: "alice", workspace: "growth", expected: http.StatusOK},
+		{name: "Test a viewer creating links", member: "bob", workspace: "growth", expected: http.StatusForbidden},
+		{name: "Test a member of another workspace", member: "carol", workspace: "growth", expected: http.StatusNotFound},
+		{name: "Test an unknown workspace", member: "alice", workspace: "sales", expected: http.StatusNotFound},
+		{name: "Test an anonymous caller", workspace: "growth", expected: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen = Membership{}
+			req := httptest.NewRequest(http.MethodPost, "/w/"+tc.workspace+"/links", nil)
+			if tc.member != "" {
+				req = req.WithContext(middleware.WithOwner(req.Context(), tc.member))
+			}
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expected, rec.Code)
+			if tc.expected == http.StatusOK {
+				assert.Equal(t, Membership{WorkspaceID: 1, Slug: "growth", Name: "growth", Member: "alice", Role: RoleEditor}, seen)
+			}
+		})
+	}
+}
+
+func TestWorkspaceByID(t *testing.T) {
+	a := New(fakeStore{
+		order: []string{"growth"},
+		roles: map[string]map[string]string{"growth": {"alice": "admin"}},
+	})
+	ctx := context.Background()
+
+	m, err := a.WorkspaceByID(ctx, "alice", 1, ManageMembers)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, m.Role)
+
+	_, err = a.WorkspaceByID(ctx, "alice", 1, ManageOwners)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = a.WorkspaceByID(ctx, "mallory", 1, View)
+	assert.ErrorIs(t, err, ErrNotMember)
+	_, err = a.WorkspaceByID(ctx, "", 1, View)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestTokenGrants(t *testing.T) {
+	a := New(fakeStore{
+		order: []string{"growth", "support"},
+		roles: map[string]map[string]string{"growth": {"alice": "viewer"}},
+	})
+	ctx := middleware.WithWorkspaceRoles(context.Background(), map[string]string{
+		"growth":  "editor",
+		"support": "viewer",
+		"sales":   "owner",
+	})
+
+	m, err := a.Workspace(ctx, "alice", "growth", CreateLinks)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleEditor, m.Role)
+
+	m, err = a.WorkspaceByID(ctx, "alice", 2, View)
+	assert.NoError(t, err)
+	assert.Equal(t, Membership{WorkspaceID: 2, Slug: "support", Name: "support", Member: "alice", Role: RoleViewer}, m)
+
+	_, err = a.Workspace(ctx, "alice", "sales", View)
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	// Unknown granted roles are ignored.
+	ctx = middleware.WithWorkspaceRoles(context.Background(), map[string]string{"growth": "bogus"})
+	m, err = a.Workspace(ctx, "alice", "growth", View)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, m.Role)
+	_, err = a.Workspace(ctx, "alice", "support", View)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
@@ -0,0 +1,56 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+type contextKey struct{}
+
+// WithMembership returns a copy of ctx carrying m.
+func WithMembership(ctx context.Context, m Membership) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext returns the membership stored by Require.
+func FromContext(ctx context.Context) (Membership, bool) {
+	m, ok := ctx.Value(contextKey{}).(Membership)
+	return m, ok
+}
+
+// Require returns middleware that lets a request through only when the
+// caller's role in the {workspace} of the path allows action. The membership
+// is then available to the handler through FromContext.
+func (a *Authorizer) Require(action Action, logger *slog.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m, err := a.Workspace(r.Context(), middleware.OwnerFromContext(r.Context()), r.PathValue("workspace"), action)
+			if err != nil {
+				WriteError(w, r, logger, err, "Workspace not found")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithMembership(r.Context(), m)))
+		})
+	}
+}
+
+// WriteError responds to a failed authorization. Non-members get a 404 with
+// notFound as message, the same answer as for something that does not exist.
+func WriteError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error, notFound string) {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		utils.RespondWithError(w, http.StatusUnauthorized, "An API key or bearer token is required")
+	case errors.Is(err, ErrNotMember):
+		utils.RespondWithError(w, http.StatusNotFound, notFound)
+	case errors.Is(err, ErrForbidden):
+		utils.RespondWithError(w, http.StatusForbidden, "Your role in this workspace does not allow this")
+	default:
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check permissions")
+		middleware.LoggerFromContext(r.Context(), logger).Error("authorization failed", "err", err)
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/cache"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	"github.com/nouvadev/veritas/pkg/outbox"
+	"github.com/nouvadev/veritas/pkg/reputation"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// Report statuses.
+const (
+	reportStatusOpen      = "open"
+	reportStatusDismissed = "dismissed"
+	reportStatusActioned  = "actioned"
+)
+
+// Reasons a link can be disabled for, stored in urls.disabled_reason.
+// reputation.DisabledReason is used by the automatic rescan.
+const (
+	DisabledReasonAbuse = "abuse"
+	DisabledReasonLegal = "legal"
+)
+
+var disabledReasons = map[string]bool{
+	reputation.DisabledReason: true,
+	DisabledReasonAbuse:       true,
+	DisabledReasonLegal:       true,
+}
+
+const (
+	defaultReportPageSize = 50
+	maxReportPageSize     = 200
+)
+
+// AdminHandler serves the moderation endpoints used to review abuse reports
+// and take links down.
+type AdminHandler struct {
+	App *config.AppConfig
+}
+
+type AdminReport struct {
+	ID           int64      `json:"id"`
+	ShortCode    string     `json:"short_code"`
+	Domain       string     `json:"domain,omitempty"`
+	OriginalURL  string     `json:"original_url"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details,omitempty"`
+	ReporterIP   string     `json:"reporter_ip,omitempty"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	LinkDisabled bool       `json:"link_disabled"`
+}
+
+type AdminReportsResponse struct {
+	Reports []AdminReport `json:"reports"`
+	// NextBefore is passed as ?before= to fetch the next page.
+	NextBefore int64 `json:"next_before,omitempty"`
+}
+
+type DisableLinkRequest struct {
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
+}
+
+type LinkStatusResponse struct {
+	ShortCode string `json:"short_code"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func NewAdminHandler(app *config.AppConfig) *AdminHandler {
+	return &AdminHandler{App: app}
+}
+
+// ListReports lists reports with the given ?status= (open by default), newest first.
+func (h *AdminHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	q := r.URL.Query()
+
+	status := q.Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	switch status {
+	case reportStatusOpen, reportStatusDismissed, reportStatusActioned:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Status must be one of open, dismissed, actioned")
+		return
+	}
+
+	limit := defaultReportPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxReportPageSize {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	before := int64(math.MaxInt64)
+	if v := q.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid before")
+			return
+		}
+		before = n
+	}
+
+	rows, err := h.App.Querier.ListReports(r.Context(), database.ListReportsParams{
+		Status: status,
+		ID:     before,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list reports")
+		logger.Error("Failed to list reports", "error", err)
+		return
+	}
+
+	resp := AdminReportsResponse{Reports: make([]AdminReport, 0, len(rows))}
+	for _, row := range rows {
+		resp.Reports = append(resp.Reports, AdminReport{
+			ID:           row.ID,
+			ShortCode:    row.ShortCode,
+			Domain:       row.Domain.String,
+			OriginalURL:  row.OriginalUrl,
+			Reason:       row.Reason,
+			Details:      row.Details,
+			ReporterIP:   row.ReporterIp,
+			Status:       row.Status,
+			CreatedAt:    row.CreatedAt,
+			ResolvedAt:   timePtr(row.ResolvedAt),
+			LinkDisabled: row.DisabledAt.Valid,
+		})
+	}
+	if len(rows) == limit {
+		resp.NextBefore = rows[len(rows)-1].ID
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// DismissReport closes an open report without acting on the link.
+func (h *AdminHandler) DismissReport(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid report id")
+		return
+	}
+
+	n, err := h.App.Querier.ResolveReport(r.Context(), database.ResolveReportParams{ID: id, Status: reportStatusDismissed})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to dismiss report")
+		logger.Error("Failed to dismiss report", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusNotFound, "No open report with this id")
+		return
+	}
+
+	logger.Info("report dismissed", "report_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableLink takes a link down, closes its open reports as actioned and
+// evicts it from the redirect cache.
+func (h *AdminHandler) DisableLink(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	shortCode := r.PathValue("code")
+
+	var req DisableLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+	if !disabledReasons[req.Reason] {
+		utils.RespondWithError(w, http.StatusBadRequest, "Reason must be one of malicious, abuse, legal")
+		return
+	}
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain, shortCode)
+	if !ok {
+		return
+	}
+
+	disabled := link
+	disabled.DisabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	disabled.DisabledReason = pgtype.Text{String: req.Reason, Valid: true}
+
+	var n int64
+	err := inTx(r.Context(), h.App, func(q *database.Queries) error {
+		var err error
+		n, err = q.DisableURL(r.Context(), database.DisableURLParams{
+			ID:             link.ID,
+			DisabledReason: disabled.DisabledReason,
+			DisabledNote:   pgtype.Text{String: strings.TrimSpace(req.Note), Valid: req.Note != ""},
+		})
+		if err != nil || n == 0 {
+			return err
+		}
+		return outbox.Add(r.Context(), q, events.SubjectLinkUpdated, events.LinkUpdated(eventLink(h.App, domain, disabled), "status", "disabled_reason"))
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to disable link")
+		logger.Error("Failed to disable link", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "Link is already disabled")
+		return
+	}
+
+	err = h.App.Querier.ResolveReportsForURL(r.Context(), database.ResolveReportsForURLParams{UrlID: link.ID, Status: reportStatusActioned})
+	if err != nil {
+		logger.Error("Failed to resolve reports for disabled link", "short_code", shortCode, "error", err)
+	}
+	evictLink(r, h.App, cache.LinkKey(domain.Hostname, shortCode))
+
+	logger.Warn("link disabled", "short_code", shortCode, "reason", req.Reason)
+	utils.RespondWithJSON(w, http.StatusOK, LinkStatusResponse{ShortCode: shortCode, Status: "disabled", Reason: req.Reason})
+}
+
+// RestoreLink re-enables a disabled link.
+func (h *AdminHandler) RestoreLink(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	shortCode := r.PathValue("code")
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain, shortCode)
+	if !ok {
+		return
+	}
+
+	restored := link
+	restored.DisabledAt, restored.DisabledReason = pgtype.Timestamptz{}, pgtype.Text{}
+
+	var n int64
+	err := inTx(r.Context(), h.App, func(q *database.Queries) error {
+		var err error
+		if n, err = q.RestoreURL(r.Context(), link.ID); err != nil || n == 0 {
+			return err
+		}
+		return outbox.Add(r.Context(), q, events.SubjectLinkUpdated, events.LinkUpdated(eventLink(h.App, domain, restored), "status", "disabled_reason"))
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to restore link")
+		logger.Error("Failed to restore link", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "Link is not disabled")
+		return
+	}
+
+	logger.Info("link restored", "short_code", shortCode)
+	utils.RespondWithJSON(w, http.StatusOK, LinkStatusResponse{ShortCode: shortCode, Status: "active"})
+}
+
+func (h *AdminHandler) getLink(w http.ResponseWriter, r *http.Request, domain linkDomain, shortCode string) (database.Url, bool) {
+	link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{
+		ShortCode: shortCode,
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+			middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		}
+		return link, false
+	}
+	return link, true
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/domains"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint violation.
+const uniqueViolation = "23505"
+
+// linkDomain is the code namespace a link lives in. The zero value is the
+// default domain, BASE_URL.
+type linkDomain struct {
+	ID       pgtype.Int8
+	Hostname string
+}
+
+// domainFromQuery resolves the optional ?domain= parameter that selects the
+// namespace of the {code} in API paths. When it returns false a response has
+// already been written.
+func domainFromQuery(w http.ResponseWriter, r *http.Request, app *config.AppConfig) (linkDomain, bool) {
+	raw := r.URL.Query().Get("domain")
+	if raw == "" {
+		return linkDomain{}, true
+	}
+
+	hostname, err := domains.NormalizeHost(raw)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid domain")
+		return linkDomain{}, false
+	}
+	d, err := app.Querier.GetDomainByHostname(r.Context(), hostname)
+	if err != nil || !d.VerifiedAt.Valid {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get domain")
+			middleware.LoggerFromContext(r.Context(), app.Logger).Error("db error", "err", err)
+			return linkDomain{}, false
+		}
+		utils.RespondWithError(w, http.StatusNotFound, "Domain not found")
+		return linkDomain{}, false
+	}
+	return linkDomain{ID: pgtype.Int8{Int64: d.ID, Valid: true}, Hostname: d.Hostname}, true
+}
+
+// shortURL builds the public URL of a link. Custom domains are served with
+// the same scheme as BASE_URL.
+func shortURL(baseURL, domain, shortCode string) string {
+	if domain == "" {
+		return fmt.Sprintf("%s/%s", baseURL, shortCode)
+	}
+	scheme := "https"
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, domain, shortCode)
+}
+
+// DomainHandler lets workspaces register custom short domains and prove they
+// control them. Its endpoints run behind authz.Require.
+type DomainHandler struct {
+	App      *config.AppConfig
+	verifier *domains.Verifier
+}
+
+type DomainRequest struct {
+	Hostname string `json:"hostname"`
+}
+
+// DomainResponse describes a domain and, until it is verified, the TXT
+// record that proves control of it.
+type DomainResponse struct {
+	Hostname   string     `json:"hostname"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	TXTRecord  *TXTRecord `json:"txt_record,omitempty"`
+}
+
+type TXTRecord struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func NewDomainHandler(app *config.AppConfig) *DomainHandler {
+	return &DomainHandler{
+		App:      app,
+		verifier: domains.NewVerifier(app.Config.Domains.Resolver, app.Config.Domains.VerifyTimeout),
+	}
+}
+
+// CreateDomain registers a domain for the workspace. It serves no links until
+// VerifyDomain succeeds.
+func (h *DomainHandler) CreateDomain(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	var req DomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	hostname, err := domains.NormalizeHost(req.Hostname)
+	if err == nil {
+		err = domains.ValidateHostname(hostname)
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid hostname")
+		return
+	}
+	if u, err := url.Parse(h.App.Config.BaseURL); err == nil && u.Hostname() == hostname {
+		utils.RespondWithError(w, http.StatusBadRequest, "Hostname is the default domain")
+		return
+	}
+
+	d, err := h.App.Querier.CreateDomain(r.Context(), database.CreateDomainParams{
+		Hostname:          hostname,
+		Owner:             m.Member,
+		VerificationToken: domains.NewToken(),
+		WorkspaceID:       m.WorkspaceID,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			utils.RespondWithError(w, http.StatusConflict, "Domain is already registered")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create domain")
+		logger.Error("Failed to create domain", "error", err)
+		return
+	}
+
+	logger.Info("domain registered", "hostname", hostname, "workspace", m.Slug, "member", m.Member)
+	utils.RespondWithJSON(w, http.StatusCreated, toDomainResponse(d))
+}
+
+// ListDomains returns the workspace's domains.
+func (h *DomainHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	rows, err := h.App.Querier.ListDomainsByWorkspace(r.Context(), m.WorkspaceID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list domains")
+		logger.Error("Failed to list domains", "error", err)
+		return
+	}
+
+	resp := make([]DomainResponse, 0, len(rows))
+	for _, d := range rows {
+		resp = append(resp, toDomainResponse(d))
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// VerifyDomain checks the domain's TXT challenge and, when it is in place,
+// marks the domain verified so links can be created under it.
+func (h *DomainHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	hostname, err := domains.NormalizeHost(r.PathValue("hostname"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid hostname")
+		return
+	}
+
+	d, err := h.App.Querier.GetDomainByHostname(r.Context(), hostname)
+	if err != nil || d.WorkspaceID != m.WorkspaceID {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get domain")
+			logger.Error("db error", "err", err)
+			return
+		}
+		// Other workspaces' domains are reported as missing, not forbidden.
+		utils.RespondWithError(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+	if d.VerifiedAt.Valid {
+		utils.RespondWithJSON(w, http.StatusOK, toDomainResponse(d))
+		return
+	}
+
+	if err := h.verifier.Verify(r.Context(), d.Hostname, d.VerificationToken); err != nil {
+		if errors.Is(err, domains.ErrNotVerified) {
+			utils.RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadGateway, "DNS lookup failed")
+		logger.Warn("domain verification lookup failed", "hostname", d.Hostname, "err", err)
+		return
+	}
+
+	d.VerifiedAt, err = h.App.Querier.MarkDomainVerified(r.Context(), d.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify domain")
+		logger.Error("Failed to mark domain verified", "error", err)
+		return
+	}
+
+	logger.Info("domain verified", "hostname", d.Hostname, "workspace", m.Slug, "member", m.Member)
+	utils.RespondWithJSON(w, http.StatusOK, toDomainResponse(d))
+}
+
+func toDomainResponse(d database.Domain) DomainResponse {
+	resp := DomainResponse{
+		Hostname:   d.Hostname,
+		Verified:   d.VerifiedAt.Valid,
+		VerifiedAt: timePtr(d.VerifiedAt),
+		CreatedAt:  d.CreatedAt,
+	}
+	if !resp.Verified {
+		resp.TXTRecord = &TXTRecord{
+			Name:  domains.ChallengeRecord(d.Hostname),
+			Value: domains.ChallengeValue(d.VerificationToken),
+		}
+	}
+	return resp
+}
@@ -3,29 +3,70 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/nouvadev/veritas/pkg/health"
+	"github.com/nouvadev/veritas/pkg/utils"
 )
 
+// readinessCacheTTL is how long a readiness report is reused before the
+// dependencies are probed again.
+const readinessCacheTTL = 2 * time.Second
+
 type HealthcheckHandler struct {
-	App *config.AppConfig
+	App     *config.AppConfig
+	checker *health.Checker
 }
 
 func NewHealthcheckHandler(app *config.AppConfig) *HealthcheckHandler {
-	return &HealthcheckHandler{App: app}
+	return &HealthcheckHandler{
+		App:     app,
+		checker: health.NewChecker(readinessCacheTTL, health.FromApp(app)...),
+	}
 }
 
 func (h *HealthcheckHandler) HealthcheckHandler(w http.ResponseWriter, r *http.Request) {
-	health := map[string]string{
+	body := map[string]string{
 		"status": "ok",
 	}
+	status := http.StatusOK
+
+	if h.App.Draining.Load() {
+		body["status"] = "draining"
+		status = http.StatusServiceUnavailable
+	}
 
-	js, err := json.Marshal(health)
+	js, err := json.Marshal(body)
 	if err != nil {
 		http.Error(w, "error marshalling healthcheck", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	w.Write(js)
 }
+
+// Livez reports whether the process is able to serve requests at all. It does
+// not look at dependencies, so an outage of Postgres or Redis never gets the
+// pod restarted.
+func (h *HealthcheckHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"status": health.StatusUp})
+}
+
+// Readyz reports whether the service should receive traffic: every dependency
+// must answer within its timeout and the service must not be shutting down.
+func (h *HealthcheckHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Report(r.Context())
+
+	status := http.StatusOK
+	if h.App.Draining.Load() {
+		report.Status = health.StatusDraining
+	}
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	utils.RespondWithJSON(w, status, report)
+}
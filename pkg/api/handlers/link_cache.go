@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+)
+
+// linkCacheTTL is how long a resolved link stays in the redirect cache.
+const linkCacheTTL = 1 * time.Hour
+
+// cachedLink is what the redirector keeps in Redis per short code: enough to
+// serve the redirect or preview page without touching Postgres.
+type cachedLink struct {
+	// ID attributes clicks to the link in analytics.
+	ID           int64  `json:"id,omitempty"`
+	URL          string `json:"url"`
+	Interstitial bool   `json:"interstitial,omitempty"`
+	Title        string `json:"title,omitempty"`
+	// WorkspaceID is sent with clicks so workspace webhooks receive them.
+	WorkspaceID int64 `json:"workspace_id,omitempty"`
+
+	// The Open Graph values served to social crawlers: the link's overrides,
+	// falling back to the destination's metadata.
+	PreviewTitle       string `json:"preview_title,omitempty"`
+	PreviewDescription string `json:"preview_description,omitempty"`
+	PreviewImage       string `json:"preview_image,omitempty"`
+	SiteName           string `json:"site_name,omitempty"`
+}
+
+// getCachedLink returns redis.Nil when the link is not cached. key is built
+// with cache.LinkKey.
+func (h *URLHandler) getCachedLink(ctx context.Context, key string) (cachedLink, error) {
+	val, err := h.App.Cache.Get(ctx, key).Result()
+	if err != nil {
+		return cachedLink{}, err
+	}
+
+	// Entries written before links carried options hold the bare URL.
+	if !strings.HasPrefix(val, "{") {
+		return cachedLink{URL: val}, nil
+	}
+
+	var link cachedLink
+	if err := json.Unmarshal([]byte(val), &link); err != nil {
+		return cachedLink{}, err
+	}
+	return link, nil
+}
+
+func (h *URLHandler) cacheLink(ctx context.Context, key string, link cachedLink) error {
+	b, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return h.App.Cache.Set(ctx, key, b, linkCacheTTL).Err()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// evictLink removes a link from the redirector's cache so a change takes
+// effect immediately rather than when the cache entry expires.
+func evictLink(r *http.Request, app *config.AppConfig, key string) {
+	logger := middleware.LoggerFromContext(r.Context(), app.Logger)
+	if app.Cache == nil {
+		logger.Warn("REDIS_URL is not set, link stays cached until it expires", "key", key)
+		return
+	}
+	if err := app.Cache.Del(r.Context(), key).Err(); err != nil {
+		logger.Error("failed to evict link from cache", "key", key, "err", err)
+	}
+}
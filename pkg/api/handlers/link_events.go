@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/nouvadev/veritas/pkg/config"
+	db "github.com/nouvadev/veritas/pkg/database"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/nouvadev/veritas/pkg/outbox"
+)
+
+// inTx runs fn in a transaction, so the events it adds to the outbox are
+// committed with its changes, and wakes the relay up once they are.
+func inTx(ctx context.Context, app *config.AppConfig, fn func(q *database.Queries) error) error {
+	if err := db.InTx(ctx, app.DB, fn); err != nil {
+		return err
+	}
+	app.Outbox.Notify()
+	return nil
+}
+
+// eventLink describes u in lifecycle events.
+func eventLink(app *config.AppConfig, domain linkDomain, u database.Url) *eventsv1.Link {
+	link := &eventsv1.Link{
+		Id:             u.ID,
+		ShortCode:      u.ShortCode,
+		Domain:         domain.Hostname,
+		ShortUrl:       shortURL(app.Config.BaseURL, domain.Hostname, u.ShortCode),
+		OriginalUrl:    u.OriginalUrl,
+		Title:          u.Title.String,
+		Interstitial:   u.Interstitial,
+		WorkspaceId:    u.WorkspaceID.Int64,
+		Owner:          u.Owner.String,
+		Status:         "active",
+		DisabledReason: u.DisabledReason.String,
+	}
+	if u.DisabledAt.Valid {
+		link.Status = "disabled"
+	}
+	return link
+}
+
+// RescanDisable returns the reputation.Rescanner hook that disables a link
+// and records a LinkUpdated event for it in the same transaction.
+func RescanDisable(app *config.AppConfig) func(context.Context, database.ListEnabledURLsRow, database.DisableURLParams) (int64, error) {
+	return func(ctx context.Context, row database.ListEnabledURLsRow, arg database.DisableURLParams) (int64, error) {
+		link := &eventsv1.Link{
+			Id:             row.ID,
+			ShortCode:      row.ShortCode,
+			Domain:         row.Domain.String,
+			ShortUrl:       shortURL(app.Config.BaseURL, row.Domain.String, row.ShortCode),
+			OriginalUrl:    row.OriginalUrl,
+			Title:          row.Title.String,
+			Interstitial:   row.Interstitial,
+			WorkspaceId:    row.WorkspaceID.Int64,
+			Owner:          row.Owner.String,
+			Status:         "disabled",
+			DisabledReason: arg.DisabledReason.String,
+		}
+
+		var n int64
+		err := inTx(ctx, app, func(q *database.Queries) error {
+			var err error
+			if n, err = q.DisableURL(ctx, arg); err != nil || n == 0 {
+				return err
+			}
+			return outbox.Add(ctx, q, events.SubjectLinkUpdated, events.LinkUpdated(link, "status", "disabled_reason"))
+		})
+		return n, err
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/cache"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/outbox"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// LinkDetails is the public view of a short link.
+type LinkDetails struct {
+	ShortCode    string    `json:"short_code"`
+	ShortURL     string    `json:"short_url"`
+	Domain       string    `json:"domain,omitempty"`
+	Workspace    string    `json:"workspace,omitempty"`
+	OriginalURL  string    `json:"original_url"`
+	Title        string    `json:"title,omitempty"`
+	Interstitial bool      `json:"interstitial"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	Owner        string    `json:"owner,omitempty"`
+	// Preview holds the Open Graph overrides set when the link was created.
+	Preview *LinkPreview `json:"preview,omitempty"`
+	// Metadata is nil until the destination has been fetched.
+	Metadata          *metadata.Metadata `json:"metadata,omitempty"`
+	MetadataFetchedAt *time.Time         `json:"metadata_fetched_at,omitempty"`
+	MetadataError     string             `json:"metadata_error,omitempty"`
+}
+
+// GetLinkDetails returns a link together with the metadata fetched from its destination.
+func (h *URLHandler) GetLinkDetails(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	shortCode := r.PathValue("code")
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{
+		ShortCode: shortCode,
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+		logger.Error("db error", "err", err)
+		return
+	}
+	m, ok := authorizeLink(w, r, h.App, h.authorizer, link.WorkspaceID, authz.View)
+	if !ok {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, h.linkDetails(r, domain, m.Slug, link))
+}
+
+// UpdateLinkRequest holds the fields of a link to change; absent fields are
+// left as they are.
+type UpdateLinkRequest struct {
+	OriginalURL  *string `json:"original_url"`
+	Title        *string `json:"title"`
+	Interstitial *bool   `json:"interstitial"`
+}
+
+// UpdateLink changes the destination, title or interstitial setting of a
+// workspace link. Public links cannot be changed, as nobody owns them.
+func (h *URLHandler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	var req UpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	domain, link, m, ok := h.workspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	params := database.UpdateURLParams{
+		ID:           link.ID,
+		OriginalUrl:  link.OriginalUrl,
+		Title:        link.Title,
+		Interstitial: link.Interstitial,
+	}
+	if req.OriginalURL != nil && *req.OriginalURL != link.OriginalUrl {
+		if !utils.ValidateURL(*req.OriginalURL) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid URL")
+			return
+		}
+		if h.App.Reputation != nil {
+			verdict, err := h.App.Reputation.Check(r.Context(), *req.OriginalURL)
+			if err != nil {
+				logger.Warn("reputation check failed, accepting URL", "url", *req.OriginalURL, "error", err)
+			} else if verdict.Blocked {
+				metrics.BlockedURLs.WithLabelValues("update", verdict.List).Inc()
+				utils.RespondWithError(w, http.StatusUnprocessableEntity, "URL is flagged as malicious")
+				logger.Warn("Rejected malicious URL", "url", *req.OriginalURL, "list", verdict.List, "match", verdict.Match)
+				return
+			}
+		}
+		params.OriginalUrl = *req.OriginalURL
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if len(title) > maxTitleLength {
+			utils.RespondWithError(w, http.StatusBadRequest, "Title is too long")
+			return
+		}
+		params.Title = pgtype.Text{String: title, Valid: title != ""}
+	}
+	if req.Interstitial != nil {
+		params.Interstitial = *req.Interstitial
+	}
+
+	var changed []string
+	if params.OriginalUrl != link.OriginalUrl {
+		changed = append(changed, "original_url")
+	}
+	if params.Title != link.Title {
+		changed = append(changed, "title")
+	}
+	if params.Interstitial != link.Interstitial {
+		changed = append(changed, "interstitial")
+	}
+
+	var updated database.Url
+	err := inTx(r.Context(), h.App, func(q *database.Queries) error {
+		var err error
+		if updated, err = q.UpdateURL(r.Context(), params); err != nil || len(changed) == 0 {
+			return err
+		}
+		return outbox.Add(r.Context(), q, events.SubjectLinkUpdated, events.LinkUpdated(eventLink(h.App, domain, updated), changed...))
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update URL")
+		logger.Error("Failed to update URL", "error", err)
+		return
+	}
+	key := cache.LinkKey(domain.Hostname, updated.ShortCode)
+	evictLink(r, h.App, key)
+	if updated.OriginalUrl != link.OriginalUrl && h.App.Metadata != nil && !h.App.Metadata.Enqueue(updated.ID, key, updated.OriginalUrl) {
+		logger.Warn("metadata queue is full, skipping fetch", "id", updated.ID)
+	}
+
+	logger.Info("link updated", "short_code", updated.ShortCode, "workspace", m.Slug, "member", m.Member)
+	utils.RespondWithJSON(w, http.StatusOK, h.linkDetails(r, domain, m.Slug, updated))
+}
+
+// DeleteLink deletes a workspace link.
+func (h *URLHandler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	domain, link, m, ok := h.workspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	err := inTx(r.Context(), h.App, func(q *database.Queries) error {
+		if err := q.DeleteURL(r.Context(), link.ID); err != nil {
+			return err
+		}
+		return outbox.Add(r.Context(), q, events.SubjectLinkDeleted, events.LinkDeleted(eventLink(h.App, domain, link), events.DeletedByMember))
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete URL")
+		logger.Error("Failed to delete URL", "error", err)
+		return
+	}
+	evictLink(r, h.App, cache.LinkKey(domain.Hostname, link.ShortCode))
+
+	logger.Info("link deleted", "short_code", link.ShortCode, "workspace", m.Slug, "member", m.Member)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workspaceLink looks up the link of a {code} path the caller may change.
+// When it returns false a response has already been written.
+func (h *URLHandler) workspaceLink(w http.ResponseWriter, r *http.Request) (linkDomain, database.Url, authz.Membership, bool) {
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return linkDomain{}, database.Url{}, authz.Membership{}, false
+	}
+	link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{
+		ShortCode: r.PathValue("code"),
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+			middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		}
+		return linkDomain{}, database.Url{}, authz.Membership{}, false
+	}
+	if !link.WorkspaceID.Valid {
+		utils.RespondWithError(w, http.StatusForbidden, "Public links cannot be changed")
+		return linkDomain{}, database.Url{}, authz.Membership{}, false
+	}
+	m, ok := authorizeLink(w, r, h.App, h.authorizer, link.WorkspaceID, authz.CreateLinks)
+	if !ok {
+		return linkDomain{}, database.Url{}, authz.Membership{}, false
+	}
+	return domain, link, m, true
+}
+
+func (h *URLHandler) linkDetails(r *http.Request, domain linkDomain, workspace string, link database.Url) LinkDetails {
+	details := LinkDetails{
+		ShortCode:         link.ShortCode,
+		ShortURL:          shortURL(h.App.Config.BaseURL, domain.Hostname, link.ShortCode),
+		Domain:            domain.Hostname,
+		Workspace:         workspace,
+		OriginalURL:       link.OriginalUrl,
+		Title:             link.Title.String,
+		Interstitial:      link.Interstitial,
+		Status:            "active",
+		CreatedAt:         link.CreatedAt,
+		Owner:             link.Owner.String,
+		MetadataFetchedAt: timePtr(link.MetadataFetchedAt),
+		MetadataError:     link.MetadataError.String,
+	}
+	if link.DisabledAt.Valid {
+		details.Status = "disabled"
+	}
+	if link.PreviewTitle.Valid || link.PreviewDescription.Valid || link.PreviewImage.Valid {
+		details.Preview = &LinkPreview{
+			Title:       link.PreviewTitle.String,
+			Description: link.PreviewDescription.String,
+			Image:       link.PreviewImage.String,
+		}
+	}
+	if len(link.Metadata) > 0 {
+		var m metadata.Metadata
+		if err := json.Unmarshal(link.Metadata, &m); err != nil {
+			middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("invalid stored metadata", "short_code", link.ShortCode, "err", err)
+		} else {
+			details.Metadata = &m
+		}
+	}
+	if details.Title == "" && details.Metadata != nil {
+		details.Title = details.Metadata.BestTitle()
+	}
+	return details
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/qr"
+	"github.com/nouvadev/veritas/pkg/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// qrCachePrefix namespaces rendered QR codes in Redis.
+const qrCachePrefix = "qr:"
+
+// QRHandler renders QR codes of short links and manages workspace logos.
+type QRHandler struct {
+	App        *config.AppConfig
+	authorizer *authz.Authorizer
+}
+
+// LogoResponse describes a workspace's stored logo.
+type LogoResponse struct {
+	Workspace   string    `json:"workspace"`
+	ContentType string    `json:"content_type"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewQRHandler(app *config.AppConfig) *QRHandler {
+	return &QRHandler{App: app, authorizer: authz.New(app.Querier)}
+}
+
+// GetQRCode renders the QR code of a short URL. Rendered codes are cached by
+// a hash of everything that affects the image, which also serves as ETag.
+func (h *QRHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	shortCode := r.PathValue("code")
+
+	opts, err := qr.ParseOptions(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	withLogo := false
+	if v := r.URL.Query().Get("logo"); v != "" {
+		if withLogo, err = strconv.ParseBool(v); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "logo must be true or false")
+			return
+		}
+	}
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{
+		ShortCode: shortCode,
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+		logger.Error("db error", "err", err)
+		return
+	}
+	if _, ok := authorizeLink(w, r, h.App, h.authorizer, link.WorkspaceID, authz.View); !ok {
+		return
+	}
+	if link.DisabledAt.Valid {
+		utils.RespondWithError(w, http.StatusGone, "Link is disabled")
+		return
+	}
+
+	var logoRow database.WorkspaceLogo
+	workspace := ""
+	logoVersion := ""
+	if link.WorkspaceID.Valid {
+		workspace = strconv.FormatInt(link.WorkspaceID.Int64, 10)
+	}
+	if withLogo {
+		if !link.WorkspaceID.Valid {
+			utils.RespondWithError(w, http.StatusNotFound, "Link has no workspace logo")
+			return
+		}
+		logoRow, err = h.App.Querier.GetWorkspaceLogo(r.Context(), link.WorkspaceID.Int64)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Link has no workspace logo")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get logo")
+			logger.Error("db error", "err", err)
+			return
+		}
+		logoVersion = strconv.FormatInt(logoRow.UpdatedAt.UnixNano(), 10)
+	}
+
+	content := shortURL(h.App.Config.BaseURL, domain.Hostname, shortCode)
+	key := opts.Key(content, workspace, logoVersion)
+
+	if h.App.Cache != nil {
+		b, err := h.App.Cache.Get(r.Context(), qrCachePrefix+key).Bytes()
+		if err == nil {
+			metrics.QRCodes.WithLabelValues(opts.Format, "hit").Inc()
+			serveQRCode(w, r, opts, key, b)
+			return
+		}
+		if !errors.Is(err, redis.Nil) {
+			logger.Error("redis error", "err", err)
+		}
+	}
+
+	var logo *qr.Logo
+	if withLogo {
+		if logo, err = qr.DecodeLogo(logoRow.Image); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to decode logo")
+			logger.Error("stored logo is invalid", "workspace_id", logoRow.WorkspaceID, "err", err)
+			return
+		}
+	}
+
+	b, err := qr.Render(content, opts, logo)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	metrics.QRCodes.WithLabelValues(opts.Format, "miss").Inc()
+
+	if h.App.Cache != nil {
+		if err := h.App.Cache.Set(r.Context(), qrCachePrefix+key, b, h.App.Config.QR.CacheTTL).Err(); err != nil {
+			logger.Error("failed to cache QR code", "err", err)
+		}
+	}
+	serveQRCode(w, r, opts, key, b)
+}
+
+// serveQRCode writes a rendered code, answering conditional requests for an
+// unchanged code with 304.
+func serveQRCode(w http.ResponseWriter, r *http.Request, opts qr.Options, key string, b []byte) {
+	w.Header().Set("Content-Type", opts.ContentType())
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+key[:32]+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+}
+
+// GetLogo describes the logo of the workspace.
+func (h *QRHandler) GetLogo(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	logo, err := h.App.Querier.GetWorkspaceLogo(r.Context(), m.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "No logo uploaded")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get logo")
+		logger.Error("db error", "err", err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, LogoResponse{
+		Workspace:   m.Slug,
+		ContentType: logo.ContentType,
+		UpdatedAt:   logo.UpdatedAt,
+	})
+}
+
+// UploadLogo stores the PNG or JPEG image in the request body as the
+// workspace's logo, replacing any previous one.
+func (h *QRHandler) UploadLogo(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "Logo too large")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	logo, err := qr.DecodeLogo(data)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.App.Querier.UpsertWorkspaceLogo(r.Context(), database.UpsertWorkspaceLogoParams{
+		WorkspaceID: m.WorkspaceID,
+		ContentType: logo.ContentType,
+		Image:       logo.Data,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to store logo")
+		logger.Error("failed to store logo", "workspace", m.Slug, "err", err)
+		return
+	}
+
+	logger.Info("workspace logo updated", "workspace", m.Slug, "member", m.Member, "content_type", logo.ContentType, "bytes", len(data))
+	utils.RespondWithJSON(w, http.StatusOK, LogoResponse{
+		Workspace:   m.Slug,
+		ContentType: logo.ContentType,
+		UpdatedAt:   time.Now().UTC(),
+	})
+}
+
+// DeleteLogo removes the workspace's logo.
+func (h *QRHandler) DeleteLogo(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	n, err := h.App.Querier.DeleteWorkspaceLogo(r.Context(), m.WorkspaceID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete logo")
+		logger.Error("failed to delete logo", "workspace", m.Slug, "err", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusNotFound, "No logo uploaded")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
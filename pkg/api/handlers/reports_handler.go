@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// maxReportDetails bounds the free-text part of an abuse report.
+const maxReportDetails = 2000
+
+// reportReasons are the reasons a link can be reported for.
+var reportReasons = map[string]bool{
+	"phishing": true,
+	"malware":  true,
+	"spam":     true,
+	"illegal":  true,
+	"other":    true,
+}
+
+// ReportHandler lets anyone flag a short link for review.
+type ReportHandler struct {
+	App *config.AppConfig
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+type ReportResponse struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func NewReportHandler(app *config.AppConfig) *ReportHandler {
+	return &ReportHandler{App: app}
+}
+
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	shortCode := r.PathValue("code")
+
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+	if !reportReasons[req.Reason] {
+		utils.RespondWithError(w, http.StatusBadRequest, "Reason must be one of phishing, malware, spam, illegal, other")
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if len(req.Details) > maxReportDetails {
+		utils.RespondWithError(w, http.StatusBadRequest, "Details are too long")
+		return
+	}
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, err := h.App.Querier.GetURLByShortCode(r.Context(), database.GetURLByShortCodeParams{
+		ShortCode: shortCode,
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+		logger.Error("db error", "err", err)
+		return
+	}
+
+	id, err := h.App.Querier.CreateReport(r.Context(), database.CreateReportParams{
+		UrlID:      link.ID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		ReporterIp: utils.ClientIP(r, h.App.Config.HTTP.TrustedProxyPrefixes()),
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save report")
+		logger.Error("Failed to save report", "error", err)
+		return
+	}
+
+	logger.Info("link reported", "report_id", id, "short_code", shortCode, "reason", req.Reason)
+	utils.RespondWithJSON(w, http.StatusAccepted, ReportResponse{ID: id, Status: reportStatusOpen})
+}
@@ -0,0 +1,555 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/analytics"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/export"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+const (
+	// defaultStatsDays is the number of days reported when no range is given.
+	defaultStatsDays = 30
+	// maxStatsDays bounds the range of a stats request.
+	maxStatsDays = 366
+	// defaultBreakdownSize and maxBreakdownSize bound the values of a
+	// breakdown.
+	defaultBreakdownSize = 10
+	maxBreakdownSize     = 100
+	// notKeptMessage answers ranges whose bounds are finer than the
+	// statistics kept that far back.
+	notKeptMessage = "Statistics are not kept to the minute or hour that far back, use whole hours or days"
+	// liveRetry is how long clients of a live stream wait before reconnecting.
+	liveRetry = 3 * time.Second
+	// maxExportLinks bounds the ?link= short codes of a workspace export.
+	maxExportLinks = 100
+)
+
+// StatsHandler serves the click statistics the analytics service records.
+type StatsHandler struct {
+	App        *config.AppConfig
+	authorizer *authz.Authorizer
+	reporter   *analytics.Reporter
+	exporter   *analytics.Exporter
+}
+
+func NewStatsHandler(app *config.AppConfig) *StatsHandler {
+	visitors := analytics.NewVisitors(app.Cache, app.Config.Analytics.VisitorRetention)
+	return &StatsHandler{
+		App:        app,
+		authorizer: authz.New(app.Querier),
+		reporter:   analytics.NewReporter(app.Querier, visitors, app.Config.Analytics.Rollups().Retention),
+		exporter:   analytics.NewExporter(app.Querier),
+	}
+}
+
+// LinkStatsResponse is the clicks and unique visitors of a link per UTC day.
+// From and To are RFC 3339 timestamps, From included and To excluded.
+type LinkStatsResponse struct {
+	ShortCode string `json:"short_code"`
+	Domain    string `json:"domain,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	analytics.Summary
+}
+
+// BreakdownResponse is the clicks of a link per value of a dimension, most
+// clicked first.
+type BreakdownResponse struct {
+	ShortCode string            `json:"short_code"`
+	Domain    string            `json:"domain,omitempty"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Dimension string            `json:"dimension"`
+	Values    []analytics.Count `json:"values"`
+}
+
+// WorkspaceBreakdownResponse is the clicks of the links of a workspace per
+// value of a dimension, most clicked first.
+type WorkspaceBreakdownResponse struct {
+	Workspace string            `json:"workspace"`
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Dimension string            `json:"dimension"`
+	Values    []analytics.Count `json:"values"`
+}
+
+// GetLinkStats reports a link's clicks and unique visitors from ?from= to
+// ?to=, which default to the last 30 days. Each is a YYYY-MM-DD date, both
+// included, or an RFC 3339 timestamp, to the minute, from included and to
+// excluded. ?exclude_bots=true leaves out the clicks of bots.
+func (h *StatsHandler) GetLinkStats(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	query, ok := statsQuery(w, r)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain)
+	if !ok {
+		return
+	}
+	query.LinkID = link.ID
+
+	summary, err := h.reporter.Link(r.Context(), query)
+	if errors.Is(err, analytics.ErrNotKept) {
+		utils.RespondWithError(w, http.StatusBadRequest, notKeptMessage)
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get stats")
+		logger.Error("Failed to get link stats", "short_code", link.ShortCode, "error", err)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, LinkStatsResponse{
+		ShortCode: link.ShortCode,
+		Domain:    domain.Hostname,
+		From:      query.From.Format(time.RFC3339),
+		To:        query.To.Format(time.RFC3339),
+		Summary:   summary,
+	})
+}
+
+// GetLinkBreakdown reports a link's clicks per value of the {dimension}
+// path, such as the browser or the referrer, over the same range as
+// GetLinkStats. ?limit= bounds the values returned.
+func (h *StatsHandler) GetLinkBreakdown(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	dimension, limit, ok := breakdownParams(w, r)
+	if !ok {
+		return
+	}
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	query, ok := statsQuery(w, r)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain)
+	if !ok {
+		return
+	}
+	query.LinkID = link.ID
+
+	values, err := h.reporter.Breakdown(r.Context(), query, dimension, limit)
+	if errors.Is(err, analytics.ErrNotKept) {
+		utils.RespondWithError(w, http.StatusBadRequest, notKeptMessage)
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get stats")
+		logger.Error("Failed to get link breakdown", "short_code", link.ShortCode, "dimension", dimension, "error", err)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, BreakdownResponse{
+		ShortCode: link.ShortCode,
+		Domain:    domain.Hostname,
+		From:      query.From.Format(time.RFC3339),
+		To:        query.To.Format(time.RFC3339),
+		Dimension: dimension,
+		Values:    values,
+	})
+}
+
+// GetWorkspaceBreakdown reports the clicks of all the links of a workspace
+// like GetLinkBreakdown. It runs behind authz.Require.
+func (h *StatsHandler) GetWorkspaceBreakdown(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	dimension, limit, ok := breakdownParams(w, r)
+	if !ok {
+		return
+	}
+	query, ok := statsQuery(w, r)
+	if !ok {
+		return
+	}
+	query.WorkspaceID = m.WorkspaceID
+
+	values, err := h.reporter.Breakdown(r.Context(), query, dimension, limit)
+	if errors.Is(err, analytics.ErrNotKept) {
+		utils.RespondWithError(w, http.StatusBadRequest, notKeptMessage)
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get stats")
+		logger.Error("Failed to get workspace breakdown", "workspace", m.Slug, "dimension", dimension, "error", err)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, WorkspaceBreakdownResponse{
+		Workspace: m.Slug,
+		From:      query.From.Format(time.RFC3339),
+		To:        query.To.Format(time.RFC3339),
+		Dimension: dimension,
+		Values:    values,
+	})
+}
+
+// StreamLinkClicks streams a link's clicks as Server-Sent Events while they
+// are recorded: a "click" event per click with its country, device and
+// referrer, and a final "close" event if the service ends the stream, because
+// the client fell behind or the service is shutting down.
+// ?exclude_bots=true leaves out the clicks of bots.
+func (h *StatsHandler) StreamLinkClicks(w http.ResponseWriter, r *http.Request) {
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain)
+	if !ok {
+		return
+	}
+	h.stream(w, r, link.ID, 0)
+}
+
+// StreamWorkspaceClicks streams the clicks of all the links of a workspace
+// like StreamLinkClicks. It runs behind authz.Require.
+func (h *StatsHandler) StreamWorkspaceClicks(w http.ResponseWriter, r *http.Request) {
+	m, _ := authz.FromContext(r.Context())
+	h.stream(w, r, 0, m.WorkspaceID)
+}
+
+// stream writes the clicks of linkID, or of the links of workspaceID, until
+// the client goes away or the subscription ends.
+func (h *StatsHandler) stream(w http.ResponseWriter, r *http.Request, linkID, workspaceID int64) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	excludeBots := false
+	if v := r.URL.Query().Get("exclude_bots"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "exclude_bots must be true or false")
+			return
+		}
+		excludeBots = b
+	}
+
+	sub, err := h.App.Live.Subscribe(linkID, workspaceID)
+	if err != nil {
+		// Clients retry another replica, or this one later.
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Live clicks are unavailable, try again later")
+		logger.Warn("Refused live click stream", "error", err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep proxies such as nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The stream outlives the server's write timeout, so each write gets a
+	// deadline of its own instead: a client that stops reading is dropped.
+	rc := http.NewResponseController(w)
+	write := func(format string, args ...any) bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(h.App.Config.HTTP.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write("retry: %d\n\n", liveRetry.Milliseconds()) {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.App.Config.Analytics.LiveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		case click, ok := <-sub.Clicks():
+			if !ok {
+				reason := "shutdown"
+				if errors.Is(sub.Err(), analytics.ErrSlowConsumer) {
+					reason = "slow_consumer"
+				}
+				write("event: close\ndata: {\"reason\":%q}\n\n", reason)
+				return
+			}
+			if excludeBots && click.Bot != "" {
+				continue
+			}
+			data, err := json.Marshal(click)
+			if err != nil {
+				logger.Error("Failed to encode live click", "error", err)
+				continue
+			}
+			if !write("event: click\ndata: %s\n\n", data) {
+				return
+			}
+		}
+	}
+}
+
+// ExportLinkClicks downloads a link's raw clicks, or its rollups, over the
+// same range as GetLinkStats. ?data= is clicks, the default, or rollups, of
+// the ?granularity= minute, hour or day, the default. ?format= is csv, the
+// default, ndjson or parquet. The file is streamed as it is read.
+func (h *StatsHandler) ExportLinkClicks(w http.ResponseWriter, r *http.Request) {
+	domain, ok := domainFromQuery(w, r, h.App)
+	if !ok {
+		return
+	}
+	e, format, ok := exportParams(w, r)
+	if !ok {
+		return
+	}
+	link, ok := h.getLink(w, r, domain)
+	if !ok {
+		return
+	}
+	e.LinkIDs = []int64{link.ID}
+	h.export(w, r, e, format, link.ShortCode)
+}
+
+// ExportWorkspaceClicks downloads the clicks of all the links of a
+// workspace, or of those of the ?link= short codes on ?domain=, like
+// ExportLinkClicks. It runs behind authz.Require.
+func (h *StatsHandler) ExportWorkspaceClicks(w http.ResponseWriter, r *http.Request) {
+	m, _ := authz.FromContext(r.Context())
+
+	e, format, ok := exportParams(w, r)
+	if !ok {
+		return
+	}
+	e.WorkspaceID = m.WorkspaceID
+
+	codes := r.URL.Query()["link"]
+	if len(codes) > maxExportLinks {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d links can be exported at once", maxExportLinks))
+		return
+	}
+	if len(codes) > 0 {
+		domain, ok := domainFromQuery(w, r, h.App)
+		if !ok {
+			return
+		}
+		for _, code := range codes {
+			link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{ShortCode: code, DomainID: domain.ID})
+			if errors.Is(err, pgx.ErrNoRows) || err == nil && link.WorkspaceID.Int64 != m.WorkspaceID {
+				utils.RespondWithError(w, http.StatusNotFound, "URL not found: "+code)
+				return
+			}
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+				middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+				return
+			}
+			e.LinkIDs = append(e.LinkIDs, link.ID)
+		}
+	}
+	h.export(w, r, e, format, m.Slug)
+}
+
+// export streams the rows of e as a file named after name.
+func (h *StatsHandler) export(w http.ResponseWriter, r *http.Request, e analytics.Export, format export.Format, name string) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	filename := fmt.Sprintf("%s-%s-%s-%s.%s", name, e.Dataset, e.From.Format("20060102"), e.To.Format("20060102"), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	// Exports outlive the server's write timeout, so each write gets a
+	// deadline of its own instead, like live streams.
+	rc := http.NewResponseController(w)
+	written := false
+	out := writerFunc(func(p []byte) (int, error) {
+		if err := rc.SetWriteDeadline(time.Now().Add(h.App.Config.HTTP.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return 0, err
+		}
+		written = true
+		return w.Write(p)
+	})
+
+	rows, err := h.exporter.Export(r.Context(), out, format, e)
+	if err == nil {
+		return
+	}
+	if r.Context().Err() == nil {
+		logger.Error("Failed to export clicks", "export", name, "dataset", e.Dataset, "rows", rows, "error", err)
+	}
+	if !written {
+		w.Header().Del("Content-Disposition")
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to export clicks")
+		return
+	}
+	// The status has gone out with the first rows: abort the response so
+	// that the client sees the file is incomplete.
+	panic(http.ErrAbortHandler)
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// exportParams parses the range, ?data=, ?granularity= and ?format= of an
+// export. When it returns false a response has already been written.
+func exportParams(w http.ResponseWriter, r *http.Request) (analytics.Export, export.Format, bool) {
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return analytics.Export{}, "", false
+	}
+	q := r.URL.Query()
+	e := analytics.Export{Dataset: analytics.DatasetClicks, Granularity: analytics.GranularityDay, From: from, To: to}
+	format := export.FormatCSV
+	var err error
+	if v := q.Get("data"); v != "" {
+		e.Dataset, err = analytics.ParseDataset(v)
+	}
+	if v := q.Get("granularity"); v != "" && err == nil {
+		e.Granularity, err = analytics.ParseGranularity(v)
+	}
+	if v := q.Get("format"); v != "" && err == nil {
+		format, err = export.ParseFormat(v)
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return analytics.Export{}, "", false
+	}
+	return e, format, true
+}
+
+// getLink looks up the link of a {code} path the caller may view. When it
+// returns false a response has already been written.
+func (h *StatsHandler) getLink(w http.ResponseWriter, r *http.Request, domain linkDomain) (database.Url, bool) {
+	link, err := h.App.Querier.GetURLDetails(r.Context(), database.GetURLDetailsParams{
+		ShortCode: r.PathValue("code"),
+		DomainID:  domain.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
+			return database.Url{}, false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
+		middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		return database.Url{}, false
+	}
+	if _, ok := authorizeLink(w, r, h.App, h.authorizer, link.WorkspaceID, authz.View); !ok {
+		return database.Url{}, false
+	}
+	return link, true
+}
+
+// breakdownParams parses the {dimension} path and ?limit= of a breakdown
+// request. When it returns false a response has already been written.
+func breakdownParams(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	dimension := r.PathValue("dimension")
+	if !slices.Contains(analytics.Dimensions, dimension) {
+		utils.RespondWithError(w, http.StatusNotFound, "Unknown dimension, expected one of "+strings.Join(analytics.Dimensions, ", "))
+		return "", 0, false
+	}
+	limit := defaultBreakdownSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxBreakdownSize {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return "", 0, false
+		}
+		limit = n
+	}
+	return dimension, limit, true
+}
+
+// statsQuery parses the range and filters of a stats request. When it
+// returns false a response has already been written.
+func statsQuery(w http.ResponseWriter, r *http.Request) (analytics.Query, bool) {
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return analytics.Query{}, false
+	}
+	query := analytics.Query{From: from, To: to}
+	if v := r.URL.Query().Get("exclude_bots"); v != "" {
+		excludeBots, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "exclude_bots must be true or false")
+			return analytics.Query{}, false
+		}
+		query.ExcludeBots = excludeBots
+	}
+	return query, true
+}
+
+// dateRange parses the ?from= and ?to= bounds of a stats request. When it
+// returns false a response has already been written.
+func dateRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	q := r.URL.Query()
+	to := analytics.Day(time.Now()).AddDate(0, 0, 1)
+	if v := q.Get("to"); v != "" {
+		t, day, ok := parseBound(v)
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid to, expected YYYY-MM-DD or an RFC 3339 timestamp")
+			return time.Time{}, time.Time{}, false
+		}
+		// A date includes the whole day.
+		if day {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -defaultStatsDays)
+	if v := q.Get("from"); v != "" {
+		t, _, ok := parseBound(v)
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid from, expected YYYY-MM-DD or an RFC 3339 timestamp")
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		utils.RespondWithError(w, http.StatusBadRequest, "from must be before to")
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Sub(from) > maxStatsDays*24*time.Hour {
+		utils.RespondWithError(w, http.StatusBadRequest, "Range must not exceed 366 days")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// parseBound parses a YYYY-MM-DD date, reporting true, or an RFC 3339
+// timestamp, truncated to the minute.
+func parseBound(v string) (time.Time, bool, bool) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return t.UTC().Truncate(time.Minute), false, true
+}
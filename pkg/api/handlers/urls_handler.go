@@ -1,159 +1,562 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/analytics"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/cache"
 	"github.com/nouvadev/veritas/pkg/config"
 	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/domains"
+	"github.com/nouvadev/veritas/pkg/events"
 	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/outbox"
+	"github.com/nouvadev/veritas/pkg/reputation"
+	"github.com/nouvadev/veritas/pkg/telemetry"
 	"github.com/nouvadev/veritas/pkg/utils"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/net/idna"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// unreachableDeleteTimeout bounds the deletion of a link found
+	// unreachable after it was created.
+	unreachableDeleteTimeout = 10 * time.Second
+	// previewSuffix appended to a short code shows the preview page.
+	previewSuffix = "+"
+	// continueParam skips the interstitial page of a link.
+	continueParam = "continue"
+	// maxTitleLength bounds user supplied link titles.
+	maxTitleLength = 300
+	// maxDescriptionLength bounds user supplied preview descriptions.
+	maxDescriptionLength = 1000
+	// maxImageURLLength bounds user supplied preview image URLs.
+	maxImageURLLength = 2048
 )
 
 // URLHandler handles all URL-related HTTP requests
 type URLHandler struct {
-	App *config.AppConfig
+	App        *config.AppConfig
+	authorizer *authz.Authorizer
 }
 
 type URLRequest struct {
 	OriginalURL string `json:"original_url"`
+	// Interstitial shows a preview page before every redirect.
+	Interstitial bool `json:"interstitial"`
+	// Title is shown on the preview page.
+	Title string `json:"title"`
+	// Preview overrides what social networks show when the link is shared.
+	Preview *LinkPreview `json:"preview,omitempty"`
+	// Workspace is the slug of the workspace the link belongs to. Links
+	// without one are public: anyone can see their details.
+	Workspace string `json:"workspace,omitempty"`
+	// Domain is a verified custom domain of the workspace to create the link
+	// under. Links go on the default domain when it is empty.
+	Domain string `json:"domain,omitempty"`
+}
+
+// LinkPreview holds per-link overrides of the destination's Open Graph tags.
+// Empty fields fall back to the fetched metadata.
+type LinkPreview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// validate trims the preview fields and checks their limits.
+func (p *LinkPreview) validate() error {
+	p.Title = strings.TrimSpace(p.Title)
+	p.Description = strings.TrimSpace(p.Description)
+	p.Image = strings.TrimSpace(p.Image)
+
+	if len(p.Title) > maxTitleLength {
+		return errors.New("title is too long")
+	}
+	if len(p.Description) > maxDescriptionLength {
+		return errors.New("description is too long")
+	}
+	if p.Image != "" {
+		u, err := url.Parse(p.Image)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(p.Image) > maxImageURLLength {
+			return errors.New("image must be an http(s) URL")
+		}
+	}
+	return nil
 }
 
 type URLResponse struct {
-	ShortURL string `json:"short_url"`
+	ShortURL  string `json:"short_url"`
+	ShortCode string `json:"short_code"`
 }
 
 func NewURLHandler(app *config.AppConfig) *URLHandler {
-	return &URLHandler{App: app}
+	return &URLHandler{App: app, authorizer: authz.New(app.Querier)}
 }
 
 func (h *URLHandler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
 	// get original url from request
 	var req URLRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			logger.Warn("Request body too large", "limit", maxBytesErr.Limit)
+			return
+		}
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		h.App.Logger.Error("Invalid request body", "error", err)
+		logger.Error("Invalid request body", "error", err)
 		return
 	}
 
 	if !utils.ValidateURL(req.OriginalURL) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid URL")
-		h.App.Logger.Error("Invalid URL", "url", req.OriginalURL)
+		logger.Error("Invalid URL", "url", req.OriginalURL)
 		return
 	}
 
-	insertedID, err := h.App.Querier.CreateURL(r.Context(), req.OriginalURL)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create URL")
-		h.App.Logger.Error("Failed to create URL", "error", err)
+	if h.App.Reputation != nil {
+		verdict, err := h.App.Reputation.Check(r.Context(), req.OriginalURL)
+		if err != nil {
+			logger.Warn("reputation check failed, accepting URL", "url", req.OriginalURL, "error", err)
+		} else if verdict.Blocked {
+			metrics.BlockedURLs.WithLabelValues("create", verdict.List).Inc()
+			utils.RespondWithError(w, http.StatusUnprocessableEntity, "URL is flagged as malicious")
+			logger.Warn("Rejected malicious URL", "url", req.OriginalURL, "list", verdict.List, "match", verdict.Match)
+			return
+		}
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if len(req.Title) > maxTitleLength {
+		utils.RespondWithError(w, http.StatusBadRequest, "Title is too long")
 		return
 	}
 
-	shortCode := utils.ToBase62(uint64(insertedID))
+	owner := middleware.OwnerFromContext(r.Context())
+	var workspaceID pgtype.Int8
+	if req.Workspace != "" {
+		m, err := h.authorizer.Workspace(r.Context(), owner, req.Workspace, authz.CreateLinks)
+		if err != nil {
+			authz.WriteError(w, r, h.App.Logger, err, "Workspace not found")
+			return
+		}
+		workspaceID = pgtype.Int8{Int64: m.WorkspaceID, Valid: true}
+	}
+	domain, ok := h.domainForCreate(w, r, req.Domain, workspaceID)
+	if !ok {
+		return
+	}
 
-	err = h.App.Querier.UpdateShortCode(r.Context(), database.UpdateShortCodeParams{
-		ShortCode: shortCode,
-		ID:        insertedID,
+	var preview LinkPreview
+	if req.Preview != nil {
+		preview = *req.Preview
+		if err := preview.validate(); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid preview: "+err.Error())
+			return
+		}
+	}
+
+	created := database.Url{
+		OriginalUrl:        req.OriginalURL,
+		Interstitial:       req.Interstitial,
+		Title:              pgtype.Text{String: req.Title, Valid: req.Title != ""},
+		PreviewTitle:       pgtype.Text{String: preview.Title, Valid: preview.Title != ""},
+		PreviewDescription: pgtype.Text{String: preview.Description, Valid: preview.Description != ""},
+		PreviewImage:       pgtype.Text{String: preview.Image, Valid: preview.Image != ""},
+		Owner:              pgtype.Text{String: owner, Valid: owner != ""},
+		DomainID:           domain.ID,
+		WorkspaceID:        workspaceID,
+	}
+	// The link and its LinkCreated event are committed together, so the
+	// event is published exactly when the link exists.
+	err := inTx(r.Context(), h.App, func(q *database.Queries) error {
+		id, err := q.CreateURL(r.Context(), database.CreateURLParams{
+			OriginalUrl:        created.OriginalUrl,
+			Interstitial:       created.Interstitial,
+			Title:              created.Title,
+			PreviewTitle:       created.PreviewTitle,
+			PreviewDescription: created.PreviewDescription,
+			PreviewImage:       created.PreviewImage,
+			Owner:              created.Owner,
+			DomainID:           created.DomainID,
+			WorkspaceID:        created.WorkspaceID,
+		})
+		if err != nil {
+			return fmt.Errorf("could not create URL: %w", err)
+		}
+		created.ID, created.ShortCode = id, utils.ToBase62(uint64(id))
+
+		err = q.UpdateShortCode(r.Context(), database.UpdateShortCodeParams{
+			ShortCode: created.ShortCode,
+			ID:        created.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("could not update short code: %w", err)
+		}
+		return outbox.Add(r.Context(), q, events.SubjectLinkCreated, events.LinkCreated(eventLink(h.App, domain, created)))
 	})
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update short code")
-		h.App.Logger.Error("Failed to update short code", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create URL")
+		logger.Error("Failed to create URL", "error", err)
 		return
 	}
+	insertedID, shortCode := created.ID, created.ShortCode
+	metrics.LinksCreated.Inc()
 
 	// Build complete URL in backend (RESTful best practice)
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:8080" // fallback for development
-	}
-	shortURL := fmt.Sprintf("%s/%s", baseURL, shortCode)
+	link := shortURL(h.App.Config.BaseURL, domain.Hostname, shortCode)
 
 	// Respond to the user with complete URL (single source of truth)
-	utils.RespondWithJSON(w, http.StatusCreated, URLResponse{ShortURL: shortURL})
+	utils.RespondWithJSON(w, http.StatusCreated, URLResponse{ShortURL: link, ShortCode: shortCode})
+
+	if h.App.Metadata != nil && !h.App.Metadata.Enqueue(insertedID, cache.LinkKey(domain.Hostname, shortCode), req.OriginalURL) {
+		logger.Warn("metadata queue is full, skipping fetch", "id", insertedID)
+	}
 
-	// Perform reachability check in the background
+	// Perform reachability check in the background. It outlives the request,
+	// whose context ends once the response is sent, so it keeps only the
+	// request's values.
+	detached := context.WithoutCancel(r.Context())
 	go func() {
-		isReachable := utils.CheckURLReachability(req.OriginalURL, h.App.Logger)
-		if !isReachable {
-			h.App.Logger.Info("URL is not reachable, deleting", "id", insertedID)
-			err := h.App.Querier.DeleteURL(r.Context(), insertedID)
-			if err != nil {
-				h.App.Logger.Error("Failed to delete unreachable URL", "id", insertedID, "error", err)
+		isReachable := utils.CheckURLReachability(req.OriginalURL, logger)
+		if isReachable {
+			return
+		}
+		logger.Info("URL is not reachable, deleting", "id", insertedID)
+		ctx, cancel := context.WithTimeout(detached, unreachableDeleteTimeout)
+		defer cancel()
+		err := inTx(ctx, h.App, func(q *database.Queries) error {
+			if err := q.DeleteURL(ctx, insertedID); err != nil {
+				return err
 			}
+			return outbox.Add(ctx, q, events.SubjectLinkDeleted, events.LinkDeleted(eventLink(h.App, domain, created), events.DeletedUnreachable))
+		})
+		if err != nil {
+			logger.Error("Failed to delete unreachable URL", "id", insertedID, "error", err)
+			return
 		}
+		// The redirector may already have cached the link.
+		evictLink(r.WithContext(ctx), h.App, cache.LinkKey(domain.Hostname, shortCode))
 	}()
 }
 
 func (h *URLHandler) RedirectToOriginalURL(w http.ResponseWriter, r *http.Request) {
 	shortCode := r.PathValue("short_code")
+
+	// A trailing "+" asks for the preview page instead of the redirect. The
+	// mux cannot match it separately, as wildcards span whole segments.
+	shortCode, preview := strings.CutSuffix(shortCode, previewSuffix)
 	if shortCode == "" {
 		utils.RespondWithError(w, http.StatusBadRequest, "Short code is required")
 		return
 	}
 
-	// 1. Try to get from cache first
-	originalURL, err := h.App.Cache.Get(r.Context(), shortCode).Result()
-	if err == nil {
-		h.App.Logger.Info("cache hit", "short_code", shortCode)
-		// Redirect and publish event
-		h.publishRedirectEvent(shortCode, originalURL, r)
-		http.Redirect(w, r, originalURL, http.StatusFound)
+	domain := h.requestDomain(r)
+	link, ok := h.lookupLink(w, r, domain, shortCode)
+	if !ok {
 		return
 	}
 
-	if !errors.Is(err, redis.Nil) {
-		h.App.Logger.Error("redis error", "err", err)
+	if preview {
+		h.renderInterstitial(w, r, shortCode, link)
+		return
+	}
+
+	// Link preview bots never follow the redirect to build a card; they get
+	// a page carrying the link's Open Graph tags instead. Their visits are
+	// still recorded, flagged as bot traffic.
+	if utils.IsSocialCrawler(r.UserAgent()) {
+		h.publishRedirectEvent(domain.Hostname, shortCode, link, true, r)
+		h.renderUnfurl(w, r, domain, shortCode, link)
+		return
+	}
+
+	if link.Interstitial && r.URL.Query().Get(continueParam) == "" {
+		h.renderInterstitial(w, r, shortCode, link)
+		return
+	}
+
+	// Redirect and publish event
+	h.publishRedirectEvent(domain.Hostname, shortCode, link, false, r)
+	http.Redirect(w, r, link.URL, http.StatusFound)
+}
+
+// requestDomain returns the code namespace of a redirect: the verified custom
+// domain its Host names, or the default domain for any other host.
+func (h *URLHandler) requestDomain(r *http.Request) linkDomain {
+	if h.App.Domains == nil {
+		return linkDomain{}
+	}
+	d, ok := h.App.Domains.Lookup(r.Host)
+	if !ok {
+		return linkDomain{}
+	}
+	return linkDomain{ID: pgtype.Int8{Int64: d.ID, Valid: true}, Hostname: d.Hostname}
+}
+
+// domainForCreate resolves the domain a new link is created under. Only links
+// of the workspace a verified domain belongs to may use it. When it returns
+// false a response has already been written.
+func (h *URLHandler) domainForCreate(w http.ResponseWriter, r *http.Request, raw string, workspaceID pgtype.Int8) (linkDomain, bool) {
+	if raw == "" {
+		return linkDomain{}, true
+	}
+
+	hostname, err := domains.NormalizeHost(raw)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid domain")
+		return linkDomain{}, false
+	}
+	d, err := h.App.Querier.GetDomainByHostname(r.Context(), hostname)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get domain")
+		middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		return linkDomain{}, false
+	}
+	if err != nil || !workspaceID.Valid || d.WorkspaceID != workspaceID.Int64 || !d.VerifiedAt.Valid {
+		utils.RespondWithError(w, http.StatusBadRequest, "Domain is not a verified domain of this workspace")
+		return linkDomain{}, false
+	}
+	return linkDomain{ID: pgtype.Int8{Int64: d.ID, Valid: true}, Hostname: d.Hostname}, true
+}
+
+// lookupLink finds an enabled link in the cache or, failing that, in the
+// database. When it returns false a response has already been written.
+func (h *URLHandler) lookupLink(w http.ResponseWriter, r *http.Request, domain linkDomain, shortCode string) (cachedLink, bool) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	key := cache.LinkKey(domain.Hostname, shortCode)
+
+	// 1. Try to get from cache first
+	// Entries cached before links carried their ID are refreshed, so that
+	// their clicks can be attributed.
+	link, err := h.getCachedLink(r.Context(), key)
+	if err == nil && link.ID != 0 {
+		metrics.CacheLookups.WithLabelValues("hit").Inc()
+		logger.Info("cache hit", "short_code", shortCode)
+		return link, true
+	}
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		metrics.CacheLookups.WithLabelValues("error").Inc()
+		logger.Error("redis error", "err", err)
 	} else {
-		h.App.Logger.Info("cache miss", "short_code", shortCode)
+		metrics.CacheLookups.WithLabelValues("miss").Inc()
+		logger.Info("cache miss", "short_code", shortCode)
 	}
 
 	// 2. If not in cache, get from DB
-	originalURL, err = h.App.Querier.GetURLByShortCode(r.Context(), shortCode)
+	row, err := h.App.Querier.GetURLByShortCode(r.Context(), database.GetURLByShortCodeParams{
+		ShortCode: shortCode,
+		DomainID:  domain.ID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			utils.RespondWithError(w, http.StatusNotFound, "URL not found")
 		} else {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get URL")
 		}
-		h.App.Logger.Error("db error", "err", err)
-		return
+		logger.Error("db error", "err", err)
+		return cachedLink{}, false
 	}
 
+	// Disabled links are never cached, so they only ever get here.
+	if row.DisabledAt.Valid {
+		h.renderDisabled(w, shortCode, row.DisabledReason.String)
+		return cachedLink{}, false
+	}
+
+	link = cachedLink{
+		ID:                 row.ID,
+		URL:                row.OriginalUrl,
+		WorkspaceID:        row.WorkspaceID.Int64,
+		Interstitial:       row.Interstitial,
+		Title:              row.Title.String,
+		PreviewTitle:       row.PreviewTitle.String,
+		PreviewDescription: row.PreviewDescription.String,
+		PreviewImage:       row.PreviewImage.String,
+	}
+	if len(row.Metadata) > 0 {
+		var m metadata.Metadata
+		if err := json.Unmarshal(row.Metadata, &m); err != nil {
+			logger.Error("invalid stored metadata", "short_code", shortCode, "err", err)
+		} else {
+			link.PreviewTitle = firstNonEmpty(link.PreviewTitle, m.BestTitle())
+			link.PreviewDescription = firstNonEmpty(link.PreviewDescription, m.BestDescription())
+			link.PreviewImage = firstNonEmpty(link.PreviewImage, m.BestImage())
+			link.SiteName = m.OGSiteName
+		}
+	}
+	link.PreviewTitle = firstNonEmpty(link.PreviewTitle, link.Title)
+
 	// 3. Store in cache for future requests
-	if err := h.App.Cache.Set(r.Context(), shortCode, originalURL, 1*time.Hour).Err(); err != nil {
-		h.App.Logger.Error("failed to set cache", "err", err)
+	if err := h.cacheLink(r.Context(), key, link); err != nil {
+		logger.Error("failed to set cache", "err", err)
 	}
+	return link, true
+}
 
-	// Redirect and publish event
-	h.publishRedirectEvent(shortCode, originalURL, r)
-	http.Redirect(w, r, originalURL, http.StatusFound)
+// renderInterstitial shows where a link goes and lets the visitor decide
+// whether to continue. Continuing comes back through the redirect with
+// continueParam set, so the click is still recorded.
+func (h *URLHandler) renderInterstitial(w http.ResponseWriter, r *http.Request, shortCode string, link cachedLink) {
+	page := interstitialPage{
+		Title:       "Check where this link goes",
+		ShortCode:   shortCode,
+		Destination: link.URL,
+		PageTitle:   link.Title,
+		ContinueURL: "/" + shortCode + "?" + continueParam + "=1",
+	}
+	if u, err := url.Parse(link.URL); err == nil {
+		page.Domain = u.Hostname()
+		if ascii, err := idna.ToASCII(page.Domain); err == nil && ascii != page.Domain {
+			// Show the punycode form too, so look-alike Unicode domains stand out.
+			page.ASCIIDomain = ascii
+		}
+	}
+
+	if err := renderPage(w, http.StatusOK, "interstitial.html", page); err != nil {
+		middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("failed to render interstitial page", "err", err)
+	}
+}
+
+// renderUnfurl serves social crawlers a page with the link's Open Graph and
+// Twitter card tags. People who end up on it are sent on to the destination.
+func (h *URLHandler) renderUnfurl(w http.ResponseWriter, r *http.Request, domain linkDomain, shortCode string, link cachedLink) {
+	page := unfurlPage{
+		Title:       link.PreviewTitle,
+		Description: link.PreviewDescription,
+		Image:       link.PreviewImage,
+		SiteName:    link.SiteName,
+		ShortURL:    shortURL(h.App.Config.BaseURL, domain.Hostname, shortCode),
+		Destination: link.URL,
+		Card:        "summary",
+	}
+	if page.Image != "" {
+		page.Card = "summary_large_image"
+	}
+	if page.Title == "" {
+		if u, err := url.Parse(link.URL); err == nil {
+			page.Title = u.Hostname()
+		}
+	}
+
+	if err := renderPage(w, http.StatusOK, "unfurl.html", page); err != nil {
+		middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("failed to render unfurl page", "err", err)
+	}
+}
+
+type unfurlPage struct {
+	Title       string
+	Description string
+	Image       string
+	SiteName    string
+	ShortURL    string
+	Destination string
+	Card        string
+}
+
+type interstitialPage struct {
+	Title       string
+	ShortCode   string
+	Destination string
+	Domain      string
+	ASCIIDomain string
+	PageTitle   string
+	ContinueURL string
 }
 
-func (h *URLHandler) publishRedirectEvent(shortCode, originalURL string, r *http.Request) {
+// renderDisabled serves the page shown in place of a disabled link. Links
+// taken down for legal reasons answer 451, all others 410.
+func (h *URLHandler) renderDisabled(w http.ResponseWriter, shortCode, reason string) {
+	page := disabledPage{
+		Title:     "Link disabled",
+		Heading:   "This link has been disabled",
+		Message:   "The destination of this short link was found to violate our terms of service, so it is no longer available.",
+		ShortCode: shortCode,
+	}
+	status := http.StatusGone
+	switch reason {
+	case DisabledReasonLegal:
+		status = http.StatusUnavailableForLegalReasons
+		page.Message = "This short link is unavailable for legal reasons."
+	case reputation.DisabledReason:
+		page.Message = "The destination of this short link was identified as phishing or malware, so it is no longer available."
+	}
+
+	if err := renderPage(w, status, "disabled.html", page); err != nil {
+		h.App.Logger.Error("failed to render disabled page", "err", err)
+	}
+}
+
+type disabledPage struct {
+	Title     string
+	Heading   string
+	Message   string
+	ShortCode string
+}
+
+func (h *URLHandler) publishRedirectEvent(domain, shortCode string, link cachedLink, isBot bool, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
 	event := &eventsv1.RedirectEvent{
 		ShortCode:   shortCode,
-		OriginalUrl: originalURL,
+		OriginalUrl: link.URL,
 		UserAgent:   r.UserAgent(),
-		IpAddress:   r.RemoteAddr,
+		IpAddress:   utils.ClientIP(r, h.App.Config.HTTP.TrustedProxyPrefixes()),
+		RequestId:   middleware.RequestIDFromContext(r.Context()),
+		IsBot:       isBot,
+		Domain:      domain,
+		WorkspaceId: link.WorkspaceID,
+		LinkId:      link.ID,
+		OccurredAt:  timestamppb.Now(),
+		Referer:     r.Referer(),
+		Utm:         analytics.UTM(r.URL.Query()),
+		Country:     utils.ClientCountry(r, h.App.Config.HTTP.CountryHeader, h.App.Config.HTTP.TrustedProxyPrefixes()),
 	}
 
+	subject := events.SubjectRedirect
+
 	eventBytes, err := proto.Marshal(event)
 	if err != nil {
-		h.App.Logger.Error("failed to marshal redirect event", "err", err)
+		metrics.NATSPublishFailures.WithLabelValues(subject).Inc()
+		logger.Error("failed to marshal redirect event", "err", err)
 		return
 	}
 
-	subject := "veritas.redirect.success"
-	if err := h.App.NATS.Publish(subject, eventBytes); err != nil {
-		h.App.Logger.Error("failed to publish nats event", "err", err)
+	// Carry the trace context in the message headers so the analytics
+	// consumer's spans join the redirect trace.
+	msg := &nats.Msg{Subject: subject, Data: eventBytes, Header: nats.Header{}}
+	if event.RequestId != "" {
+		msg.Header.Set(middleware.RequestIDHeader, event.RequestId)
+	}
+	_, span := telemetry.StartPublish(r.Context(), msg)
+	defer span.End()
+
+	if err := h.App.Events.Publish(r.Context(), msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.Error("failed to publish redirect event", "err", err)
 	} else {
-		h.App.Logger.Info("published nats event", "subject", subject)
+		logger.Info("published redirect event", "subject", subject)
 	}
 }
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/utils"
+	"github.com/nouvadev/veritas/pkg/webhooks"
+)
+
+const (
+	// maxWebhookURLLength bounds the endpoint URLs of webhooks.
+	maxWebhookURLLength = 2048
+	maxWebhookBatchSize = 100
+	// maxWebhookBatchWindow bounds, in seconds, how long events wait to be
+	// batched.
+	maxWebhookBatchWindow = 3600
+)
+
+const (
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+)
+
+// WebhookHandler manages the webhook endpoints of workspaces and their
+// deliveries. Its endpoints run behind authz.Require.
+type WebhookHandler struct {
+	App *config.AppConfig
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// SampleRate is the fraction of link.clicked events delivered, 1 when
+	// omitted. Lifecycle events are always delivered.
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// BatchSize greater than 1 sends events as JSON arrays of up to that
+	// many, collected for BatchWindowSeconds.
+	BatchSize          int32 `json:"batch_size,omitempty"`
+	BatchWindowSeconds int32 `json:"batch_window_seconds,omitempty"`
+}
+
+type WebhookResponse struct {
+	ID                 int64     `json:"id"`
+	URL                string    `json:"url"`
+	Events             []string  `json:"events"`
+	SampleRate         float64   `json:"sample_rate"`
+	BatchSize          int32     `json:"batch_size"`
+	BatchWindowSeconds int32     `json:"batch_window_seconds"`
+	CreatedBy          string    `json:"created_by"`
+	CreatedAt          time.Time `json:"created_at"`
+	// Secret signs the requests to the endpoint. It is only returned when
+	// the webhook is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int32      `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	// Payload and AttemptLog are only returned for a single delivery.
+	Payload    json.RawMessage  `json:"payload,omitempty"`
+	AttemptLog []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+type WebhookAttempt struct {
+	AttemptedAt    time.Time `json:"attempted_at"`
+	ResponseStatus int32     `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMs     int32     `json:"duration_ms"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	// NextBefore is passed as ?before= to fetch the next page.
+	NextBefore int64 `json:"next_before,omitempty"`
+}
+
+func NewWebhookHandler(app *config.AppConfig) *WebhookHandler {
+	return &WebhookHandler{App: app}
+}
+
+// CreateWebhook registers an endpoint for the workspace's events and returns
+// the secret its requests are signed with.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(req.URL) > maxWebhookURLLength {
+		utils.RespondWithError(w, http.StatusBadRequest, "URL must be an http(s) URL")
+		return
+	}
+	if len(req.Events) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "At least one event is required")
+		return
+	}
+	for _, e := range req.Events {
+		if !webhooks.IsEventType(e) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Unknown event "+strconv.Quote(e))
+			return
+		}
+	}
+	slices.Sort(req.Events)
+	req.Events = slices.Compact(req.Events)
+
+	sampleRate := 1.0
+	if req.SampleRate != nil {
+		sampleRate = *req.SampleRate
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Sample rate must be greater than 0 and at most 1")
+		return
+	}
+	if req.BatchSize == 0 {
+		req.BatchSize = 1
+	}
+	if req.BatchSize < 1 || req.BatchSize > maxWebhookBatchSize {
+		utils.RespondWithError(w, http.StatusBadRequest, "Batch size must be between 1 and 100")
+		return
+	}
+	if req.BatchWindowSeconds < 0 || req.BatchWindowSeconds > maxWebhookBatchWindow {
+		utils.RespondWithError(w, http.StatusBadRequest, "Batch window must be between 0 and 3600 seconds")
+		return
+	}
+
+	hook, err := h.App.Querier.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		WorkspaceID:        m.WorkspaceID,
+		Url:                req.URL,
+		Secret:             webhooks.NewSecret(),
+		Events:             req.Events,
+		SampleRate:         sampleRate,
+		BatchSize:          req.BatchSize,
+		BatchWindowSeconds: req.BatchWindowSeconds,
+		CreatedBy:          m.Member,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		logger.Error("Failed to create webhook", "error", err)
+		return
+	}
+
+	logger.Info("webhook created", "webhook", hook.ID, "workspace", m.Slug, "member", m.Member)
+	resp := toWebhookResponse(hook)
+	resp.Secret = hook.Secret
+	utils.RespondWithJSON(w, http.StatusCreated, resp)
+}
+
+// ListWebhooks returns the workspace's webhooks.
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	rows, err := h.App.Querier.ListWebhooksByWorkspace(r.Context(), m.WorkspaceID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list webhooks")
+		logger.Error("Failed to list webhooks", "error", err)
+		return
+	}
+
+	resp := make([]WebhookResponse, 0, len(rows))
+	for _, hook := range rows {
+		resp = append(resp, toWebhookResponse(hook))
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// GetWebhook returns one of the workspace's webhooks.
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.getWebhook(w, r)
+	if !ok {
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, toWebhookResponse(hook))
+}
+
+// DeleteWebhook removes a webhook together with its deliveries.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	n, err := h.App.Querier.DeleteWebhook(r.Context(), database.DeleteWebhookParams{ID: id, WorkspaceID: m.WorkspaceID})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		logger.Error("Failed to delete webhook", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	logger.Info("webhook deleted", "webhook", id, "workspace", m.Slug, "member", m.Member)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries lists a webhook's deliveries, newest first, optionally
+// filtered by ?status=.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	q := r.URL.Query()
+
+	hook, ok := h.getWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	status := q.Get("status")
+	switch status {
+	case "", webhooks.StatusPending, webhooks.StatusSucceeded, webhooks.StatusDead:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Status must be one of pending, succeeded, dead")
+		return
+	}
+
+	limit := defaultDeliveryPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryPageSize {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	before := int64(math.MaxInt64)
+	if v := q.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid before")
+			return
+		}
+		before = n
+	}
+
+	rows, err := h.App.Querier.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		WebhookID: hook.ID,
+		Before:    before,
+		Status:    status,
+		MaxCount:  int32(limit),
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list deliveries")
+		logger.Error("Failed to list webhook deliveries", "error", err)
+		return
+	}
+
+	resp := WebhookDeliveriesResponse{Deliveries: make([]WebhookDelivery, 0, len(rows))}
+	for _, row := range rows {
+		resp.Deliveries = append(resp.Deliveries, toWebhookDelivery(database.WebhookDelivery{
+			ID:             row.ID,
+			EventID:        row.EventID,
+			EventType:      row.EventType,
+			Status:         row.Status,
+			Attempts:       row.Attempts,
+			NextAttemptAt:  row.NextAttemptAt,
+			LastAttemptAt:  row.LastAttemptAt,
+			ResponseStatus: row.ResponseStatus,
+			LastError:      row.LastError,
+			DeliveredAt:    row.DeliveredAt,
+			CreatedAt:      row.CreatedAt,
+		}))
+	}
+	if len(rows) == limit {
+		resp.NextBefore = rows[len(rows)-1].ID
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// GetDelivery returns a delivery with its payload and the log of its attempts.
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+
+	d, ok := h.getDelivery(w, r)
+	if !ok {
+		return
+	}
+	attempts, err := h.App.Querier.ListWebhookAttempts(r.Context(), d.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get delivery")
+		logger.Error("Failed to list webhook attempts", "error", err)
+		return
+	}
+
+	resp := toWebhookDelivery(d)
+	resp.Payload = d.Payload
+	resp.AttemptLog = make([]WebhookAttempt, 0, len(attempts))
+	for _, a := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, WebhookAttempt{
+			AttemptedAt:    a.AttemptedAt,
+			ResponseStatus: a.ResponseStatus.Int32,
+			Error:          a.Error.String,
+			DurationMs:     a.DurationMs,
+		})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// Redeliver queues a delivery that failed or already succeeded to be sent
+// again with a fresh set of attempts.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	d, ok := h.getDelivery(w, r)
+	if !ok {
+		return
+	}
+	n, err := h.App.Querier.RedeliverWebhookDelivery(r.Context(), database.RedeliverWebhookDeliveryParams{ID: d.ID, WebhookID: d.WebhookID})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to redeliver")
+		logger.Error("Failed to redeliver webhook delivery", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "Delivery is already pending")
+		return
+	}
+
+	logger.Info("webhook delivery queued again", "delivery", d.ID, "workspace", m.Slug, "member", m.Member)
+	d.Status, d.Attempts, d.NextAttemptAt = webhooks.StatusPending, 0, time.Now()
+	d.DeliveredAt.Valid = false
+	utils.RespondWithJSON(w, http.StatusAccepted, toWebhookDelivery(d))
+}
+
+// getWebhook loads the {id} webhook of the workspace. When it returns false
+// a response has already been written.
+func (h *WebhookHandler) getWebhook(w http.ResponseWriter, r *http.Request) (database.Webhook, bool) {
+	m, _ := authz.FromContext(r.Context())
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+		return database.Webhook{}, false
+	}
+	hook, err := h.App.Querier.GetWebhook(r.Context(), database.GetWebhookParams{ID: id, WorkspaceID: m.WorkspaceID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get webhook")
+			middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		}
+		return database.Webhook{}, false
+	}
+	return hook, true
+}
+
+// getDelivery loads the {delivery} of the {id} webhook. When it returns
+// false a response has already been written.
+func (h *WebhookHandler) getDelivery(w http.ResponseWriter, r *http.Request) (database.WebhookDelivery, bool) {
+	hook, ok := h.getWebhook(w, r)
+	if !ok {
+		return database.WebhookDelivery{}, false
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("delivery"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Delivery not found")
+		return database.WebhookDelivery{}, false
+	}
+	d, err := h.App.Querier.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{ID: id, WebhookID: hook.ID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Delivery not found")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get delivery")
+			middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		}
+		return database.WebhookDelivery{}, false
+	}
+	return d, true
+}
+
+func toWebhookResponse(hook database.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:                 hook.ID,
+		URL:                hook.Url,
+		Events:             hook.Events,
+		SampleRate:         hook.SampleRate,
+		BatchSize:          hook.BatchSize,
+		BatchWindowSeconds: hook.BatchWindowSeconds,
+		CreatedBy:          hook.CreatedBy,
+		CreatedAt:          hook.CreatedAt,
+	}
+}
+
+func toWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+	resp := WebhookDelivery{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  timePtr(d.LastAttemptAt),
+		ResponseStatus: d.ResponseStatus.Int32,
+		LastError:      d.LastError.String,
+		DeliveredAt:    timePtr(d.DeliveredAt),
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == webhooks.StatusPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/api/authz"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	database "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// maxWorkspaceNameLength bounds workspace display names.
+const maxWorkspaceNameLength = 100
+
+const (
+	defaultLinkPageSize = 50
+	maxLinkPageSize     = 200
+)
+
+// workspaceSlug is the form of the slugs new workspaces are created with.
+var workspaceSlug = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,38}[a-z0-9])?$`)
+
+// authorizeLink checks that the caller may perform action on a link of
+// workspaceID. Links without a workspace are public and need no membership;
+// for the others, callers outside the workspace are told the link does not
+// exist. When it returns false a response has already been written.
+func authorizeLink(w http.ResponseWriter, r *http.Request, app *config.AppConfig, authorizer *authz.Authorizer, workspaceID pgtype.Int8, action authz.Action) (authz.Membership, bool) {
+	if !workspaceID.Valid {
+		return authz.Membership{}, true
+	}
+	m, err := authorizer.WorkspaceByID(r.Context(), middleware.OwnerFromContext(r.Context()), workspaceID.Int64, action)
+	if errors.Is(err, authz.ErrUnauthenticated) {
+		err = authz.ErrNotMember
+	}
+	if err != nil {
+		authz.WriteError(w, r, app.Logger, err, "URL not found")
+		return authz.Membership{}, false
+	}
+	return m, true
+}
+
+// WorkspaceHandler manages workspaces and their members. Apart from
+// CreateWorkspace and ListWorkspaces, its endpoints run behind authz.Require.
+type WorkspaceHandler struct {
+	App        *config.AppConfig
+	authorizer *authz.Authorizer
+}
+
+type WorkspaceRequest struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// WorkspaceResponse describes a workspace and the caller's role in it.
+type WorkspaceResponse struct {
+	Slug      string     `json:"slug"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type MemberRequest struct {
+	Role string `json:"role"`
+}
+
+type MemberResponse struct {
+	Member    string     `json:"member"`
+	Role      string     `json:"role"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// WorkspaceLink is the summary of a link in a workspace listing.
+type WorkspaceLink struct {
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	Domain      string    `json:"domain,omitempty"`
+	OriginalURL string    `json:"original_url"`
+	Title       string    `json:"title,omitempty"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	Owner       string    `json:"owner,omitempty"`
+}
+
+type WorkspaceLinksResponse struct {
+	Links []WorkspaceLink `json:"links"`
+	// NextBefore is passed as ?before= to fetch the next page.
+	NextBefore int64 `json:"next_before,omitempty"`
+}
+
+func NewWorkspaceHandler(app *config.AppConfig) *WorkspaceHandler {
+	return &WorkspaceHandler{App: app, authorizer: authz.New(app.Querier)}
+}
+
+// CreateWorkspace creates a workspace owned by the caller.
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	member := middleware.OwnerFromContext(r.Context())
+
+	var req WorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Slug = strings.TrimSpace(req.Slug)
+	if !workspaceSlug.MatchString(req.Slug) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Slug must be 1 to 40 lowercase letters, digits and dashes")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = req.Slug
+	}
+	if len(req.Name) > maxWorkspaceNameLength {
+		utils.RespondWithError(w, http.StatusBadRequest, "Name is too long")
+		return
+	}
+
+	ws, err := h.App.Querier.CreateWorkspace(r.Context(), database.CreateWorkspaceParams{
+		Slug:   req.Slug,
+		Name:   req.Name,
+		Member: member,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			utils.RespondWithError(w, http.StatusConflict, "Slug is already taken")
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create workspace")
+		logger.Error("Failed to create workspace", "error", err)
+		return
+	}
+
+	logger.Info("workspace created", "workspace", ws.Slug, "member", member)
+	utils.RespondWithJSON(w, http.StatusCreated, WorkspaceResponse{
+		Slug:      ws.Slug,
+		Name:      ws.Name,
+		Role:      string(authz.RoleOwner),
+		CreatedAt: &ws.CreatedAt,
+	})
+}
+
+// ListWorkspaces returns the workspaces the caller is a member of.
+func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	member := middleware.OwnerFromContext(r.Context())
+
+	rows, err := h.App.Querier.ListWorkspacesByMember(r.Context(), member)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list workspaces")
+		logger.Error("Failed to list workspaces", "error", err)
+		return
+	}
+
+	resp := make([]WorkspaceResponse, 0, len(rows))
+	listed := make(map[string]int, len(rows))
+	for _, row := range rows {
+		listed[row.Slug] = len(resp)
+		resp = append(resp, WorkspaceResponse{
+			Slug:      row.Slug,
+			Name:      row.Name,
+			Role:      row.Role,
+			CreatedAt: &row.CreatedAt,
+		})
+	}
+
+	// Roles granted by a bearer token add workspaces, or raise the role in
+	// them, without being stored as memberships.
+	for _, slug := range slices.Sorted(maps.Keys(middleware.WorkspaceRolesFromContext(r.Context()))) {
+		m, err := h.authorizer.Workspace(r.Context(), member, slug, authz.View)
+		if err != nil {
+			if !errors.Is(err, authz.ErrNotMember) {
+				logger.Error("Failed to resolve granted workspace", "workspace", slug, "error", err)
+			}
+			continue
+		}
+		if i, ok := listed[slug]; ok {
+			resp[i].Role = string(m.Role)
+			continue
+		}
+		resp = append(resp, WorkspaceResponse{Slug: m.Slug, Name: m.Name, Role: string(m.Role)})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// GetWorkspace describes the workspace and the caller's role in it.
+func (h *WorkspaceHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	m, _ := authz.FromContext(r.Context())
+	utils.RespondWithJSON(w, http.StatusOK, WorkspaceResponse{Slug: m.Slug, Name: m.Name, Role: string(m.Role)})
+}
+
+// ListMembers returns the members of the workspace.
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+
+	rows, err := h.App.Querier.ListWorkspaceMembers(r.Context(), m.WorkspaceID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list members")
+		logger.Error("Failed to list members", "error", err)
+		return
+	}
+
+	resp := make([]MemberResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, MemberResponse{Member: row.Member, Role: row.Role, CreatedAt: &row.CreatedAt})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// PutMember adds {member} to the workspace or changes its role. Only owners
+// can grant the owner role or change the role of another owner, and the last
+// owner cannot be demoted.
+func (h *WorkspaceHandler) PutMember(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+	target := r.PathValue("member")
+
+	var req MemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	role, err := authz.ParseRole(strings.ToLower(strings.TrimSpace(req.Role)))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Role must be one of owner, admin, editor, viewer")
+		return
+	}
+
+	current, ok := h.currentRole(w, r, m, target)
+	if !ok {
+		return
+	}
+	if (role == authz.RoleOwner || current == authz.RoleOwner) && !m.Role.Can(authz.ManageOwners) {
+		utils.RespondWithError(w, http.StatusForbidden, "Only owners can grant or revoke the owner role")
+		return
+	}
+
+	n, err := h.App.Querier.UpsertWorkspaceMember(r.Context(), database.UpsertWorkspaceMemberParams{
+		WorkspaceID: m.WorkspaceID,
+		Member:      target,
+		Role:        string(role),
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update member")
+		logger.Error("Failed to update member", "error", err)
+		return
+	}
+	if n == 0 {
+		utils.RespondWithError(w, http.StatusConflict, "A workspace must keep at least one owner")
+		return
+	}
+
+	logger.Info("workspace member updated", "workspace", m.Slug, "member", target, "role", role, "by", m.Member)
+	utils.RespondWithJSON(w, http.StatusOK, MemberResponse{Member: target, Role: string(role)})
+}
+
+// RemoveMember removes {member} from the workspace. Any member can leave;
+// removing someone else takes the ManageMembers permission, and ManageOwners
+// for an owner.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+	target := r.PathValue("member")
+
+	if target != m.Member {
+		if !m.Role.Can(authz.ManageMembers) {
+			authz.WriteError(w, r, h.App.Logger, authz.ErrForbidden, "")
+			return
+		}
+		current, ok := h.currentRole(w, r, m, target)
+		if !ok {
+			return
+		}
+		if current == authz.RoleOwner && !m.Role.Can(authz.ManageOwners) {
+			utils.RespondWithError(w, http.StatusForbidden, "Only owners can remove an owner")
+			return
+		}
+	}
+
+	n, err := h.App.Querier.DeleteWorkspaceMember(r.Context(), database.DeleteWorkspaceMemberParams{
+		WorkspaceID: m.WorkspaceID,
+		Member:      target,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to remove member")
+		logger.Error("Failed to remove member", "error", err)
+		return
+	}
+	if n == 0 {
+		if target == m.Member && m.Role == authz.RoleOwner {
+			utils.RespondWithError(w, http.StatusConflict, "A workspace must keep at least one owner")
+			return
+		}
+		utils.RespondWithError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	logger.Info("workspace member removed", "workspace", m.Slug, "member", target, "by", m.Member)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentRole returns the role target has in the workspace, or "" when it is
+// not a member. When it returns false a response has already been written.
+func (h *WorkspaceHandler) currentRole(w http.ResponseWriter, r *http.Request, m authz.Membership, target string) (authz.Role, bool) {
+	row, err := h.App.Querier.GetWorkspaceMember(r.Context(), database.GetWorkspaceMemberParams{
+		WorkspaceID: m.WorkspaceID,
+		Member:      target,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", true
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get member")
+		middleware.LoggerFromContext(r.Context(), h.App.Logger).Error("db error", "err", err)
+		return "", false
+	}
+	return authz.Role(row.Role), true
+}
+
+// ListLinks lists the links of the workspace, newest first.
+func (h *WorkspaceHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	logger := middleware.LoggerFromContext(r.Context(), h.App.Logger)
+	m, _ := authz.FromContext(r.Context())
+	q := r.URL.Query()
+
+	limit := defaultLinkPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLinkPageSize {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	before := int64(math.MaxInt64)
+	if v := q.Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid before")
+			return
+		}
+		before = n
+	}
+
+	rows, err := h.App.Querier.ListURLsByWorkspace(r.Context(), database.ListURLsByWorkspaceParams{
+		WorkspaceID: pgtype.Int8{Int64: m.WorkspaceID, Valid: true},
+		ID:          before,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to list links")
+		logger.Error("Failed to list links", "error", err)
+		return
+	}
+
+	resp := WorkspaceLinksResponse{Links: make([]WorkspaceLink, 0, len(rows))}
+	for _, row := range rows {
+		link := WorkspaceLink{
+			ShortCode:   row.ShortCode,
+			ShortURL:    shortURL(h.App.Config.BaseURL, row.Domain.String, row.ShortCode),
+			Domain:      row.Domain.String,
+			OriginalURL: row.OriginalUrl,
+			Title:       row.Title.String,
+			Status:      "active",
+			CreatedAt:   row.CreatedAt,
+			Owner:       row.Owner.String,
+		}
+		if row.DisabledAt.Valid {
+			link.Status = "disabled"
+		}
+		resp.Links = append(resp.Links, link)
+	}
+	if len(rows) == limit {
+		resp.NextBefore = rows[len(rows)-1].ID
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/nouvadev/veritas/pkg/oidc"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// Authenticate identifies callers by the JWT in their Authorization header.
+// A valid token sets the owner to the token's user, taking precedence over
+// an API key, and passes on the workspace roles it grants; an invalid one is
+// rejected. Requests without a bearer token, and bearer tokens that are not
+// JWTs such as ADMIN_TOKEN, are passed through untouched.
+func Authenticate(v *oidc.Validator, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || strings.Count(raw, ".") != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := v.Validate(r.Context(), raw)
+			if err != nil {
+				if oidc.IsKeyError(err) {
+					LoggerFromContext(r.Context(), logger).Error("could not load token signing keys", "err", err)
+					utils.RespondWithError(w, http.StatusServiceUnavailable, "Could not verify token")
+					return
+				}
+				LoggerFromContext(r.Context(), logger).Info("rejected bearer token", "err", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			ctx := WithOwner(r.Context(), id.User)
+			ctx = WithWorkspaceRoles(ctx, id.Workspaces)
+			serveWithContext(ctx, next, w, r)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaxBodySize limits request bodies to n bytes. Handlers reading past the
+// limit get an *http.MaxBytesError and should answer 413.
+func MaxBodySize(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
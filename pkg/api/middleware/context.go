@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	loggerKey
+	ownerKey
+	workspaceRolesKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// LoggerFromContext returns the request scoped logger stored in ctx, or
+// fallback if the request did not pass through RequestID.
+func LoggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// WithOwner returns a copy of ctx carrying the owner the request was made for.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey, owner)
+}
+
+// OwnerFromContext returns the owner identified by IdentifyOwner, or "" for
+// anonymous requests.
+func OwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey).(string)
+	return owner
+}
+
+// WithWorkspaceRoles returns a copy of ctx carrying roles granted to the
+// caller by its token, by workspace slug.
+func WithWorkspaceRoles(ctx context.Context, roles map[string]string) context.Context {
+	return context.WithValue(ctx, workspaceRolesKey, roles)
+}
+
+// WorkspaceRolesFromContext returns the roles stored by Authenticate, or nil.
+func WorkspaceRolesFromContext(ctx context.Context) map[string]string {
+	roles, _ := ctx.Value(workspaceRolesKey).(map[string]string)
+	return roles
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// Middleware wraps an http.Handler with additional behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middlewares to h so that the first one listed is the
+// outermost, i.e. the first to see the request.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// serveWithContext passes r with ctx to next. The ServeMux sets the pattern
+// it matched on the request it is given, so it is copied back to r for the
+// layers outside, such as tracing and metrics, to label requests by route.
+func serveWithContext(ctx context.Context, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	routed := r.WithContext(ctx)
+	next.ServeHTTP(w, routed)
+	r.Pattern = routed.Pattern
+}
+
+// responseRecorder captures the status code and body size written by the
+// wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
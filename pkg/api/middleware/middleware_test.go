@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/oidc"
+	"github.com/nouvadev/veritas/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var seen string
+	h := RequestID(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	testCases := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "Test reusing a client supplied ID", header: "abc-123", expected: "abc-123"},
+		{name: "Test replacing an ID with control characters", header: "abc\n123"},
+		{name: "Test generating an ID when none is sent"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, seen)
+			} else {
+				assert.Len(t, seen, 32)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID(logger), Recover(logger))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error":"Internal server error"}`, rec.Body.String())
+	assert.True(t, strings.Contains(logs.String(), "request_id="))
+	assert.True(t, strings.Contains(logs.String(), "panic=boom"))
+}
+
+func TestIdentifyOwner(t *testing.T) {
+	keys, err := ParseOwnerKeys([]string{"secret-key=acme"})
+	assert.NoError(t, err)
+
+	var seen string
+	h := IdentifyOwner(keys)(RequireOwner(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = OwnerFromContext(r.Context())
+	})))
+
+	testCases := []struct {
+		name     string
+		apiKey   string
+		status   int
+		expected string
+	}{
+		{name: "Test a known key", apiKey: "secret-key", status: http.StatusOK, expected: "acme"},
+		{name: "Test an unknown key", apiKey: "other-key", status: http.StatusUnauthorized},
+		{name: "Test no key", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, tc.expected, seen)
+		})
+	}
+
+	_, err = ParseOwnerKeys([]string{"missing-owner"})
+	assert.Error(t, err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	iss := oidctest.NewIssuer(t, "veritas-api")
+	v := oidc.NewValidator(oidc.NewKeySet(iss.URL, iss.JWKSURL(), time.Hour), oidc.Config{
+		Issuer:          iss.URL,
+		Audience:        "veritas-api",
+		UserClaim:       "sub",
+		WorkspacesClaim: "workspaces",
+	})
+	keys, err := ParseOwnerKeys([]string{"secret-key=acme"})
+	require.NoError(t, err)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var seen string
+	var roles map[string]string
+	h := IdentifyOwner(keys)(Authenticate(v, logger)(RequireOwner(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = OwnerFromContext(r.Context())
+		roles = WorkspaceRolesFromContext(r.Context())
+	}))))
+
+	testCases := []struct {
+		name     string
+		apiKey   string
+		auth     string
+		status   int
+		expected string
+		roles    map[string]string
+	}{
+		{
+			name:     "Test a valid token",
+			auth:     "Bearer " + iss.Token(t, "alice", map[string]any{"workspaces": []any{"growth:editor"}}),
+			status:   http.StatusOK,
+			expected: "alice",
+			roles:    map[string]string{"growth": "editor"},
+		},
+		{
+			name:     "Test a token taking precedence over an API key",
+			apiKey:   "secret-key",
+			auth:     "Bearer " + iss.Token(t, "alice", nil),
+			status:   http.StatusOK,
+			expected: "alice",
+			roles:    map[string]string{},
+		},
+		{name: "Test an expired token", auth: "Bearer " + iss.Token(t, "alice", map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}), status: http.StatusUnauthorized},
+		{name: "Test a bearer token that is not a JWT", apiKey: "secret-key", auth: "Bearer admin-token", status: http.StatusOK, expected: "acme"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen, roles = "", nil
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tc.auth)
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, tc.expected, seen)
+			assert.Equal(t, tc.roles, roles)
+			if tc.status == http.StatusUnauthorized {
+				assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "invalid_token")
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// APIKeyHeader carries the API key that identifies a link owner. It is the
+// same header the rate limiter reads, so one key can do both.
+const APIKeyHeader = "X-API-Key"
+
+// OwnerKeys maps the SHA-256 of API keys to the owners they belong to.
+type OwnerKeys map[[sha256.Size]byte]string
+
+// ParseOwnerKeys parses entries of the form "key=owner".
+func ParseOwnerKeys(entries []string) (OwnerKeys, error) {
+	keys := make(OwnerKeys, len(entries))
+	for _, entry := range entries {
+		key, owner, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || owner == "" {
+			return nil, fmt.Errorf("owner api key entry: expected key=owner")
+		}
+		keys[sha256.Sum256([]byte(key))] = owner
+	}
+	return keys, nil
+}
+
+// IdentifyOwner stores the owner of a known API key in the request context.
+// Requests without a key, or with a key that only selects a rate limit tier,
+// stay anonymous.
+func IdentifyOwner(keys OwnerKeys) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+				// Looking up the hash keeps the key itself out of map comparisons.
+				if owner, ok := keys[sha256.Sum256([]byte(apiKey))]; ok {
+					serveWithContext(WithOwner(r.Context(), owner), next, w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireOwner rejects requests that neither IdentifyOwner nor Authenticate
+// attached an owner to.
+func RequireOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if OwnerFromContext(r.Context()) == "" {
+			utils.RespondWithError(w, http.StatusUnauthorized, "An API key or bearer token is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
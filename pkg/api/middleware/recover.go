@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// Recover turns a panic in a handler into a 500 response and an error log
+// with the stack trace, instead of tearing down the connection.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := newResponseRecorder(w)
+
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				// Let the server abort the response as it normally would.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				LoggerFromContext(r.Context(), logger).Error("panic serving request",
+					"panic", err,
+					"stack", string(debug.Stack()),
+				)
+
+				if !rec.wroteHeader {
+					utils.RespondWithError(rec, http.StatusInternalServerError, "Internal server error")
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
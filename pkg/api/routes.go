@@ -3,30 +3,171 @@ package api
 import (
 	"net/http"
 
+	"github.com/nouvadev/veritas/pkg/api/authz"
 	"github.com/nouvadev/veritas/pkg/api/handlers"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
 	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/qr"
+	"github.com/nouvadev/veritas/pkg/telemetry"
 )
 
+// maxCreateBodyBytes bounds the JSON body accepted by the create endpoint.
+const maxCreateBodyBytes = 1 << 20
+
+// maxReportBodyBytes bounds the JSON body of abuse reports.
+const maxReportBodyBytes = 16 << 10
+
+// maxWorkspaceBodyBytes bounds the JSON bodies of the workspace endpoints.
+const maxWorkspaceBodyBytes = 16 << 10
+
 func CreateURLRoutes(app *config.AppConfig) http.Handler {
 	mux := http.NewServeMux()
 
-	h := handlers.NewHealthcheckHandler(app)
 	u := handlers.NewURLHandler(app)
+	rp := handlers.NewReportHandler(app)
+	q := handlers.NewQRHandler(app)
+	d := handlers.NewDomainHandler(app)
+	ws := handlers.NewWorkspaceHandler(app)
+	wh := handlers.NewWebhookHandler(app)
 
-	mux.HandleFunc("GET /healthcheck", h.HealthcheckHandler)
-	mux.HandleFunc("POST /create", u.CreateShortURL)
+	// inWorkspace guards routes under /api/workspaces/{workspace} with the
+	// caller's role in that workspace.
+	authorizer := authz.New(app.Querier)
+	inWorkspace := func(action authz.Action, h http.Handler) http.Handler {
+		return authorizer.Require(action, app.Logger)(h)
+	}
+	limitBody := middleware.MaxBodySize(maxWorkspaceBodyBytes)
+
+	mux.Handle("POST /api/create", rateLimited(app, "create",
+		middleware.MaxBodySize(maxCreateBodyBytes)(http.HandlerFunc(u.CreateShortURL))))
+	mux.HandleFunc("GET /api/links/{code}", u.GetLinkDetails)
+	mux.Handle("PATCH /api/links/{code}", limitBody(http.HandlerFunc(u.UpdateLink)))
+	mux.HandleFunc("DELETE /api/links/{code}", u.DeleteLink)
+	mux.Handle("GET /api/links/{code}/qr", rateLimited(app, "qr", http.HandlerFunc(q.GetQRCode)))
+	mux.Handle("GET /api/workspaces", middleware.RequireOwner(http.HandlerFunc(ws.ListWorkspaces)))
+	mux.Handle("POST /api/workspaces", middleware.RequireOwner(limitBody(http.HandlerFunc(ws.CreateWorkspace))))
+	mux.Handle("GET /api/workspaces/{workspace}", inWorkspace(authz.View, http.HandlerFunc(ws.GetWorkspace)))
+	mux.Handle("GET /api/workspaces/{workspace}/links", inWorkspace(authz.View, http.HandlerFunc(ws.ListLinks)))
+	mux.Handle("GET /api/workspaces/{workspace}/members", inWorkspace(authz.View, http.HandlerFunc(ws.ListMembers)))
+	mux.Handle("PUT /api/workspaces/{workspace}/members/{member}",
+		inWorkspace(authz.ManageMembers, limitBody(http.HandlerFunc(ws.PutMember))))
+	// Members may always leave; RemoveMember checks the role for removing others.
+	mux.Handle("DELETE /api/workspaces/{workspace}/members/{member}", inWorkspace(authz.View, http.HandlerFunc(ws.RemoveMember)))
+	mux.Handle("GET /api/workspaces/{workspace}/domains", inWorkspace(authz.View, http.HandlerFunc(d.ListDomains)))
+	mux.Handle("POST /api/workspaces/{workspace}/domains",
+		inWorkspace(authz.ManageWorkspace, limitBody(http.HandlerFunc(d.CreateDomain))))
+	mux.Handle("POST /api/workspaces/{workspace}/domains/{hostname}/verify",
+		inWorkspace(authz.ManageWorkspace, http.HandlerFunc(d.VerifyDomain)))
+	mux.Handle("GET /api/workspaces/{workspace}/logo", inWorkspace(authz.View, http.HandlerFunc(q.GetLogo)))
+	mux.Handle("PUT /api/workspaces/{workspace}/logo",
+		inWorkspace(authz.ManageWorkspace, middleware.MaxBodySize(qr.MaxLogoBytes)(http.HandlerFunc(q.UploadLogo))))
+	mux.Handle("DELETE /api/workspaces/{workspace}/logo", inWorkspace(authz.ManageWorkspace, http.HandlerFunc(q.DeleteLogo)))
+	mux.Handle("GET /api/workspaces/{workspace}/webhooks", inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.ListWebhooks)))
+	mux.Handle("POST /api/workspaces/{workspace}/webhooks",
+		inWorkspace(authz.ManageWorkspace, limitBody(http.HandlerFunc(wh.CreateWebhook))))
+	mux.Handle("GET /api/workspaces/{workspace}/webhooks/{id}", inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.GetWebhook)))
+	mux.Handle("DELETE /api/workspaces/{workspace}/webhooks/{id}", inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.DeleteWebhook)))
+	mux.Handle("GET /api/workspaces/{workspace}/webhooks/{id}/deliveries",
+		inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.ListDeliveries)))
+	mux.Handle("GET /api/workspaces/{workspace}/webhooks/{id}/deliveries/{delivery}",
+		inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.GetDelivery)))
+	mux.Handle("POST /api/workspaces/{workspace}/webhooks/{id}/deliveries/{delivery}/redeliver",
+		inWorkspace(authz.ManageWorkspace, http.HandlerFunc(wh.Redeliver)))
+	mux.Handle("POST /api/report/{code}", rateLimited(app, "report",
+		middleware.MaxBodySize(maxReportBodyBytes)(http.HandlerFunc(rp.CreateReport))))
+
+	// The moderation endpoints only exist when an admin token is configured.
+	if app.Config.Admin.Token != "" {
+		a := handlers.NewAdminHandler(app)
+		admin := middleware.RequireBearerToken(app.Config.Admin.Token)
+
+		mux.Handle("GET /api/admin/reports", admin(http.HandlerFunc(a.ListReports)))
+		mux.Handle("POST /api/admin/reports/{id}/dismiss", admin(http.HandlerFunc(a.DismissReport)))
+		mux.Handle("POST /api/admin/links/{code}/disable", admin(http.HandlerFunc(a.DisableLink)))
+		mux.Handle("POST /api/admin/links/{code}/restore", admin(http.HandlerFunc(a.RestoreLink)))
+	}
 
-	return mux
+	return withMiddleware(app, identify(app, mux))
 }
 
 func RedirectRoutes(app *config.AppConfig) http.Handler {
 	mux := http.NewServeMux()
 
-	h := handlers.NewHealthcheckHandler(app)
 	u := handlers.NewURLHandler(app)
 
+	mux.Handle("GET /{short_code}", rateLimited(app, "redirect", http.HandlerFunc(u.RedirectToOriginalURL)))
+
+	return withMiddleware(app, mux)
+}
+
+func AnalyticsRoutes(app *config.AppConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	st := handlers.NewStatsHandler(app)
+
+	authorizer := authz.New(app.Querier)
+	inWorkspace := func(action authz.Action, h http.Handler) http.Handler {
+		return authorizer.Require(action, app.Logger)(h)
+	}
+
+	mux.HandleFunc("GET /api/analytics/links/{code}", st.GetLinkStats)
+	mux.HandleFunc("GET /api/analytics/links/{code}/breakdown/{dimension}", st.GetLinkBreakdown)
+	mux.Handle("GET /api/analytics/workspaces/{workspace}/breakdown/{dimension}",
+		inWorkspace(authz.View, http.HandlerFunc(st.GetWorkspaceBreakdown)))
+	mux.HandleFunc("GET /api/analytics/links/{code}/export", st.ExportLinkClicks)
+	mux.Handle("GET /api/analytics/workspaces/{workspace}/export",
+		inWorkspace(authz.View, http.HandlerFunc(st.ExportWorkspaceClicks)))
+	mux.HandleFunc("GET /api/links/{code}/live", st.StreamLinkClicks)
+	mux.Handle("GET /api/workspaces/{workspace}/live", inWorkspace(authz.View, http.HandlerFunc(st.StreamWorkspaceClicks)))
+
+	return withMiddleware(app, identify(app, mux))
+}
+
+// InternalRoutes serves the metrics and health probes of a service. They
+// are kept off the public routes, on the internal port the ingress does not
+// expose, so that they neither leak nor shadow short codes.
+func InternalRoutes(app *config.AppConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	h := handlers.NewHealthcheckHandler(app)
+
 	mux.HandleFunc("GET /healthcheck", h.HealthcheckHandler)
-	mux.HandleFunc("GET /{short_code}", u.RedirectToOriginalURL)
+	mux.HandleFunc("GET /livez", h.Livez)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return middleware.Recover(app.Logger)(mux)
+}
+
+// rateLimited applies the limits configured for route to h, if rate limiting is enabled.
+func rateLimited(app *config.AppConfig, route string, h http.Handler) http.Handler {
+	if app.RateLimiter == nil {
+		return h
+	}
+	return app.RateLimiter.Middleware(route, app.Config.HTTP.TrustedProxyPrefixes(), app.Logger)(h)
+}
+
+// identify attaches the caller identified by an API key or bearer token to
+// requests. Authenticate runs inside IdentifyOwner so a valid token
+// overrides the owner of an API key sent along with it.
+func identify(app *config.AppConfig, h http.Handler) http.Handler {
+	if app.Tokens != nil {
+		h = middleware.Authenticate(app.Tokens, app.Logger)(h)
+	}
+	return middleware.IdentifyOwner(app.Config.Owners.Keys())(h)
+}
 
-	return mux
+// withMiddleware wraps a service mux with the middleware chain shared by all
+// HTTP services. Tracing comes first so every other layer runs inside the
+// request span, and recovery sits inside the access log and metrics so panics
+// are logged and counted with the 500 they turn into.
+func withMiddleware(app *config.AppConfig, mux http.Handler) http.Handler {
+	return middleware.Chain(mux,
+		telemetry.Middleware,
+		middleware.RequestID(app.Logger),
+		middleware.AccessLog(app.Logger),
+		metrics.Middleware,
+		middleware.Recover(app.Logger),
+	)
 }
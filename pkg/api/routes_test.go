@@ -0,0 +1,83 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	app := &config.AppConfig{
+		Config: &config.Config{Owners: config.OwnersConfig{APIKeys: []string{"secret-key=acme"}}},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/links/{code}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/boom", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	h := withMiddleware(app, identify(app, mux))
+
+	testCases := []struct {
+		name   string
+		path   string
+		apiKey string
+		route  string
+		status int
+	}{
+		{name: "Test an anonymous request", path: "/api/links/abc123", route: "GET /api/links/{code}"},
+		{name: "Test a request identified by an API key", path: "/api/links/abc123", apiKey: "secret-key", route: "GET /api/links/{code}"},
+		{name: "Test a request that panics", path: "/api/boom", route: "GET /api/boom", status: http.StatusInternalServerError},
+		{name: "Test an unmatched request", path: "/nowhere", status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.apiKey != "" {
+				req.Header.Set(middleware.APIKeyHeader, tc.apiKey)
+			}
+			if tc.status == 0 {
+				tc.status = http.StatusOK
+			}
+			route := tc.route
+			if route == "" {
+				route = "unmatched"
+			}
+			requests := metrics.HTTPRequests.WithLabelValues(route, http.MethodGet, strconv.Itoa(tc.status))
+			before := testutil.ToFloat64(requests)
+
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, before+1, testutil.ToFloat64(requests), "requests counted by route and status")
+
+			ended := spans.Ended()
+			require.NotEmpty(t, ended)
+			span := ended[len(ended)-1]
+			if tc.route == "" {
+				assert.Equal(t, "http.request", span.Name())
+				return
+			}
+			assert.Equal(t, tc.route, span.Name())
+			assert.Contains(t, span.Attributes(), attribute.KeyValue(semconv.HTTPRoute(tc.route)))
+		})
+	}
+}
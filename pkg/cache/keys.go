@@ -0,0 +1,11 @@
+package cache
+
+// LinkKey is the redirect cache key of a link. Links on the default domain
+// are keyed by their short code alone, as they always have been; links on a
+// custom domain are prefixed with its hostname.
+func LinkKey(domain, shortCode string) string {
+	if domain == "" {
+		return shortCode
+	}
+	return domain + "/" + shortCode
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,6 +16,10 @@ func ConnectRedis(redisURL string) (*redis.Client, error) {
 
 	client := redis.NewClient(opts)
 
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return nil, fmt.Errorf("could not instrument redis tracing: %w", err)
+	}
+
 	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, fmt.Errorf("could not ping redis: %w", err)
 	}
@@ -2,18 +2,63 @@ package config
 
 import (
 	"log/slog"
+	"sync/atomic"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/analytics"
 	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/domains"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	"github.com/nouvadev/veritas/pkg/oidc"
+	"github.com/nouvadev/veritas/pkg/outbox"
+	"github.com/nouvadev/veritas/pkg/publisher"
+	"github.com/nouvadev/veritas/pkg/ratelimit"
+	"github.com/nouvadev/veritas/pkg/reputation"
 	"github.com/redis/go-redis/v9"
 )
 
 // AppConfig struct holds the dependencies for our HTTP handlers, helpers, and middleware.
 type AppConfig struct {
+	Config  *Config
 	Logger  *slog.Logger
 	DB      *pgxpool.Pool
 	Querier sqlc.Querier
 	Cache   *redis.Client
 	NATS    *nats.Conn
+
+	// Events publishes click events. It is publisher.Noop in services that
+	// do not publish them and when NATS is not configured.
+	Events publisher.EventPublisher
+
+	// RateLimiter is nil when rate limiting is disabled or Redis is not configured.
+	RateLimiter *ratelimit.Limiter
+
+	// Reputation is nil when no blocklists are configured.
+	Reputation reputation.Checker
+
+	// Domains resolves the Host of redirects to a custom domain. It is nil
+	// in services that do not serve redirects, which then only know the
+	// default domain.
+	Domains *domains.Registry
+
+	// Tokens validates OIDC bearer tokens. It is nil when OIDC is not
+	// configured, and bearer tokens are then not accepted.
+	Tokens *oidc.Validator
+
+	// Outbox publishes the events handlers add to the outbox. It is nil in
+	// services that do not publish link events and when NATS is not
+	// configured, and events then wait in the outbox.
+	Outbox *outbox.Relay
+
+	// Metadata is nil in services that do not fetch destination metadata.
+	Metadata *metadata.Worker
+
+	// Live streams the clicks the analytics service records. It is nil in
+	// other services.
+	Live *analytics.Live
+
+	// Draining is set once shutdown starts so readiness checks fail and
+	// Kubernetes stops routing new traffic to the pod.
+	Draining atomic.Bool
 }
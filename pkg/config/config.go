@@ -0,0 +1,315 @@
+package config
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/analytics"
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	"github.com/nouvadev/veritas/pkg/oidc"
+	"github.com/nouvadev/veritas/pkg/publisher"
+	"github.com/nouvadev/veritas/pkg/server"
+	"github.com/nouvadev/veritas/pkg/telemetry"
+	"github.com/nouvadev/veritas/pkg/utils"
+	"github.com/nouvadev/veritas/pkg/webhooks"
+)
+
+// Config is the typed configuration shared by all services. Every field is
+// read from the environment variable named in its env tag, optionally from a
+// YAML file, and from a file named by the <ENV>_FILE variable for secrets.
+// ${SERVICE} in an env tag is replaced with the upper-cased service name.
+type Config struct {
+	// Service is the name of the running service, e.g. "creator".
+	Service string `yaml:"-"`
+	// PrintConfig is set by the --print-config flag.
+	PrintConfig bool `yaml:"-"`
+
+	// BaseURL is the public origin short links are built on.
+	BaseURL string `yaml:"base_url" env:"BASE_URL" default:"http://localhost:8080"`
+
+	HTTP       HTTPConfig       `yaml:"http"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Redis      RedisConfig      `yaml:"redis"`
+	NATS       NATSConfig       `yaml:"nats"`
+	Events     EventsConfig     `yaml:"events"`
+	Telemetry  TelemetryConfig  `yaml:"telemetry"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Reputation ReputationConfig `yaml:"reputation"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Metadata   MetadataConfig   `yaml:"metadata"`
+	Owners     OwnersConfig     `yaml:"owners"`
+	QR         QRConfig         `yaml:"qr"`
+	Domains    DomainsConfig    `yaml:"domains"`
+	OIDC       OIDCConfig       `yaml:"oidc"`
+	Webhooks   WebhooksConfig   `yaml:"webhooks"`
+	Analytics  AnalyticsConfig  `yaml:"analytics"`
+}
+
+// HTTPConfig configures the HTTP server of a service.
+type HTTPConfig struct {
+	Port              string        `yaml:"port" env:"${SERVICE}_PORT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"10s"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"15s"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"60s"`
+	DrainDelay        time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"20s"`
+	// InternalPort serves metrics and health probes apart from the public
+	// routes, so that they are not reachable through the ingress.
+	InternalPort string `yaml:"internal_port" env:"${SERVICE}_INTERNAL_PORT"`
+	// TrustedProxies are the networks whose X-Forwarded-For header is believed
+	// when working out a client's address.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" default:"10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.0/8,::1"`
+	// CountryHeader names the header trusted proxies set to the client's
+	// country, such as CF-IPCountry behind Cloudflare.
+	CountryHeader string `yaml:"country_header" env:"COUNTRY_HEADER"`
+}
+
+// DatabaseConfig configures the Postgres connection pool.
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DATABASE_URL" secret:"true"`
+}
+
+// RedisConfig configures the Redis client.
+type RedisConfig struct {
+	URL string `yaml:"url" env:"REDIS_URL" secret:"true"`
+}
+
+// NATSConfig configures the NATS connection.
+type NATSConfig struct {
+	URL string `yaml:"url" env:"NATS_URL" secret:"true"`
+}
+
+// EventsConfig configures how the redirector publishes click events. They
+// are buffered in memory and spooled to disk while NATS is unavailable.
+type EventsConfig struct {
+	BufferSize    int    `yaml:"buffer_size" env:"EVENTS_BUFFER_SIZE" default:"10000"`
+	SpoolDir      string `yaml:"spool_dir" env:"EVENTS_SPOOL_DIR" default:"/tmp/veritas-spool"`
+	SpoolMaxBytes int64  `yaml:"spool_max_bytes" env:"EVENTS_SPOOL_MAX_BYTES" default:"268435456"`
+	// RetryInterval is how often spooled events are replayed.
+	RetryInterval time.Duration `yaml:"retry_interval" env:"EVENTS_RETRY_INTERVAL" default:"1s"`
+}
+
+// TelemetryConfig configures trace export.
+type TelemetryConfig struct {
+	Exporter     string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+}
+
+// RateLimitConfig configures per-client rate limits. Rules have the form
+// "route:tier=rate/period[/burst]"; API keys have the form "key=tier".
+type RateLimitConfig struct {
+	Enabled bool     `yaml:"enabled" env:"RATE_LIMIT_ENABLED" default:"true"`
+	Rules   []string `yaml:"rules" env:"RATE_LIMIT_RULES" default:"create:anonymous=10/1m/20,create:standard=120/1m,redirect:anonymous=300/1m/60,redirect:standard=3000/1m,report:anonymous=5/1h,qr:anonymous=60/1m"`
+	APIKeys []string `yaml:"api_keys" env:"RATE_LIMIT_API_KEYS" secret:"true"`
+}
+
+// ReputationConfig configures the local lists destinations are checked
+// against. With no files configured every destination is accepted.
+type ReputationConfig struct {
+	BlocklistFile   string   `yaml:"blocklist_file" env:"REPUTATION_BLOCKLIST_FILE"`
+	HashPrefixFiles []string `yaml:"hash_prefix_files" env:"REPUTATION_HASH_PREFIX_FILES"`
+	// RescanInterval is how often existing links are checked again; zero disables rescans.
+	RescanInterval time.Duration `yaml:"rescan_interval" env:"REPUTATION_RESCAN_INTERVAL" default:"1h"`
+}
+
+// AdminConfig configures access to the moderation endpoints.
+type AdminConfig struct {
+	// Token is the bearer token admin requests must present. The admin
+	// endpoints are disabled when it is empty.
+	Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+// OwnersConfig maps API keys to the identities of callers, which are what
+// workspace memberships refer to. Entries have the form "key=owner".
+type OwnersConfig struct {
+	APIKeys []string `yaml:"api_keys" env:"OWNER_API_KEYS" secret:"true"`
+}
+
+// OIDCConfig configures authentication with JWTs issued by an OpenID
+// Connect provider. Bearer tokens are ignored when Issuer is empty.
+type OIDCConfig struct {
+	Issuer   string `yaml:"issuer" env:"OIDC_ISSUER"`
+	Audience string `yaml:"audience" env:"OIDC_AUDIENCE"`
+	// JWKSURL is an http(s) or file URL of the provider's keys. It is
+	// discovered from the issuer's OpenID configuration when empty.
+	JWKSURL      string        `yaml:"jwks_url" env:"OIDC_JWKS_URL"`
+	JWKSCacheTTL time.Duration `yaml:"jwks_cache_ttl" env:"OIDC_JWKS_CACHE_TTL" default:"1h"`
+	// UserClaim names the claim holding the caller's identity.
+	UserClaim string `yaml:"user_claim" env:"OIDC_USER_CLAIM" default:"sub"`
+	// WorkspacesClaim optionally names a claim granting workspace roles.
+	WorkspacesClaim string        `yaml:"workspaces_claim" env:"OIDC_WORKSPACES_CLAIM"`
+	ClockSkew       time.Duration `yaml:"clock_skew" env:"OIDC_CLOCK_SKEW" default:"1m"`
+}
+
+// QRConfig configures QR code generation.
+type QRConfig struct {
+	// CacheTTL is how long rendered codes are kept in Redis.
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"QR_CACHE_TTL" default:"24h"`
+}
+
+// DomainsConfig configures custom short domains.
+type DomainsConfig struct {
+	// Resolver is the host:port of the DNS server TXT challenges are looked
+	// up on; the system resolver is used when it is empty.
+	Resolver      string        `yaml:"resolver" env:"DOMAINS_DNS_RESOLVER"`
+	VerifyTimeout time.Duration `yaml:"verify_timeout" env:"DOMAINS_VERIFY_TIMEOUT" default:"5s"`
+	// RefreshInterval is how often the redirector reloads verified domains.
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"DOMAINS_REFRESH_INTERVAL" default:"30s"`
+}
+
+// WebhooksConfig configures delivery of events to workspace webhooks.
+type WebhooksConfig struct {
+	// Timeout bounds a single request to an endpoint.
+	Timeout     time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" default:"10s"`
+	MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" default:"10"`
+	// PollInterval is how often due deliveries are looked for.
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" default:"1s"`
+	Concurrency  int           `yaml:"concurrency" env:"WEBHOOK_CONCURRENCY" default:"4"`
+	// Retention is how long finished deliveries are kept; zero keeps them.
+	Retention time.Duration `yaml:"retention" env:"WEBHOOK_RETENTION" default:"720h"`
+}
+
+// AnalyticsConfig configures the click statistics of the analytics service.
+type AnalyticsConfig struct {
+	// VisitorRetention is how long the daily unique visitor sketches of a
+	// link are kept.
+	VisitorRetention time.Duration `yaml:"visitor_retention" env:"ANALYTICS_VISITOR_RETENTION" default:"8784h"`
+	// UserAgentRulesFile replaces the built-in rules classifying the
+	// User-Agent of clicks.
+	UserAgentRulesFile string `yaml:"user_agent_rules_file" env:"ANALYTICS_USER_AGENT_RULES_FILE"`
+	// RollupInterval is how often clicks are rolled up into the buckets the
+	// stats are read from.
+	RollupInterval time.Duration `yaml:"rollup_interval" env:"ANALYTICS_ROLLUP_INTERVAL" default:"1m"`
+	// RollupLateness is how late a click may be recorded and still count.
+	RollupLateness time.Duration `yaml:"rollup_lateness" env:"ANALYTICS_ROLLUP_LATENESS" default:"1h"`
+	// RawRetention is how long raw click events are kept.
+	RawRetention time.Duration `yaml:"raw_retention" env:"ANALYTICS_RAW_RETENTION" default:"720h"`
+	// MinuteRetention, HourRetention and DayRetention are how long the
+	// rollups of each granularity are kept, or forever when 0.
+	MinuteRetention time.Duration `yaml:"minute_retention" env:"ANALYTICS_MINUTE_RETENTION" default:"168h"`
+	HourRetention   time.Duration `yaml:"hour_retention" env:"ANALYTICS_HOUR_RETENTION" default:"2160h"`
+	DayRetention    time.Duration `yaml:"day_retention" env:"ANALYTICS_DAY_RETENTION"`
+	// LiveBuffer is the number of clicks buffered for each client of a live
+	// stream; clients falling further behind are disconnected.
+	LiveBuffer int `yaml:"live_buffer" env:"ANALYTICS_LIVE_BUFFER" default:"256"`
+	// LiveMaxClients bounds the live streams served at once.
+	LiveMaxClients int `yaml:"live_max_clients" env:"ANALYTICS_LIVE_MAX_CLIENTS" default:"1000"`
+	// LiveHeartbeat is how often idle live streams send a comment, so that
+	// proxies keep them open.
+	LiveHeartbeat time.Duration `yaml:"live_heartbeat" env:"ANALYTICS_LIVE_HEARTBEAT" default:"15s"`
+}
+
+// MetadataConfig configures fetching of destination titles, descriptions,
+// Open Graph tags and favicons after a link is created.
+type MetadataConfig struct {
+	Enabled      bool          `yaml:"enabled" env:"METADATA_FETCH_ENABLED" default:"true"`
+	Timeout      time.Duration `yaml:"timeout" env:"METADATA_FETCH_TIMEOUT" default:"10s"`
+	MaxBytes     int64         `yaml:"max_bytes" env:"METADATA_FETCH_MAX_BYTES" default:"1048576"`
+	MaxRedirects int           `yaml:"max_redirects" env:"METADATA_FETCH_MAX_REDIRECTS" default:"5"`
+	Workers      int           `yaml:"workers" env:"METADATA_FETCH_WORKERS" default:"4"`
+	QueueSize    int           `yaml:"queue_size" env:"METADATA_FETCH_QUEUE_SIZE" default:"1000"`
+	UserAgent    string        `yaml:"user_agent" env:"METADATA_FETCH_USER_AGENT" default:"VeritasBot/1.0 (+link preview)"`
+}
+
+// TrustedProxyPrefixes returns the parsed TrustedProxies. They are checked
+// when the configuration is loaded.
+func (c HTTPConfig) TrustedProxyPrefixes() []netip.Prefix {
+	prefixes, _ := utils.ParsePrefixes(c.TrustedProxies)
+	return prefixes
+}
+
+// Keys returns the parsed APIKeys. They are checked when the configuration
+// is loaded.
+func (c OwnersConfig) Keys() middleware.OwnerKeys {
+	keys, _ := middleware.ParseOwnerKeys(c.APIKeys)
+	return keys
+}
+
+// Server returns the HTTP server settings for this configuration.
+func (c HTTPConfig) Server() server.Config {
+	return server.Config{
+		Addr:              ":" + c.Port,
+		InternalAddr:      ":" + c.InternalPort,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		DrainDelay:        c.DrainDelay,
+		ShutdownTimeout:   c.ShutdownTimeout,
+	}
+}
+
+// Fetcher returns the settings of the destination metadata fetcher.
+func (c MetadataConfig) Fetcher() metadata.FetcherConfig {
+	return metadata.FetcherConfig{
+		Timeout:      c.Timeout,
+		MaxBytes:     c.MaxBytes,
+		MaxRedirects: c.MaxRedirects,
+		UserAgent:    c.UserAgent,
+	}
+}
+
+// Buffer returns the settings of the buffered event publisher.
+func (c EventsConfig) Buffer() publisher.BufferConfig {
+	return publisher.BufferConfig{
+		Size:          c.BufferSize,
+		SpoolDir:      c.SpoolDir,
+		SpoolMaxBytes: c.SpoolMaxBytes,
+		RetryInterval: c.RetryInterval,
+	}
+}
+
+// Rollups returns the settings of the click rollups.
+func (c AnalyticsConfig) Rollups() analytics.RollupConfig {
+	return analytics.RollupConfig{
+		Interval: c.RollupInterval,
+		Lateness: c.RollupLateness,
+		Retention: analytics.Retention{
+			Raw:    c.RawRetention,
+			Minute: c.MinuteRetention,
+			Hour:   c.HourRetention,
+			Day:    c.DayRetention,
+		},
+	}
+}
+
+// Worker returns the settings of the webhook delivery worker.
+func (c WebhooksConfig) Worker() webhooks.Config {
+	return webhooks.Config{
+		Timeout:      c.Timeout,
+		MaxAttempts:  c.MaxAttempts,
+		PollInterval: c.PollInterval,
+		Concurrency:  c.Concurrency,
+		Retention:    c.Retention,
+	}
+}
+
+// Validator returns the token validator for c, or nil when no issuer is
+// configured.
+func (c OIDCConfig) Validator() *oidc.Validator {
+	if c.Issuer == "" {
+		return nil
+	}
+	return oidc.NewValidator(oidc.NewKeySet(c.Issuer, c.JWKSURL, c.JWKSCacheTTL), oidc.Config{
+		Issuer:          c.Issuer,
+		Audience:        c.Audience,
+		UserClaim:       c.UserClaim,
+		WorkspacesClaim: c.WorkspacesClaim,
+		Leeway:          c.ClockSkew,
+	})
+}
+
+// Tracing returns the tracer provider settings for the service.
+func (c *Config) Tracing() telemetry.Config {
+	return telemetry.Config{
+		ServiceName:  c.Service + "-service",
+		Exporter:     c.Telemetry.Exporter,
+		OTLPEndpoint: c.Telemetry.OTLPEndpoint,
+		OTLPInsecure: c.Telemetry.OTLPInsecure,
+		SampleRatio:  c.Telemetry.SampleRatio,
+	}
+}
@@ -0,0 +1,361 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/ratelimit"
+	"github.com/nouvadev/veritas/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv names the YAML file to load when --config is not given.
+const configFileEnv = "CONFIG_FILE"
+
+const redacted = "[REDACTED]"
+
+// Options control how Load builds a Config.
+type Options struct {
+	// Service is the service name, used for ${SERVICE} env names and the tracing service name.
+	Service string
+	// DefaultPort is used when ${SERVICE}_PORT is not set.
+	DefaultPort string
+	// DefaultInternalPort is used when ${SERVICE}_INTERNAL_PORT is not set.
+	DefaultInternalPort string
+	// Required lists the env names of settings the service cannot start without.
+	Required []string
+	// Args are the command line arguments, without the program name.
+	Args []string
+}
+
+// Load builds the configuration for a service. Values are resolved in order
+// of increasing precedence: defaults, the YAML file named by --config or
+// CONFIG_FILE, environment variables, and files named by <ENV>_FILE. Every
+// problem found is reported in the returned error, not just the first one.
+func Load(opts Options) (*Config, error) {
+	cfg := &Config{Service: opts.Service}
+
+	fs := flag.NewFlagSet(opts.Service, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(configFileEnv), "path to a YAML configuration file")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the resolved configuration with secrets redacted and exit")
+	if err := fs.Parse(opts.Args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), opts.Service)
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setValue(f.value, f.def); err != nil {
+			errs = append(errs, fmt.Errorf("default for %s: %w", f.env, err))
+		}
+	}
+	if cfg.HTTP.Port == "" {
+		cfg.HTTP.Port = opts.DefaultPort
+	}
+	if cfg.HTTP.InternalPort == "" {
+		cfg.HTTP.InternalPort = opts.DefaultInternalPort
+	}
+
+	if *configFile != "" {
+		if err := loadYAML(cfg, *configFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, f := range fields {
+		raw, ok, err := lookupEnv(f.env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+
+	errs = append(errs, cfg.validate(fields, opts.Required)...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+// WriteRedacted writes the configuration as YAML with secret values masked.
+func (c *Config) WriteRedacted(w io.Writer) error {
+	copied := *c
+	for _, f := range collectFields(reflect.ValueOf(&copied).Elem(), c.Service) {
+		if !f.secret || f.value.IsZero() {
+			continue
+		}
+		if f.value.Kind() == reflect.Slice {
+			f.value.Set(reflect.ValueOf([]string{redacted}))
+			continue
+		}
+		f.value.SetString(redacted)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&copied); err != nil {
+		return fmt.Errorf("could not encode configuration: %w", err)
+	}
+	return enc.Close()
+}
+
+func (c *Config) validate(fields []field, required []string) []error {
+	var errs []error
+
+	byEnv := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byEnv[f.env] = f
+	}
+	for _, name := range required {
+		name = expandEnv(name, c.Service)
+		f, ok := byEnv[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting marked as required", name))
+			continue
+		}
+		if f.value.IsZero() {
+			errs = append(errs, fmt.Errorf("%s: is required", name))
+		}
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("BASE_URL: %q is not an absolute URL", c.BaseURL))
+	}
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("%s: %q is not a valid port", expandEnv("${SERVICE}_PORT", c.Service), c.HTTP.Port))
+	}
+	if port, err := strconv.Atoi(c.HTTP.InternalPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("%s: %q is not a valid port", expandEnv("${SERVICE}_INTERNAL_PORT", c.Service), c.HTTP.InternalPort))
+	} else if c.HTTP.InternalPort == c.HTTP.Port {
+		errs = append(errs, fmt.Errorf("%s: must differ from %s", expandEnv("${SERVICE}_INTERNAL_PORT", c.Service), expandEnv("${SERVICE}_PORT", c.Service)))
+	}
+	for _, f := range fields {
+		if d, ok := f.value.Interface().(time.Duration); ok && d < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", f.env))
+		}
+	}
+
+	switch c.Telemetry.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_EXPORTER: %q is not one of none, stdout, otlp", c.Telemetry.Exporter))
+	}
+	if c.Telemetry.SampleRatio < 0 || c.Telemetry.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %v is not between 0 and 1", c.Telemetry.SampleRatio))
+	}
+	if c.Metadata.Enabled {
+		if c.Metadata.MaxBytes <= 0 {
+			errs = append(errs, fmt.Errorf("METADATA_FETCH_MAX_BYTES: must be positive"))
+		}
+		if c.Metadata.Workers <= 0 {
+			errs = append(errs, fmt.Errorf("METADATA_FETCH_WORKERS: must be positive"))
+		}
+		if c.Metadata.QueueSize < 0 || c.Metadata.MaxRedirects < 0 {
+			errs = append(errs, fmt.Errorf("METADATA_FETCH_QUEUE_SIZE and METADATA_FETCH_MAX_REDIRECTS: must not be negative"))
+		}
+	}
+	if c.Events.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("EVENTS_BUFFER_SIZE: must not be negative"))
+	}
+	if c.Events.SpoolDir == "" {
+		errs = append(errs, fmt.Errorf("EVENTS_SPOOL_DIR: is required"))
+	}
+	if c.Events.SpoolMaxBytes <= 0 || c.Events.RetryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("EVENTS_SPOOL_MAX_BYTES and EVENTS_RETRY_INTERVAL: must be positive"))
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_TIMEOUT and WEBHOOK_POLL_INTERVAL: must be positive"))
+	}
+	if c.Webhooks.MaxAttempts <= 0 || c.Webhooks.Concurrency <= 0 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS and WEBHOOK_CONCURRENCY: must be positive"))
+	}
+	if c.Analytics.VisitorRetention <= 0 {
+		errs = append(errs, fmt.Errorf("ANALYTICS_VISITOR_RETENTION: must be positive"))
+	}
+	if c.Analytics.RollupInterval <= 0 || c.Analytics.RollupLateness <= 0 {
+		errs = append(errs, fmt.Errorf("ANALYTICS_ROLLUP_INTERVAL and ANALYTICS_ROLLUP_LATENESS: must be positive"))
+	}
+	if c.Analytics.RawRetention <= c.Analytics.RollupLateness {
+		errs = append(errs, fmt.Errorf("ANALYTICS_RAW_RETENTION: must be longer than ANALYTICS_ROLLUP_LATENESS"))
+	}
+	if c.Analytics.LiveBuffer <= 0 || c.Analytics.LiveMaxClients <= 0 || c.Analytics.LiveHeartbeat <= 0 {
+		errs = append(errs, fmt.Errorf("ANALYTICS_LIVE_BUFFER, ANALYTICS_LIVE_MAX_CLIENTS and ANALYTICS_LIVE_HEARTBEAT: must be positive"))
+	}
+	if _, err := utils.ParsePrefixes(c.HTTP.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %w", err))
+	}
+	if _, err := ratelimit.ParsePolicy(c.RateLimit.Rules, c.RateLimit.APIKeys); err != nil {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_RULES: %w", err))
+	}
+	if _, err := middleware.ParseOwnerKeys(c.Owners.APIKeys); err != nil {
+		errs = append(errs, fmt.Errorf("OWNER_API_KEYS: %w", err))
+	}
+	if c.Domains.Resolver != "" {
+		if _, _, err := net.SplitHostPort(c.Domains.Resolver); err != nil {
+			errs = append(errs, fmt.Errorf("DOMAINS_DNS_RESOLVER: %q is not a host:port address", c.Domains.Resolver))
+		}
+	}
+	if c.Domains.RefreshInterval == 0 || c.Domains.VerifyTimeout == 0 {
+		errs = append(errs, fmt.Errorf("DOMAINS_REFRESH_INTERVAL and DOMAINS_VERIFY_TIMEOUT: must be positive"))
+	}
+	if c.OIDC.Issuer != "" {
+		if u, err := url.Parse(c.OIDC.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OIDC_ISSUER: %q is not an absolute URL", c.OIDC.Issuer))
+		}
+		if c.OIDC.Audience == "" {
+			errs = append(errs, fmt.Errorf("OIDC_AUDIENCE: is required when OIDC_ISSUER is set"))
+		}
+		if c.OIDC.UserClaim == "" {
+			errs = append(errs, fmt.Errorf("OIDC_USER_CLAIM: is required when OIDC_ISSUER is set"))
+		}
+		if c.OIDC.JWKSCacheTTL == 0 {
+			errs = append(errs, fmt.Errorf("OIDC_JWKS_CACHE_TTL: must be positive"))
+		}
+	}
+	if c.OIDC.JWKSURL != "" {
+		if u, err := url.Parse(c.OIDC.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+			errs = append(errs, fmt.Errorf("OIDC_JWKS_URL: %q is not an http, https or file URL", c.OIDC.JWKSURL))
+		}
+	}
+
+	return errs
+}
+
+// field is a settable leaf of Config together with its struct tags.
+type field struct {
+	env    string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+func collectFields(v reflect.Value, service string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(fv, service)...)
+			continue
+		}
+
+		env, ok := sf.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		fields = append(fields, field{
+			env:    expandEnv(env, service),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  fv,
+		})
+	}
+	return fields
+}
+
+func expandEnv(name, service string) string {
+	return strings.ReplaceAll(name, "${SERVICE}", strings.ToUpper(service))
+}
+
+// lookupEnv reads NAME, or the contents of the file named by NAME_FILE. The
+// _FILE form lets secrets be mounted as files instead of plain env vars.
+func lookupEnv(name string) (string, bool, error) {
+	if path, ok := os.LookupEnv(name + "_FILE"); ok && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(b)), true, nil
+	}
+	raw, ok := os.LookupEnv(name)
+	if !ok || raw == "" {
+		return "", false, nil
+	}
+	return raw, true, nil
+}
+
+func loadYAML(cfg *Config, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -6,13 +6,23 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/telemetry"
 )
 
 func ConnectDB(databaseURL string) (*pgxpool.Pool, error) {
 	var err error
 	var pool *pgxpool.Pool
 
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	// Record per-query latencies and spans for every sqlc query run through the pool.
+	poolConfig.ConnConfig.Tracer = multitracer.New(&metrics.QueryTracer{}, &telemetry.QueryTracer{})
+
 	const maxRetries = 5
 	const backoff = 2 * time.Second
 
@@ -20,7 +30,7 @@ func ConnectDB(databaseURL string) (*pgxpool.Pool, error) {
 		// Create a new context for each attempt.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
-		pool, err = pgxpool.New(ctx, databaseURL)
+		pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
 		if err == nil {
 			// If pool is created, try to ping.
 			err = pool.Ping(ctx)
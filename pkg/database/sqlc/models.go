@@ -6,11 +6,150 @@ package sqlc
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Click struct {
+	ID             int64       `json:"id"`
+	UrlID          int64       `json:"url_id"`
+	WorkspaceID    pgtype.Int8 `json:"workspace_id"`
+	ClickedAt      time.Time   `json:"clicked_at"`
+	IsBot          bool        `json:"is_bot"`
+	RequestID      pgtype.Text `json:"request_id"`
+	Browser        string      `json:"browser"`
+	BrowserVersion string      `json:"browser_version"`
+	Os             string      `json:"os"`
+	DeviceType     string      `json:"device_type"`
+	Bot            string      `json:"bot"`
+	Referrer       string      `json:"referrer"`
+	Source         string      `json:"source"`
+	Medium         string      `json:"medium"`
+	Campaign       string      `json:"campaign"`
+	Channel        string      `json:"channel"`
+}
+
+type ClickRollup struct {
+	Granularity string      `json:"granularity"`
+	UrlID       int64       `json:"url_id"`
+	WorkspaceID pgtype.Int8 `json:"workspace_id"`
+	Bucket      time.Time   `json:"bucket"`
+	Dimension   string      `json:"dimension"`
+	Value       string      `json:"value"`
+	Clicks      int64       `json:"clicks"`
+	BotClicks   int64       `json:"bot_clicks"`
+}
+
+type ClickRollupState struct {
+	ID         bool      `json:"id"`
+	RolledUpTo time.Time `json:"rolled_up_to"`
+}
+
+type Domain struct {
+	ID                int64              `json:"id"`
+	Hostname          string             `json:"hostname"`
+	Owner             string             `json:"owner"`
+	VerificationToken string             `json:"verification_token"`
+	VerifiedAt        pgtype.Timestamptz `json:"verified_at"`
+	CreatedAt         time.Time          `json:"created_at"`
+	WorkspaceID       int64              `json:"workspace_id"`
+}
+
+type OutboxEvent struct {
+	ID        int64     `json:"id"`
+	Subject   string    `json:"subject"`
+	Payload   []byte    `json:"payload"`
+	Headers   []byte    `json:"headers"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Report struct {
+	ID         int64              `json:"id"`
+	UrlID      int64              `json:"url_id"`
+	Reason     string             `json:"reason"`
+	Details    string             `json:"details"`
+	ReporterIp string             `json:"reporter_ip"`
+	Status     string             `json:"status"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ResolvedAt pgtype.Timestamptz `json:"resolved_at"`
+}
+
 type Url struct {
-	ID          int64     `json:"id"`
-	ShortCode   string    `json:"short_code"`
-	OriginalUrl string    `json:"original_url"`
+	ID                 int64              `json:"id"`
+	ShortCode          string             `json:"short_code"`
+	OriginalUrl        string             `json:"original_url"`
+	CreatedAt          time.Time          `json:"created_at"`
+	DisabledAt         pgtype.Timestamptz `json:"disabled_at"`
+	DisabledReason     pgtype.Text        `json:"disabled_reason"`
+	DisabledNote       pgtype.Text        `json:"disabled_note"`
+	Interstitial       bool               `json:"interstitial"`
+	Title              pgtype.Text        `json:"title"`
+	Metadata           []byte             `json:"metadata"`
+	MetadataError      pgtype.Text        `json:"metadata_error"`
+	MetadataFetchedAt  pgtype.Timestamptz `json:"metadata_fetched_at"`
+	PreviewTitle       pgtype.Text        `json:"preview_title"`
+	PreviewDescription pgtype.Text        `json:"preview_description"`
+	PreviewImage       pgtype.Text        `json:"preview_image"`
+	Owner              pgtype.Text        `json:"owner"`
+	DomainID           pgtype.Int8        `json:"domain_id"`
+	WorkspaceID        pgtype.Int8        `json:"workspace_id"`
+}
+
+type Webhook struct {
+	ID                 int64     `json:"id"`
+	WorkspaceID        int64     `json:"workspace_id"`
+	Url                string    `json:"url"`
+	Secret             string    `json:"secret"`
+	Events             []string  `json:"events"`
+	SampleRate         float64   `json:"sample_rate"`
+	BatchSize          int32     `json:"batch_size"`
+	BatchWindowSeconds int32     `json:"batch_window_seconds"`
+	CreatedBy          string    `json:"created_by"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type WebhookAttempt struct {
+	ID             int64       `json:"id"`
+	DeliveryID     int64       `json:"delivery_id"`
+	AttemptedAt    time.Time   `json:"attempted_at"`
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+	Error          pgtype.Text `json:"error"`
+	DurationMs     int32       `json:"duration_ms"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `json:"id"`
+	WebhookID      int64              `json:"webhook_id"`
+	EventID        string             `json:"event_id"`
+	EventType      string             `json:"event_type"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  time.Time          `json:"next_attempt_at"`
+	LastAttemptAt  pgtype.Timestamptz `json:"last_attempt_at"`
+	ResponseStatus pgtype.Int4        `json:"response_status"`
+	LastError      pgtype.Text        `json:"last_error"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WorkspaceLogo struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	ContentType string    `json:"content_type"`
+	Image       []byte    `json:"image"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int64     `json:"workspace_id"`
+	Member      string    `json:"member"`
+	Role        string    `json:"role"`
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -6,13 +6,77 @@ package sqlc
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	CreateURL(ctx context.Context, originalUrl string) (int64, error)
+	ClaimOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)
+	CountOutboxEvents(ctx context.Context) (int64, error)
+	CreateClick(ctx context.Context, arg CreateClickParams) (int64, error)
+	CreateDomain(ctx context.Context, arg CreateDomainParams) (Domain, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
+	CreateReport(ctx context.Context, arg CreateReportParams) (int64, error)
+	CreateURL(ctx context.Context, arg CreateURLParams) (int64, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookAttempt(ctx context.Context, arg CreateWebhookAttemptParams) error
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (int64, error)
+	CreateWorkspace(ctx context.Context, arg CreateWorkspaceParams) (Workspace, error)
+	DeleteFinishedWebhookDeliveries(ctx context.Context, createdAt time.Time) (int64, error)
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
 	DeleteURL(ctx context.Context, id int64) error
-	GetURLByShortCode(ctx context.Context, shortCode string) (string, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	DeleteWorkspaceLogo(ctx context.Context, workspaceID int64) (int64, error)
+	DeleteWorkspaceMember(ctx context.Context, arg DeleteWorkspaceMemberParams) (int64, error)
+	DisableURL(ctx context.Context, arg DisableURLParams) (int64, error)
+	ExportClickRollups(ctx context.Context, arg ExportClickRollupsParams) ([]ExportClickRollupsRow, error)
+	ExportClicks(ctx context.Context, arg ExportClicksParams) ([]ExportClicksRow, error)
+	ExportWorkspaceClickRollups(ctx context.Context, arg ExportWorkspaceClickRollupsParams) ([]ExportWorkspaceClickRollupsRow, error)
+	ExportWorkspaceClicks(ctx context.Context, arg ExportWorkspaceClicksParams) ([]ExportWorkspaceClicksRow, error)
+	GetClickRollupState(ctx context.Context) (time.Time, error)
+	GetDomainByHostname(ctx context.Context, hostname string) (Domain, error)
+	GetMembership(ctx context.Context, arg GetMembershipParams) (GetMembershipRow, error)
+	GetMembershipByID(ctx context.Context, arg GetMembershipByIDParams) (GetMembershipByIDRow, error)
+	GetURLByShortCode(ctx context.Context, arg GetURLByShortCodeParams) (GetURLByShortCodeRow, error)
+	GetURLDetails(ctx context.Context, arg GetURLDetailsParams) (Url, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error)
+	GetWorkspaceByID(ctx context.Context, id int64) (Workspace, error)
+	GetWorkspaceBySlug(ctx context.Context, slug string) (Workspace, error)
+	GetWorkspaceLogo(ctx context.Context, workspaceID int64) (WorkspaceLogo, error)
+	GetWorkspaceMember(ctx context.Context, arg GetWorkspaceMemberParams) (WorkspaceMember, error)
+	ListClickTotals(ctx context.Context, arg ListClickTotalsParams) ([]ListClickTotalsRow, error)
+	ListDomainsByWorkspace(ctx context.Context, workspaceID int64) ([]Domain, error)
+	ListEnabledURLs(ctx context.Context, arg ListEnabledURLsParams) ([]ListEnabledURLsRow, error)
+	ListReports(ctx context.Context, arg ListReportsParams) ([]ListReportsRow, error)
+	ListURLsByWorkspace(ctx context.Context, arg ListURLsByWorkspaceParams) ([]ListURLsByWorkspaceRow, error)
+	ListVerifiedDomains(ctx context.Context) ([]ListVerifiedDomainsRow, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]ListWebhookDeliveriesRow, error)
+	ListWebhooksByWorkspace(ctx context.Context, workspaceID int64) ([]Webhook, error)
+	ListWebhooksForEvent(ctx context.Context, arg ListWebhooksForEventParams) ([]Webhook, error)
+	ListWorkspaceMembers(ctx context.Context, workspaceID int64) ([]WorkspaceMember, error)
+	ListWorkspacesByMember(ctx context.Context, member string) ([]ListWorkspacesByMemberRow, error)
+	MarkDomainVerified(ctx context.Context, id int64) (pgtype.Timestamptz, error)
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) error
+	RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (int64, error)
+	ResolveReport(ctx context.Context, arg ResolveReportParams) (int64, error)
+	ResolveReportsForURL(ctx context.Context, arg ResolveReportsForURLParams) error
+	RestoreURL(ctx context.Context, id int64) (int64, error)
+	RollupClickRollups(ctx context.Context, arg RollupClickRollupsParams) (int64, error)
+	RollupClicks(ctx context.Context, arg RollupClicksParams) (int64, error)
+	SetClickRollupState(ctx context.Context, rolledUpTo time.Time) error
+	SumClicksByValue(ctx context.Context, arg SumClicksByValueParams) ([]SumClicksByValueRow, error)
+	SumWorkspaceClicksByValue(ctx context.Context, arg SumWorkspaceClicksByValueParams) ([]SumWorkspaceClicksByValueRow, error)
+	TryLockClickRollups(ctx context.Context) (bool, error)
 	UpdateShortCode(ctx context.Context, arg UpdateShortCodeParams) error
+	UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error)
+	UpdateURLMetadata(ctx context.Context, arg UpdateURLMetadataParams) error
+	UpsertWorkspaceLogo(ctx context.Context, arg UpsertWorkspaceLogoParams) error
+	UpsertWorkspaceMember(ctx context.Context, arg UpsertWorkspaceMemberParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
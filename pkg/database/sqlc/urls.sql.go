@@ -7,14 +7,41 @@ package sqlc
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createURL = `-- name: CreateURL :one
-INSERT INTO urls (original_url) VALUES ($1) RETURNING id
+INSERT INTO urls (original_url, interstitial, title, preview_title, preview_description, preview_image, owner, domain_id, workspace_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id
 `
 
-func (q *Queries) CreateURL(ctx context.Context, originalUrl string) (int64, error) {
-	row := q.db.QueryRow(ctx, createURL, originalUrl)
+type CreateURLParams struct {
+	OriginalUrl        string      `json:"original_url"`
+	Interstitial       bool        `json:"interstitial"`
+	Title              pgtype.Text `json:"title"`
+	PreviewTitle       pgtype.Text `json:"preview_title"`
+	PreviewDescription pgtype.Text `json:"preview_description"`
+	PreviewImage       pgtype.Text `json:"preview_image"`
+	Owner              pgtype.Text `json:"owner"`
+	DomainID           pgtype.Int8 `json:"domain_id"`
+	WorkspaceID        pgtype.Int8 `json:"workspace_id"`
+}
+
+func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createURL,
+		arg.OriginalUrl,
+		arg.Interstitial,
+		arg.Title,
+		arg.PreviewTitle,
+		arg.PreviewDescription,
+		arg.PreviewImage,
+		arg.Owner,
+		arg.DomainID,
+		arg.WorkspaceID,
+	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
@@ -29,15 +56,225 @@ func (q *Queries) DeleteURL(ctx context.Context, id int64) error {
 	return err
 }
 
+const disableURL = `-- name: DisableURL :execrows
+UPDATE urls SET disabled_at = now(), disabled_reason = $2, disabled_note = $3
+WHERE id = $1 AND disabled_at IS NULL
+`
+
+type DisableURLParams struct {
+	ID             int64       `json:"id"`
+	DisabledReason pgtype.Text `json:"disabled_reason"`
+	DisabledNote   pgtype.Text `json:"disabled_note"`
+}
+
+func (q *Queries) DisableURL(ctx context.Context, arg DisableURLParams) (int64, error) {
+	result, err := q.db.Exec(ctx, disableURL, arg.ID, arg.DisabledReason, arg.DisabledNote)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getURLByShortCode = `-- name: GetURLByShortCode :one
-SELECT original_url FROM urls WHERE short_code = $1
+SELECT id, original_url, interstitial,
+       COALESCE(title, metadata->>'og_title', metadata->>'title') AS title,
+       disabled_at, disabled_reason, metadata, preview_title, preview_description, preview_image, workspace_id
+FROM urls WHERE short_code = $1 AND domain_id IS NOT DISTINCT FROM $2
+`
+
+type GetURLByShortCodeParams struct {
+	ShortCode string      `json:"short_code"`
+	DomainID  pgtype.Int8 `json:"domain_id"`
+}
+
+type GetURLByShortCodeRow struct {
+	ID                 int64              `json:"id"`
+	OriginalUrl        string             `json:"original_url"`
+	Interstitial       bool               `json:"interstitial"`
+	Title              pgtype.Text        `json:"title"`
+	DisabledAt         pgtype.Timestamptz `json:"disabled_at"`
+	DisabledReason     pgtype.Text        `json:"disabled_reason"`
+	Metadata           []byte             `json:"metadata"`
+	PreviewTitle       pgtype.Text        `json:"preview_title"`
+	PreviewDescription pgtype.Text        `json:"preview_description"`
+	PreviewImage       pgtype.Text        `json:"preview_image"`
+	WorkspaceID        pgtype.Int8        `json:"workspace_id"`
+}
+
+func (q *Queries) GetURLByShortCode(ctx context.Context, arg GetURLByShortCodeParams) (GetURLByShortCodeRow, error) {
+	row := q.db.QueryRow(ctx, getURLByShortCode, arg.ShortCode, arg.DomainID)
+	var i GetURLByShortCodeRow
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.Interstitial,
+		&i.Title,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.Metadata,
+		&i.PreviewTitle,
+		&i.PreviewDescription,
+		&i.PreviewImage,
+		&i.WorkspaceID,
+	)
+	return i, err
+}
+
+const getURLDetails = `-- name: GetURLDetails :one
+SELECT id, short_code, original_url, created_at, disabled_at, disabled_reason, disabled_note, interstitial, title, metadata, metadata_error, metadata_fetched_at, preview_title, preview_description, preview_image, owner, domain_id, workspace_id FROM urls WHERE short_code = $1 AND domain_id IS NOT DISTINCT FROM $2
+`
+
+type GetURLDetailsParams struct {
+	ShortCode string      `json:"short_code"`
+	DomainID  pgtype.Int8 `json:"domain_id"`
+}
+
+func (q *Queries) GetURLDetails(ctx context.Context, arg GetURLDetailsParams) (Url, error) {
+	row := q.db.QueryRow(ctx, getURLDetails, arg.ShortCode, arg.DomainID)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.CreatedAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.DisabledNote,
+		&i.Interstitial,
+		&i.Title,
+		&i.Metadata,
+		&i.MetadataError,
+		&i.MetadataFetchedAt,
+		&i.PreviewTitle,
+		&i.PreviewDescription,
+		&i.PreviewImage,
+		&i.Owner,
+		&i.DomainID,
+		&i.WorkspaceID,
+	)
+	return i, err
+}
+
+const listEnabledURLs = `-- name: ListEnabledURLs :many
+SELECT u.id, u.short_code, u.original_url, u.title, u.interstitial, u.owner, d.hostname AS domain, u.workspace_id
+FROM urls u
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE u.disabled_at IS NULL AND u.short_code IS NOT NULL AND u.id > $1
+ORDER BY u.id
+LIMIT $2
+`
+
+type ListEnabledURLsParams struct {
+	ID    int64 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+type ListEnabledURLsRow struct {
+	ID           int64       `json:"id"`
+	ShortCode    string      `json:"short_code"`
+	OriginalUrl  string      `json:"original_url"`
+	Title        pgtype.Text `json:"title"`
+	Interstitial bool        `json:"interstitial"`
+	Owner        pgtype.Text `json:"owner"`
+	Domain       pgtype.Text `json:"domain"`
+	WorkspaceID  pgtype.Int8 `json:"workspace_id"`
+}
+
+func (q *Queries) ListEnabledURLs(ctx context.Context, arg ListEnabledURLsParams) ([]ListEnabledURLsRow, error) {
+	rows, err := q.db.Query(ctx, listEnabledURLs, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListEnabledURLsRow{}
+	for rows.Next() {
+		var i ListEnabledURLsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.Interstitial,
+			&i.Owner,
+			&i.Domain,
+			&i.WorkspaceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listURLsByWorkspace = `-- name: ListURLsByWorkspace :many
+SELECT u.id, u.short_code, u.original_url, u.title, u.owner, u.created_at, u.disabled_at, d.hostname AS domain
+FROM urls u
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE u.workspace_id = $1 AND u.id < $2
+ORDER BY u.id DESC
+LIMIT $3
+`
+
+type ListURLsByWorkspaceParams struct {
+	WorkspaceID pgtype.Int8 `json:"workspace_id"`
+	ID          int64       `json:"id"`
+	Limit       int32       `json:"limit"`
+}
+
+type ListURLsByWorkspaceRow struct {
+	ID          int64              `json:"id"`
+	ShortCode   string             `json:"short_code"`
+	OriginalUrl string             `json:"original_url"`
+	Title       pgtype.Text        `json:"title"`
+	Owner       pgtype.Text        `json:"owner"`
+	CreatedAt   time.Time          `json:"created_at"`
+	DisabledAt  pgtype.Timestamptz `json:"disabled_at"`
+	Domain      pgtype.Text        `json:"domain"`
+}
+
+func (q *Queries) ListURLsByWorkspace(ctx context.Context, arg ListURLsByWorkspaceParams) ([]ListURLsByWorkspaceRow, error) {
+	rows, err := q.db.Query(ctx, listURLsByWorkspace, arg.WorkspaceID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListURLsByWorkspaceRow{}
+	for rows.Next() {
+		var i ListURLsByWorkspaceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.Owner,
+			&i.CreatedAt,
+			&i.DisabledAt,
+			&i.Domain,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreURL = `-- name: RestoreURL :execrows
+UPDATE urls SET disabled_at = NULL, disabled_reason = NULL, disabled_note = NULL
+WHERE id = $1 AND disabled_at IS NOT NULL
 `
 
-func (q *Queries) GetURLByShortCode(ctx context.Context, shortCode string) (string, error) {
-	row := q.db.QueryRow(ctx, getURLByShortCode, shortCode)
-	var original_url string
-	err := row.Scan(&original_url)
-	return original_url, err
+func (q *Queries) RestoreURL(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreURL, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateShortCode = `-- name: UpdateShortCode :exec
@@ -53,3 +290,62 @@ func (q *Queries) UpdateShortCode(ctx context.Context, arg UpdateShortCodeParams
 	_, err := q.db.Exec(ctx, updateShortCode, arg.ShortCode, arg.ID)
 	return err
 }
+
+const updateURL = `-- name: UpdateURL :one
+UPDATE urls SET original_url = $2, title = $3, interstitial = $4
+WHERE id = $1
+RETURNING id, short_code, original_url, created_at, disabled_at, disabled_reason, disabled_note, interstitial, title, metadata, metadata_error, metadata_fetched_at, preview_title, preview_description, preview_image, owner, domain_id, workspace_id
+`
+
+type UpdateURLParams struct {
+	ID           int64       `json:"id"`
+	OriginalUrl  string      `json:"original_url"`
+	Title        pgtype.Text `json:"title"`
+	Interstitial bool        `json:"interstitial"`
+}
+
+func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error) {
+	row := q.db.QueryRow(ctx, updateURL,
+		arg.ID,
+		arg.OriginalUrl,
+		arg.Title,
+		arg.Interstitial,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.CreatedAt,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.DisabledNote,
+		&i.Interstitial,
+		&i.Title,
+		&i.Metadata,
+		&i.MetadataError,
+		&i.MetadataFetchedAt,
+		&i.PreviewTitle,
+		&i.PreviewDescription,
+		&i.PreviewImage,
+		&i.Owner,
+		&i.DomainID,
+		&i.WorkspaceID,
+	)
+	return i, err
+}
+
+const updateURLMetadata = `-- name: UpdateURLMetadata :exec
+UPDATE urls SET metadata = $2, metadata_error = $3, metadata_fetched_at = now() WHERE id = $1
+`
+
+type UpdateURLMetadataParams struct {
+	ID            int64       `json:"id"`
+	Metadata      []byte      `json:"metadata"`
+	MetadataError pgtype.Text `json:"metadata_error"`
+}
+
+func (q *Queries) UpdateURLMetadata(ctx context.Context, arg UpdateURLMetadataParams) error {
+	_, err := q.db.Exec(ctx, updateURLMetadata, arg.ID, arg.Metadata, arg.MetadataError)
+	return err
+}
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+)
+
+// InTx runs fn with queries bound to a new transaction, which is committed
+// when fn returns nil and rolled back otherwise.
+func InTx(ctx context.Context, db *pgxpool.Pool, fn func(q *sqlc.Queries) error) error {
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return fn(sqlc.New(tx))
+	})
+}
@@ -0,0 +1,110 @@
+package domains
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	testCases := []struct {
+		name     string
+		host     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Test a plain hostname", host: "go.example.com", expected: "go.example.com"},
+		{name: "Test upper case and a port", host: "Go.Example.COM:8080", expected: "go.example.com"},
+		{name: "Test a trailing dot", host: "go.example.com.", expected: "go.example.com"},
+		{name: "Test an internationalized name", host: "bücher.example", expected: "xn--bcher-kva.example"},
+		{name: "Test an empty host", host: " ", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NormalizeHost(tc.host)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidHostname)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	assert.NoError(t, ValidateHostname("go.example.com"))
+	assert.NoError(t, ValidateHostname("xn--bcher-kva.example"))
+
+	for _, hostname := range []string{"localhost", "127.0.0.1", "-bad.example.com", "a..example.com", "under_score.example.com"} {
+		assert.ErrorIs(t, ValidateHostname(hostname), ErrInvalidHostname, hostname)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	addr := startDNSServer(t, map[string][]string{
+		ChallengeRecord("go.example.com") + ".": {"unrelated", ChallengeValue("token")},
+	})
+	v := NewVerifier(addr, 2*time.Second)
+	ctx := context.Background()
+
+	assert.NoError(t, v.Verify(ctx, "go.example.com", "token"))
+	assert.ErrorIs(t, v.Verify(ctx, "go.example.com", "other"), ErrNotVerified)
+	assert.ErrorIs(t, v.Verify(ctx, "missing.example.com", "token"), ErrNotVerified)
+}
+
+// startDNSServer answers TXT queries over UDP from records, keyed by fully
+// qualified name, and returns its address.
+func startDNSServer(t *testing.T, records map[string][]string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) == 0 {
+				continue
+			}
+
+			q := req.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+				Questions: req.Questions,
+			}
+			values, ok := records[q.Name.String()]
+			switch {
+			case !ok:
+				resp.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeTXT:
+				for _, v := range values {
+					resp.Answers = append(resp.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.TXTResource{TXT: []string{v}},
+					})
+				}
+			}
+
+			b, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(b, from)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
@@ -0,0 +1,55 @@
+// Package domains manages the custom short domains links can be created
+// under: checking hostnames, verifying ownership through DNS and resolving
+// the Host of incoming redirects.
+package domains
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidHostname is returned for names that cannot be used as a short domain.
+var ErrInvalidHostname = errors.New("invalid hostname")
+
+// NormalizeHost lower-cases a hostname, converts it to its ASCII form and
+// strips any port and trailing dot, so that the same domain always compares
+// equal. It accepts the value of a Host header.
+func NormalizeHost(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", ErrInvalidHostname
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", ErrInvalidHostname
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// ValidateHostname checks that a normalized name is a registrable-looking
+// DNS name rather than an IP address, a single label or localhost.
+func ValidateHostname(hostname string) error {
+	if net.ParseIP(hostname) != nil || !strings.Contains(hostname, ".") || len(hostname) > 253 {
+		return ErrInvalidHostname
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return ErrInvalidHostname
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return ErrInvalidHostname
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package domains
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+)
+
+// Domain is a verified custom domain.
+type Domain struct {
+	ID       int64
+	Hostname string
+}
+
+// Registry keeps the verified custom domains in memory so the redirector can
+// map a request's Host to a code namespace without a database round trip.
+// Hosts it does not know are served from the default domain.
+type Registry struct {
+	querier sqlc.Querier
+	logger  *slog.Logger
+
+	mu      sync.RWMutex
+	domains map[string]Domain
+}
+
+// NewRegistry returns an empty Registry; call Refresh or Run to load it.
+func NewRegistry(querier sqlc.Querier, logger *slog.Logger) *Registry {
+	return &Registry{querier: querier, logger: logger, domains: map[string]Domain{}}
+}
+
+// Lookup returns the verified domain serving host, which may carry a port.
+func (r *Registry) Lookup(host string) (Domain, bool) {
+	hostname, err := NormalizeHost(host)
+	if err != nil {
+		return Domain{}, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.domains[hostname]
+	return d, ok
+}
+
+// Refresh reloads the verified domains from the database.
+func (r *Registry) Refresh(ctx context.Context) error {
+	rows, err := r.querier.ListVerifiedDomains(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains := make(map[string]Domain, len(rows))
+	for _, row := range rows {
+		domains[row.Hostname] = Domain{ID: row.ID, Hostname: row.Hostname}
+	}
+
+	r.mu.Lock()
+	r.domains = domains
+	r.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the registry every interval until ctx is cancelled. A failed
+// refresh keeps the domains loaded last.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(ctx); err != nil {
+				r.logger.Error("failed to refresh custom domains", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// challengeLabel is prepended to a hostname to form the name of its TXT
+	// challenge record.
+	challengeLabel = "_veritas-challenge."
+	// challengePrefix starts the value of the TXT challenge record.
+	challengePrefix = "veritas-verification="
+)
+
+// ErrNotVerified is returned when a domain's TXT records do not contain the
+// expected challenge.
+var ErrNotVerified = errors.New("verification record not found")
+
+// ChallengeRecord returns the name of the TXT record that proves control of hostname.
+func ChallengeRecord(hostname string) string {
+	return challengeLabel + hostname
+}
+
+// ChallengeValue returns the value the TXT record must hold for token.
+func ChallengeValue(token string) string {
+	return challengePrefix + token
+}
+
+// NewToken returns a random verification token.
+func NewToken() string {
+	return strings.ToLower(rand.Text())
+}
+
+// Verifier checks DNS TXT challenges.
+type Verifier struct {
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+// NewVerifier returns a Verifier that queries the DNS server at resolverAddr
+// (host:port), or the system resolver when resolverAddr is empty.
+func NewVerifier(resolverAddr string, timeout time.Duration) *Verifier {
+	resolver := net.DefaultResolver
+	if resolverAddr != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+	return &Verifier{resolver: resolver, timeout: timeout}
+}
+
+// Verify looks up the challenge record of hostname and checks that one of its
+// values carries token.
+func (v *Verifier) Verify(ctx context.Context, hostname, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	records, err := v.resolver.LookupTXT(ctx, ChallengeRecord(hostname))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return fmt.Errorf("%w: no TXT record at %s", ErrNotVerified, ChallengeRecord(hostname))
+		}
+		return fmt.Errorf("could not look up %s: %w", ChallengeRecord(hostname), err)
+	}
+
+	want := ChallengeValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has %d TXT records, none matches", ErrNotVerified, ChallengeRecord(hostname), len(records))
+}
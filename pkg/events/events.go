@@ -0,0 +1,53 @@
+// Package events names the NATS subjects Veritas publishes on and builds the
+// link lifecycle events published on them. Messages are the protobuf types
+// of proto/events/v1.
+package events
+
+import (
+	"crypto/rand"
+	"strings"
+
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Subjects events are published on.
+const (
+	// SubjectRedirect carries a RedirectEvent per click.
+	SubjectRedirect = "veritas.redirect.success"
+	// SubjectClickRecorded carries the RedirectEvent of each click again
+	// once the analytics service has stored it.
+	SubjectClickRecorded = "veritas.click.recorded"
+	// SubjectLinks matches the subjects of all link lifecycle events.
+	SubjectLinks       = "veritas.link.*"
+	SubjectLinkCreated = "veritas.link.created"
+	SubjectLinkUpdated = "veritas.link.updated"
+	SubjectLinkDeleted = "veritas.link.deleted"
+)
+
+// Reasons of LinkDeleted events.
+const (
+	DeletedByMember    = "deleted"
+	DeletedUnreachable = "unreachable"
+)
+
+// NewID returns a random event ID.
+func NewID() string {
+	return "evt_" + strings.ToLower(rand.Text())
+}
+
+// LinkCreated returns the event of link being created.
+func LinkCreated(link *eventsv1.Link) *eventsv1.LinkCreated {
+	return &eventsv1.LinkCreated{EventId: NewID(), OccurredAt: timestamppb.Now(), Link: link}
+}
+
+// LinkUpdated returns the event of link changing. changed names the fields
+// of link that changed.
+func LinkUpdated(link *eventsv1.Link, changed ...string) *eventsv1.LinkUpdated {
+	return &eventsv1.LinkUpdated{EventId: NewID(), OccurredAt: timestamppb.Now(), Link: link, ChangedFields: changed}
+}
+
+// LinkDeleted returns the event of link being deleted for reason.
+func LinkDeleted(link *eventsv1.Link, reason string) *eventsv1.LinkDeleted {
+	return &eventsv1.LinkDeleted{EventId: NewID(), OccurredAt: timestamppb.Now(), Link: link, Reason: reason}
+}
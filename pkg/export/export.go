@@ -0,0 +1,217 @@
+// Package export writes tables as CSV, newline-delimited JSON or Parquet a
+// row at a time, so that exports of any size are streamed rather than held
+// in memory.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Type is the type of the values of a column.
+type Type int
+
+// Types of columns, and the Go types of their values.
+const (
+	// Int64 values are int64.
+	Int64 Type = iota
+	// String values are string.
+	String
+	// Bool values are bool.
+	Bool
+	// Timestamp values are time.Time, written in UTC to the microsecond.
+	Timestamp
+)
+
+// Column is a named column of a table.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Format is the file format of an export.
+type Format string
+
+// Formats of exports.
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// Formats lists the formats exports can be written in.
+var Formats = []Format{FormatCSV, FormatNDJSON, FormatParquet}
+
+// ParseFormat returns the format called s.
+func ParseFormat(s string) (Format, error) {
+	if f := Format(s); slices.Contains(Formats, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected csv, ndjson or parquet", s)
+}
+
+// ContentType returns the media type of files of format f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Writer writes the rows of a table. Each row holds a value per column, of
+// the type of the column. Close must be called after the last row to
+// complete the file; it does not close the underlying writer.
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+// NewWriter returns a Writer of the table of columns in format f to w.
+func NewWriter(w io.Writer, f Format, columns []Column) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatParquet:
+		return newParquetWriter(w, columns), nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", f)
+	}
+}
+
+// csvWriter writes a header line with the names of the columns, then a line
+// per row.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, c := range columns {
+		cw.record[i] = c.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(row []any) error {
+	if err := check(cw.columns, row); err != nil {
+		return err
+	}
+	for i, v := range row {
+		switch v := v.(type) {
+		case int64:
+			cw.record[i] = strconv.FormatInt(v, 10)
+		case string:
+			cw.record[i] = v
+		case bool:
+			cw.record[i] = strconv.FormatBool(v)
+		case time.Time:
+			cw.record[i] = formatTime(v)
+		}
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// ndjsonWriter writes a JSON object per row, its keys the names of the
+// columns in their order.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []Column
+	keys    [][]byte
+	line    []byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []Column) *ndjsonWriter {
+	nw := &ndjsonWriter{w: bufio.NewWriter(w), columns: columns, keys: make([][]byte, len(columns))}
+	for i, c := range columns {
+		key, _ := json.Marshal(c.Name)
+		nw.keys[i] = append(key, ':')
+	}
+	return nw
+}
+
+func (nw *ndjsonWriter) Write(row []any) error {
+	if err := check(nw.columns, row); err != nil {
+		return err
+	}
+	line := append(nw.line[:0], '{')
+	for i, v := range row {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = append(line, nw.keys[i]...)
+		switch v := v.(type) {
+		case int64:
+			line = strconv.AppendInt(line, v, 10)
+		case string:
+			s, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			line = append(line, s...)
+		case bool:
+			line = strconv.AppendBool(line, v)
+		case time.Time:
+			line = strconv.AppendQuote(line, formatTime(v))
+		}
+	}
+	line = append(line, '}', '\n')
+	nw.line = line
+	_, err := nw.w.Write(line)
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
+
+// check reports whether row holds a value of the right type per column.
+func check(columns []Column, row []any) error {
+	if len(row) != len(columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(columns))
+	}
+	for i, c := range columns {
+		var ok bool
+		switch c.Type {
+		case Int64:
+			_, ok = row[i].(int64)
+		case String:
+			_, ok = row[i].(string)
+		case Bool:
+			_, ok = row[i].(bool)
+		case Timestamp:
+			_, ok = row[i].(time.Time)
+		}
+		if !ok {
+			return fmt.Errorf("value %v of column %s has type %T", row[i], c.Name, row[i])
+		}
+	}
+	return nil
+}
+
+// formatTime formats t in UTC in RFC 3339 to the microsecond, as timestamps
+// are stored.
+func formatTime(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
@@ -0,0 +1,323 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testColumns = []Column{
+		{Name: "id", Type: Int64},
+		{Name: "at", Type: Timestamp},
+		{Name: "referrer", Type: String},
+		{Name: "is_bot", Type: Bool},
+	}
+	testRows = [][]any{
+		{int64(1), time.Date(2026, 10, 19, 13, 45, 0, 123456789, time.UTC), "news.example.com", false},
+		{int64(2), time.Date(2026, 10, 19, 15, 45, 1, 0, time.FixedZone("CEST", 2*60*60)), "a \"quoted\", line\nbreak", true},
+	}
+)
+
+func TestWriter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{
+			name:   "Test CSV",
+			format: FormatCSV,
+			want: "id,at,referrer,is_bot\n" +
+				"1,2026-10-19T13:45:00.123456Z,news.example.com,false\n" +
+				"2,2026-10-19T13:45:01Z,\"a \"\"quoted\"\", line\nbreak\",true\n",
+		},
+		{
+			name:   "Test NDJSON",
+			format: FormatNDJSON,
+			want: `{"id":1,"at":"2026-10-19T13:45:00.123456Z","referrer":"news.example.com","is_bot":false}` + "\n" +
+				`{"id":2,"at":"2026-10-19T13:45:01Z","referrer":"a \"quoted\", line\nbreak","is_bot":true}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, tc.format, testColumns)
+			require.NoError(t, err)
+			for _, row := range testRows {
+				require.NoError(t, w.Write(row))
+			}
+			require.NoError(t, w.Close())
+			assert.Equal(t, tc.want, buf.String())
+		})
+	}
+
+	w, err := NewWriter(&bytes.Buffer{}, FormatCSV, testColumns)
+	require.NoError(t, err)
+	assert.Error(t, w.Write([]any{"1", time.Now(), "", false}), "value of the wrong type")
+	assert.Error(t, w.Write([]any{int64(1)}), "missing values")
+
+	_, err = NewWriter(&bytes.Buffer{}, "xlsx", testColumns)
+	assert.Error(t, err)
+}
+
+func TestParquet(t *testing.T) {
+	testCases := []struct {
+		name       string
+		rows       [][]any
+		groupBytes int
+		groups     int
+	}{
+		{name: "Test a row group", rows: testRows, groupBytes: rowGroupBytes, groups: 1},
+		{name: "Test several row groups", rows: manyRows(50), groupBytes: 256, groups: 8},
+		// An empty export is still a valid file, without row groups.
+		{name: "Test no rows", groupBytes: rowGroupBytes},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, FormatParquet, testColumns)
+			require.NoError(t, err)
+			w.(*parquetWriter).groupBytes = tc.groupBytes
+			for _, row := range tc.rows {
+				require.NoError(t, w.Write(row))
+			}
+			require.NoError(t, w.Close())
+
+			groups, rows := readParquet(t, buf.Bytes(), testColumns)
+			assert.Equal(t, tc.groups, groups)
+			require.Len(t, rows, len(tc.rows))
+			for i, row := range tc.rows {
+				want := slices.Clone(row)
+				want[1] = time.UnixMicro(row[1].(time.Time).UnixMicro()).UTC()
+				assert.Equal(t, want, rows[i], "row %d", i)
+			}
+		})
+	}
+}
+
+func manyRows(n int) [][]any {
+	at := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	rows := make([][]any, n)
+	for i := range rows {
+		rows[i] = []any{int64(i), at.Add(time.Duration(i) * time.Second), strings.Repeat("r", i), i%3 == 0}
+	}
+	return rows
+}
+
+// readParquet decodes a Parquet file as written for columns, checking its
+// metadata, and returns its number of row groups and its rows.
+func readParquet(t *testing.T, file []byte, columns []Column) (int, [][]any) {
+	t.Helper()
+	require.Greater(t, len(file), 12)
+	require.Equal(t, parquetMagic, string(file[:4]))
+	require.Equal(t, parquetMagic, string(file[len(file)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	require.LessOrEqual(t, footerLen, len(file)-12)
+	footer := file[len(file)-8-footerLen : len(file)-8]
+
+	// FileMetaData: version, schema, num_rows, row_groups, created_by.
+	meta, n := readThriftStruct(t, footer)
+	require.Equal(t, len(footer), n, "footer length")
+	assert.Equal(t, int64(parquetFileVersion), meta[1])
+	schema := meta[2].([]any)
+	require.Len(t, schema, len(columns)+1)
+	root := schema[0].(map[int16]any)
+	assert.Equal(t, "schema", root[4])
+	assert.Equal(t, int64(len(columns)), root[5])
+	for i, c := range columns {
+		physical, converted := parquetTypes(c.Type)
+		el := schema[i+1].(map[int16]any)
+		assert.Equal(t, int64(physical), el[1], c.Name)
+		assert.Equal(t, int64(parquetRequired), el[3], c.Name)
+		assert.Equal(t, c.Name, el[4])
+		if converted >= 0 {
+			assert.Equal(t, int64(converted), el[6], c.Name)
+		} else {
+			assert.NotContains(t, el, int16(6), c.Name)
+		}
+	}
+
+	var rows [][]any
+	groups, _ := meta[4].([]any)
+	for _, g := range groups {
+		// RowGroup: columns, total_byte_size, num_rows.
+		group := g.(map[int16]any)
+		numRows := group[3].(int64)
+		chunks := group[1].([]any)
+		require.Len(t, chunks, len(columns))
+		values := make([][]any, len(columns))
+		var size int64
+		for i, c := range columns {
+			// ColumnChunk: file_offset, meta_data.
+			chunk := chunks[i].(map[int16]any)
+			cm := chunk[3].(map[int16]any)
+			physical, _ := parquetTypes(c.Type)
+			assert.Equal(t, int64(physical), cm[1], c.Name)
+			assert.Equal(t, []any{int64(parquetPlain)}, cm[2], c.Name)
+			assert.Equal(t, []any{c.Name}, cm[3])
+			assert.Equal(t, int64(parquetGzip), cm[4], c.Name)
+			assert.Equal(t, numRows, cm[5], c.Name)
+			assert.Equal(t, chunk[2], cm[9], c.Name)
+			size += cm[6].(int64)
+
+			// PageHeader: type, sizes and data_page_header with the number
+			// of values and their encodings.
+			offset := cm[9].(int64)
+			header, n := readThriftStruct(t, file[offset:])
+			assert.Equal(t, int64(parquetDataPage), header[1], c.Name)
+			dph := header[5].(map[int16]any)
+			assert.Equal(t, numRows, dph[1], c.Name)
+			assert.Equal(t, int64(parquetPlain), dph[2], c.Name)
+			compressed := header[3].(int64)
+			assert.Equal(t, int64(n)+compressed, cm[7], c.Name)
+
+			page := file[offset+int64(n) : offset+int64(n)+compressed]
+			gz, err := gzip.NewReader(bytes.NewReader(page))
+			require.NoError(t, err)
+			data, err := io.ReadAll(gz)
+			require.NoError(t, err)
+			assert.Equal(t, header[2], int64(len(data)), c.Name)
+			assert.Equal(t, int64(n+len(data)), cm[6], c.Name)
+			values[i] = decodePlain(t, c.Type, data, int(numRows))
+		}
+		assert.Equal(t, size, group[2])
+		for r := range int(numRows) {
+			row := make([]any, len(columns))
+			for i := range columns {
+				row[i] = values[i][r]
+			}
+			rows = append(rows, row)
+		}
+	}
+	assert.Equal(t, int64(len(rows)), meta[3])
+	return len(groups), rows
+}
+
+// decodePlain decodes n plain encoded values of type typ.
+func decodePlain(t *testing.T, typ Type, data []byte, n int) []any {
+	t.Helper()
+	values := make([]any, n)
+	for i := range values {
+		switch typ {
+		case Bool:
+			require.Less(t, i/8, len(data))
+			values[i] = data[i/8]&(1<<(i%8)) != 0
+			continue
+		case String:
+			require.GreaterOrEqual(t, len(data), 4)
+			l := int(binary.LittleEndian.Uint32(data))
+			require.GreaterOrEqual(t, len(data), 4+l)
+			values[i], data = string(data[4:4+l]), data[4+l:]
+			continue
+		}
+		require.GreaterOrEqual(t, len(data), 8)
+		v := int64(binary.LittleEndian.Uint64(data))
+		data = data[8:]
+		if typ == Timestamp {
+			values[i] = time.UnixMicro(v).UTC()
+		} else {
+			values[i] = v
+		}
+	}
+	if typ != Bool {
+		assert.Empty(t, data, "trailing bytes")
+	}
+	return values
+}
+
+// readThriftStruct decodes a struct in the Thrift compact protocol into its
+// fields by id, and returns the number of bytes it took. Integers are
+// returned as int64, binaries as strings, lists as []any and structs as
+// map[int16]any.
+func readThriftStruct(t *testing.T, b []byte) (map[int16]any, int) {
+	t.Helper()
+	fields := make(map[int16]any)
+	var id int16
+	n := 0
+	for {
+		require.Less(t, n, len(b), "unterminated struct")
+		header := b[n]
+		n++
+		if header == 0 {
+			return fields, n
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, k := binary.Varint(b[n:])
+			require.Positive(t, k)
+			id, n = int16(v), n+k
+		}
+		v, k := readThriftValue(t, header&0x0f, b[n:])
+		fields[id], n = v, n+k
+	}
+}
+
+func readThriftValue(t *testing.T, typ byte, b []byte) (any, int) {
+	t.Helper()
+	switch typ {
+	case 1, 2:
+		return typ == 1, 0
+	case thriftI32, thriftI64:
+		v, k := binary.Varint(b)
+		require.Positive(t, k)
+		return v, k
+	case thriftBinary:
+		l, k := binary.Uvarint(b)
+		require.Positive(t, k)
+		require.LessOrEqual(t, k+int(l), len(b))
+		return string(b[k : k+int(l)]), k + int(l)
+	case thriftList:
+		require.NotEmpty(t, b)
+		size, elem, n := int(b[0]>>4), b[0]&0x0f, 1
+		if size == 15 {
+			l, k := binary.Uvarint(b[1:])
+			require.Positive(t, k)
+			size, n = int(l), n+k
+		}
+		list := make([]any, size)
+		for i := range list {
+			v, k := readThriftValue(t, elem, b[n:])
+			list[i], n = v, n+k
+		}
+		return list, n
+	case thriftStruct:
+		return readThriftStruct(t, b)
+	}
+	t.Fatalf("unexpected thrift type %d", typ)
+	return nil, 0
+}
+
+func TestThrift(t *testing.T) {
+	var tw thriftWriter
+	tw.begin()
+	tw.i32(1, 1)
+	tw.i64(3, -2)
+	tw.list(4, thriftBinary, 1)
+	tw.elementString("id")
+	tw.structField(20)
+	tw.i32(1, 300)
+	tw.end()
+	tw.end()
+
+	assert.Equal(t, []byte{
+		0x15, 0x02, // field 1, i32 1
+		0x26, 0x03, // field 3, i64 -2
+		0x19, 0x18, 0x02, 'i', 'd', // field 4, list of a binary
+		0x0c, 0x28, // field 20, struct, with its id as a varint
+		0x15, 0xd8, 0x04, // field 1, i32 300
+		0x00, // end of field 20
+		0x00, // end
+	}, tw.buf.Bytes())
+}
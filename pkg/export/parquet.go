@@ -0,0 +1,373 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// rowGroupBytes bounds the size of the values buffered before they are
+// written as a row group, and so the memory held by a Parquet export.
+const rowGroupBytes = 8 << 20
+
+// parquetMagic starts and ends Parquet files.
+const parquetMagic = "PAR1"
+
+// Parquet physical and converted types, and the other enums of the format,
+// as numbered in parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetRequired    = 0
+	parquetPlain       = 0
+	parquetRLE         = 3
+	parquetGzip        = 2
+	parquetDataPage    = 0
+	parquetFileVersion = 1
+)
+
+// parquetWriter writes a Parquet file of required columns. The values are
+// buffered until groupBytes, rowGroupBytes by default, then written as a row
+// group holding a single gzipped, plain encoded data page per column. The
+// metadata of the row groups is written in the footer by Close.
+type parquetWriter struct {
+	w          *countingWriter
+	columns    []Column
+	groupBytes int
+	values     []bytes.Buffer
+	bools      [][]bool
+	rows       int64
+	total      int64
+	groups     []parquetRowGroup
+	page       bytes.Buffer
+	gz         *gzip.Writer
+	scratch    []byte
+}
+
+type parquetRowGroup struct {
+	rows    int64
+	size    int64
+	columns []parquetColumnChunk
+}
+
+type parquetColumnChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column) *parquetWriter {
+	pw := &parquetWriter{
+		w:          &countingWriter{w: w},
+		columns:    columns,
+		groupBytes: rowGroupBytes,
+		values:     make([]bytes.Buffer, len(columns)),
+		bools:      make([][]bool, len(columns)),
+	}
+	pw.gz = gzip.NewWriter(&pw.page)
+	return pw
+}
+
+// start writes the magic that starts the file, before its first row group
+// or footer.
+func (pw *parquetWriter) start() error {
+	if pw.w.n > 0 {
+		return nil
+	}
+	_, err := io.WriteString(pw.w, parquetMagic)
+	return err
+}
+
+func (pw *parquetWriter) Write(row []any) error {
+	if err := check(pw.columns, row); err != nil {
+		return err
+	}
+	size := 0
+	for i, v := range row {
+		buf := &pw.values[i]
+		switch v := v.(type) {
+		case int64:
+			pw.scratch = binary.LittleEndian.AppendUint64(pw.scratch[:0], uint64(v))
+			buf.Write(pw.scratch)
+		case time.Time:
+			pw.scratch = binary.LittleEndian.AppendUint64(pw.scratch[:0], uint64(v.UnixMicro()))
+			buf.Write(pw.scratch)
+		case string:
+			pw.scratch = binary.LittleEndian.AppendUint32(pw.scratch[:0], uint32(len(v)))
+			buf.Write(pw.scratch)
+			buf.WriteString(v)
+		case bool:
+			pw.bools[i] = append(pw.bools[i], v)
+		}
+		size += buf.Len() + len(pw.bools[i])/8
+	}
+	pw.rows++
+	if size >= pw.groupBytes {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *parquetWriter) flush() error {
+	if err := pw.start(); err != nil {
+		return err
+	}
+	group := parquetRowGroup{rows: pw.rows, columns: make([]parquetColumnChunk, len(pw.columns))}
+	for i := range pw.columns {
+		data := pw.values[i].Bytes()
+		if pw.columns[i].Type == Bool {
+			data = packBits(pw.bools[i])
+		}
+
+		pw.page.Reset()
+		pw.gz.Reset(&pw.page)
+		if _, err := pw.gz.Write(data); err != nil {
+			return err
+		}
+		if err := pw.gz.Close(); err != nil {
+			return err
+		}
+		header := pageHeader(len(data), pw.page.Len(), pw.rows)
+
+		chunk := parquetColumnChunk{
+			offset:       pw.w.n,
+			uncompressed: int64(len(header) + len(data)),
+			compressed:   int64(len(header) + pw.page.Len()),
+		}
+		if _, err := pw.w.Write(header); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(pw.page.Bytes()); err != nil {
+			return err
+		}
+		group.columns[i] = chunk
+		group.size += chunk.uncompressed
+
+		pw.values[i].Reset()
+		pw.bools[i] = pw.bools[i][:0]
+	}
+	pw.groups = append(pw.groups, group)
+	pw.total += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// Close writes the buffered rows and the footer.
+func (pw *parquetWriter) Close() error {
+	if pw.rows > 0 {
+		if err := pw.flush(); err != nil {
+			return err
+		}
+	}
+	if err := pw.start(); err != nil {
+		return err
+	}
+	footer := pw.fileMetaData()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+	_, err := pw.w.Write(footer)
+	return err
+}
+
+// fileMetaData encodes the FileMetaData of the file: its schema and the
+// location of the column chunks of its row groups.
+func (pw *parquetWriter) fileMetaData() []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, parquetFileVersion)
+	t.list(2, thriftStruct, len(pw.columns)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.end()
+	for _, c := range pw.columns {
+		physical, converted := parquetTypes(c.Type)
+		t.begin()
+		t.i32(1, physical)
+		t.i32(3, parquetRequired)
+		t.binary(4, c.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.end()
+	}
+	t.i64(3, pw.total)
+	t.list(4, thriftStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		t.begin()
+		t.list(1, thriftStruct, len(g.columns))
+		for i, chunk := range g.columns {
+			physical, _ := parquetTypes(pw.columns[i].Type)
+			t.begin()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, physical)
+			t.list(2, thriftI32, 1)
+			t.element(parquetPlain)
+			t.list(3, thriftBinary, 1)
+			t.elementString(pw.columns[i].Name)
+			t.i32(4, parquetGzip)
+			t.i64(5, g.rows)
+			t.i64(6, chunk.uncompressed)
+			t.i64(7, chunk.compressed)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.binary(6, "veritas")
+	t.end()
+	return t.buf.Bytes()
+}
+
+// pageHeader encodes the PageHeader of a data page of rows values.
+func pageHeader(uncompressed, compressed int, rows int64) []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, parquetDataPage)
+	t.i32(2, int32(uncompressed))
+	t.i32(3, int32(compressed))
+	t.structField(5)
+	t.i32(1, int32(rows))
+	t.i32(2, parquetPlain)
+	t.i32(3, parquetRLE)
+	t.i32(4, parquetRLE)
+	t.end()
+	t.end()
+	return t.buf.Bytes()
+}
+
+// parquetTypes returns the physical type of the values of columns of type
+// typ, and their converted type or -1.
+func parquetTypes(typ Type) (int32, int32) {
+	switch typ {
+	case String:
+		return parquetByteArray, parquetUTF8
+	case Bool:
+		return parquetBoolean, -1
+	case Timestamp:
+		return parquetInt64, parquetTimestampMicros
+	default:
+		return parquetInt64, -1
+	}
+}
+
+// packBits packs bools a bit each, the first in the least significant bit.
+func packBits(bools []bool) []byte {
+	packed := make([]byte, (len(bools)+7)/8)
+	for i, b := range bools {
+		if b {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// countingWriter counts the bytes written, which locate the pages of a
+// Parquet file in its footer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the structs of Parquet metadata with the Thrift
+// compact protocol. Fields must be written in increasing order of id, and
+// structs between begin and end.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.elementString(s)
+}
+
+// list starts a list field of n elements of type elem, which follow.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elem)
+	t.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+// structField starts a struct field, ended by end.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// element writes an integer element of a list.
+func (t *thriftWriter) element(v int64) {
+	t.varint(v)
+}
+
+// elementString writes a binary element of a list.
+func (t *thriftWriter) elementString(s string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	t.buf.WriteString(s)
+}
+
+// varint writes v zigzag encoded.
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendVarint(nil, v))
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultTimeout = time.Second
+
+// FromApp returns a check for every dependency configured in app.
+func FromApp(app *config.AppConfig) []Check {
+	var checks []Check
+	if app.DB != nil {
+		checks = append(checks, Postgres(app.DB))
+	}
+	if app.Cache != nil {
+		checks = append(checks, Redis(app.Cache))
+	}
+	if app.NATS != nil {
+		checks = append(checks, NATS(app.NATS))
+	}
+	return checks
+}
+
+// Postgres pings the database through the pool.
+func Postgres(pool *pgxpool.Pool) Check {
+	return Check{
+		Name:    "postgres",
+		Timeout: defaultTimeout,
+		Probe:   pool.Ping,
+	}
+}
+
+// Redis pings the cache.
+func Redis(client *redis.Client) Check {
+	return Check{
+		Name:    "redis",
+		Timeout: defaultTimeout,
+		Probe: func(ctx context.Context) error {
+			return client.Ping(ctx).Err()
+		},
+	}
+}
+
+// NATS checks the connection state and measures a round trip to the server.
+func NATS(nc *nats.Conn) Check {
+	return Check{
+		Name:    "nats",
+		Timeout: defaultTimeout,
+		Probe: func(ctx context.Context) error {
+			if status := nc.Status(); status != nats.CONNECTED {
+				return fmt.Errorf("connection is %s", status)
+			}
+			return nc.FlushWithContext(ctx)
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status values reported for the service and its dependencies.
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+)
+
+// Check probes a single dependency.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Probe   func(ctx context.Context) error
+}
+
+// DependencyStatus is the outcome of the most recent probe of a dependency.
+type DependencyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	LatencyMs   float64    `json:"latency_ms"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Report aggregates the status of all dependencies.
+type Report struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Healthy reports whether every dependency is up.
+func (r Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Checker runs dependency checks concurrently and caches the report for a
+// short TTL, so that frequent probes from several kubelets do not turn into
+// a steady stream of pings against the backends.
+type Checker struct {
+	checks []Check
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	report    Report
+	checkedAt time.Time
+	lastErr   map[string]DependencyStatus
+	// refreshing is closed once the checks in flight have finished.
+	refreshing chan struct{}
+}
+
+// NewChecker returns a Checker for checks whose results are reused for ttl.
+func NewChecker(ttl time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		ttl:     ttl,
+		lastErr: make(map[string]DependencyStatus),
+	}
+}
+
+// Report returns the cached report, re-running the checks if it has expired.
+// The checks run detached from ctx, each within its own timeout, and are
+// shared by concurrent callers. A caller whose ctx ends first gets a report
+// of that, which is not cached.
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.ttl {
+		report := c.report
+		c.mu.Unlock()
+		return report
+	}
+	done := c.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		c.refreshing = done
+		go c.refresh(context.WithoutCancel(ctx), done)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return c.abandoned(ctx.Err())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report
+}
+
+// refresh runs the checks and caches their report, then closes done.
+func (c *Checker) refresh(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	statuses := make([]DependencyStatus, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := Report{Status: StatusUp, Dependencies: statuses}
+	for i := range statuses {
+		s := &statuses[i]
+		if s.Status == StatusDown {
+			report.Status = StatusDown
+			c.lastErr[s.Name] = *s
+			continue
+		}
+		// Keep reporting the last failure of a recovered dependency.
+		if prev, ok := c.lastErr[s.Name]; ok {
+			s.LastError = prev.LastError
+			s.LastErrorAt = prev.LastErrorAt
+		}
+	}
+
+	c.report = report
+	c.checkedAt = time.Now()
+	c.refreshing = nil
+}
+
+// abandoned reports every dependency down with err, for a caller that
+// stopped waiting for the checks.
+func (c *Checker) abandoned(err error) Report {
+	now := time.Now()
+	report := Report{Status: StatusDown, Dependencies: make([]DependencyStatus, len(c.checks))}
+	for i, check := range c.checks {
+		report.Dependencies[i] = DependencyStatus{
+			Name:        check.Name,
+			Status:      StatusDown,
+			CheckedAt:   now,
+			LastError:   err.Error(),
+			LastErrorAt: &now,
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) DependencyStatus {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	status := DependencyStatus{
+		Name:      check.Name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.LastError = err.Error()
+		status.LastErrorAt = &start
+	}
+	return status
+}
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerReport(t *testing.T) {
+	calls := 0
+	var failing error
+	checker := NewChecker(time.Hour, Check{
+		Name: "postgres",
+		Probe: func(ctx context.Context) error {
+			calls++
+			return failing
+		},
+	})
+
+	report := checker.Report(context.Background())
+	assert.True(t, report.Healthy())
+	assert.Equal(t, StatusUp, report.Dependencies[0].Status)
+
+	// Within the TTL the cached report is returned without probing again.
+	failing = errors.New("connection refused")
+	report = checker.Report(context.Background())
+	assert.True(t, report.Healthy())
+	assert.Equal(t, 1, calls)
+
+	checker.checkedAt = time.Time{}
+	report = checker.Report(context.Background())
+	assert.False(t, report.Healthy())
+	assert.Equal(t, "connection refused", report.Dependencies[0].LastError)
+
+	// A recovered dependency is up but still reports its last failure.
+	failing = nil
+	checker.checkedAt = time.Time{}
+	report = checker.Report(context.Background())
+	assert.True(t, report.Healthy())
+	assert.Equal(t, "connection refused", report.Dependencies[0].LastError)
+	assert.NotNil(t, report.Dependencies[0].LastErrorAt)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker(0, Check{
+		Name:    "redis",
+		Timeout: 10 * time.Millisecond,
+		Probe: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	report := checker.Report(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Dependencies[0].LastError)
+}
+
+func TestCheckerCallerContext(t *testing.T) {
+	release := make(chan struct{})
+	checker := NewChecker(time.Hour, Check{
+		Name:    "postgres",
+		Timeout: time.Minute,
+		Probe: func(ctx context.Context) error {
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	// A caller giving up is told so, without cancelling the checks.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := checker.Report(ctx)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, context.Canceled.Error(), report.Dependencies[0].LastError)
+
+	// Its failure is not cached: the next caller gets the checks' result.
+	close(release)
+	report = checker.Report(context.Background())
+	assert.True(t, report.Healthy())
+	assert.Empty(t, report.Dependencies[0].LastError)
+}
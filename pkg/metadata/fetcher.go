@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrForbiddenAddress is returned when a destination resolves to an address
+// the fetcher must not connect to, such as a private or loopback address.
+var ErrForbiddenAddress = errors.New("destination address is not publicly routable")
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// treat as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// FetcherConfig configures a Fetcher.
+type FetcherConfig struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	UserAgent    string
+	// AllowPrivateNetworks disables the address checks. Only tests should set it.
+	AllowPrivateNetworks bool
+}
+
+// Fetcher downloads destination pages on behalf of users, so it treats every
+// URL as hostile: it only speaks http(s), refuses to connect to internal
+// addresses (checked on the resolved IP at dial time, so DNS tricks do not
+// help), follows a bounded number of redirects and reads at most MaxBytes.
+type Fetcher struct {
+	client *http.Client
+	cfg    FetcherConfig
+}
+
+// NewFetcher returns a Fetcher with the given limits.
+func NewFetcher(cfg FetcherConfig) *Fetcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = DialControl
+	}
+
+	transport := &http.Transport{
+		// Never go through an environment proxy: it would connect on our behalf
+		// and bypass the address checks.
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    cfg.Timeout,
+		ResponseHeaderTimeout:  cfg.Timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= cfg.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", cfg.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+
+	return &Fetcher{client: client, cfg: cfg}
+}
+
+// DialControl is a net.Dialer Control function that refuses connections to
+// addresses that are not publicly routable. Clients requesting user supplied
+// URLs install it so the check applies to the resolved IP.
+func DialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// Fetch downloads rawURL and extracts its metadata. Destinations that are
+// not HTML are not an error: only their content type and the site's default
+// favicon are returned.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return Metadata{}, fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return Metadata{}, fmt.Errorf("destination answered %s", resp.Status)
+	}
+
+	// Resolve relative URLs against where the redirects ended up.
+	base := resp.Request.URL
+	body := bufio.NewReader(io.LimitReader(resp.Body, f.cfg.MaxBytes))
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		peek, _ := body.Peek(512)
+		contentType = http.DetectContentType(peek)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		m := Metadata{ContentType: mediaType}.finish(base, "", "")
+		return m, nil
+	}
+
+	// charset.NewReader honours the Content-Type header, a BOM or a <meta
+	// charset> in the first 1024 bytes, and falls back to windows-1252.
+	r, err := charset.NewReader(body, contentType)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("could not decode body: %w", err)
+	}
+
+	m, err := Parse(r, base)
+	if err != nil {
+		return Metadata{}, err
+	}
+	m.ContentType = mediaType
+	return m, nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,215 @@
+// Package metadata fetches a link's destination and extracts what is needed
+// to show it nicely: the title, description, Open Graph and Twitter card tags
+// and the favicon.
+package metadata
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTextLength = 1000
+	maxURLLength  = 2048
+)
+
+// Metadata describes a destination page. Every field is optional.
+type Metadata struct {
+	// ContentType is the media type the destination was served with.
+	ContentType string `json:"content_type,omitempty"`
+
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	FaviconURL  string `json:"favicon_url,omitempty"`
+
+	OGTitle       string `json:"og_title,omitempty"`
+	OGDescription string `json:"og_description,omitempty"`
+	OGImage       string `json:"og_image,omitempty"`
+	OGSiteName    string `json:"og_site_name,omitempty"`
+	OGType        string `json:"og_type,omitempty"`
+	OGURL         string `json:"og_url,omitempty"`
+
+	TwitterCard        string `json:"twitter_card,omitempty"`
+	TwitterTitle       string `json:"twitter_title,omitempty"`
+	TwitterDescription string `json:"twitter_description,omitempty"`
+	TwitterImage       string `json:"twitter_image,omitempty"`
+}
+
+// BestTitle returns the most descriptive title available.
+func (m Metadata) BestTitle() string {
+	return firstNonEmpty(m.OGTitle, m.TwitterTitle, m.Title)
+}
+
+// BestDescription returns the most descriptive description available.
+func (m Metadata) BestDescription() string {
+	return firstNonEmpty(m.OGDescription, m.TwitterDescription, m.Description)
+}
+
+// BestImage returns the preview image, if any.
+func (m Metadata) BestImage() string {
+	return firstNonEmpty(m.OGImage, m.TwitterImage)
+}
+
+// Parse extracts metadata from the <head> of an HTML document, which must
+// already be decoded to UTF-8. Relative URLs are resolved against base, or the
+// document's <base href> when it has one.
+func Parse(r io.Reader, base *url.URL) (Metadata, error) {
+	var m Metadata
+	var icon, touchIcon string
+	sawBase := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return m, err
+			}
+			return m.finish(base, icon, touchIcon), nil
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return m.finish(base, icon, touchIcon), nil
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+
+			switch string(name) {
+			case "body":
+				// Everything we look for lives in <head>.
+				return m.finish(base, icon, touchIcon), nil
+			case "title":
+				if m.Title == "" && tt == html.StartTagToken && z.Next() == html.TextToken {
+					m.Title = string(z.Text())
+				}
+			case "base":
+				if href := attrs["href"]; href != "" && !sawBase {
+					sawBase = true
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch rel {
+					case "icon":
+						if icon == "" {
+							icon = attrs["href"]
+						}
+					case "apple-touch-icon":
+						if touchIcon == "" {
+							touchIcon = attrs["href"]
+						}
+					}
+				}
+			case "meta":
+				m.setMeta(strings.ToLower(firstNonEmpty(attrs["property"], attrs["name"])), attrs["content"])
+			}
+		}
+	}
+}
+
+func (m *Metadata) setMeta(key, content string) {
+	var field *string
+	switch key {
+	case "description":
+		field = &m.Description
+	case "og:title":
+		field = &m.OGTitle
+	case "og:description":
+		field = &m.OGDescription
+	case "og:image", "og:image:url", "og:image:secure_url":
+		field = &m.OGImage
+	case "og:site_name":
+		field = &m.OGSiteName
+	case "og:type":
+		field = &m.OGType
+	case "og:url":
+		field = &m.OGURL
+	case "twitter:card":
+		field = &m.TwitterCard
+	case "twitter:title":
+		field = &m.TwitterTitle
+	case "twitter:description":
+		field = &m.TwitterDescription
+	case "twitter:image", "twitter:image:src":
+		field = &m.TwitterImage
+	default:
+		return
+	}
+	// The first occurrence wins, as it does for crawlers.
+	if *field == "" {
+		*field = content
+	}
+}
+
+// finish cleans up text fields and resolves URLs. A site without a declared
+// icon gets the conventional /favicon.ico.
+func (m Metadata) finish(base *url.URL, icon, touchIcon string) Metadata {
+	for _, s := range []*string{
+		&m.Title, &m.Description, &m.OGTitle, &m.OGDescription, &m.OGSiteName, &m.OGType,
+		&m.TwitterCard, &m.TwitterTitle, &m.TwitterDescription,
+	} {
+		*s = cleanText(*s)
+	}
+
+	m.OGImage = resolve(base, m.OGImage)
+	m.OGURL = resolve(base, m.OGURL)
+	m.TwitterImage = resolve(base, m.TwitterImage)
+	m.FaviconURL = resolve(base, firstNonEmpty(icon, touchIcon, "/favicon.ico"))
+	return m
+}
+
+// cleanText collapses whitespace and truncates s to maxTextLength bytes
+// without splitting a UTF-8 sequence.
+func cleanText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= maxTextLength {
+		return s
+	}
+	s = s[:maxTextLength]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// resolve makes ref absolute and drops anything that is not an http(s) URL,
+// such as data: or javascript: URLs.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	s := u.String()
+	if len(s) > maxURLLength {
+		return ""
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const page = `<!doctype html>
+<html><head>
+<meta charset="iso-8859-1">
+<title>  Caf` + "\xe9" + ` &amp; Bar
+</title>
+<meta name="description" content="Best coffee in town">
+<meta property="og:title" content="Caf&eacute; &amp; Bar">
+<meta property="og:image" content="/img/cover.png">
+<meta property="og:image" content="/img/second.png">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="javascript:alert(1)">
+<link rel="apple-touch-icon" href="/touch.png">
+<link rel="shortcut icon" href="static/favicon.png">
+</head>
+<body><meta property="og:description" content="ignored, not in head"></body></html>`
+
+func testFetcher() *Fetcher {
+	return NewFetcher(FetcherConfig{
+		Timeout:              5 * time.Second,
+		MaxBytes:             1 << 20,
+		MaxRedirects:         3,
+		UserAgent:            "test",
+		AllowPrivateNetworks: true,
+	})
+}
+
+func TestFetchHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/dir/page", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	m, err := testFetcher().Fetch(context.Background(), srv.URL+"/start")
+	require.NoError(t, err)
+
+	assert.Equal(t, "text/html", m.ContentType)
+	assert.Equal(t, "Café & Bar", m.Title)
+	assert.Equal(t, "Best coffee in town", m.Description)
+	assert.Equal(t, "Café & Bar", m.OGTitle)
+	assert.Equal(t, srv.URL+"/img/cover.png", m.OGImage)
+	assert.Equal(t, "summary_large_image", m.TwitterCard)
+	assert.Empty(t, m.TwitterImage, "non-http URLs are dropped")
+	assert.Empty(t, m.OGDescription)
+	assert.Equal(t, srv.URL+"/dir/static/favicon.png", m.FaviconURL)
+}
+
+func TestFetchNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	}))
+	defer srv.Close()
+
+	m, err := testFetcher().Fetch(context.Background(), srv.URL+"/report.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", m.ContentType)
+	assert.Empty(t, m.Title)
+	assert.Equal(t, srv.URL+"/favicon.ico", m.FaviconURL)
+}
+
+func TestFetchStopsAtSizeLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + "<title>Too late</title></head></html>"))
+	}))
+	defer srv.Close()
+
+	f := testFetcher()
+	f.cfg.MaxBytes = 1024
+	m, err := f.Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Empty(t, m.Title)
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>internal</title>"))
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(FetcherConfig{Timeout: time.Second, MaxBytes: 1024, MaxRedirects: 3})
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), "got %v", err)
+
+	_, err = f.Fetch(context.Background(), "file:///etc/passwd")
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// job asks for the metadata of one link.
+type job struct {
+	id       int64
+	cacheKey string
+	url      string
+}
+
+// Worker fetches metadata for newly created links in the background, with a
+// bounded queue and a fixed number of concurrent fetches.
+type Worker struct {
+	fetcher     *Fetcher
+	querier     sqlc.Querier
+	cache       *redis.Client
+	logger      *slog.Logger
+	jobs        chan job
+	concurrency int
+}
+
+// NewWorker returns a Worker that queues up to queueSize links and fetches
+// concurrency of them at a time. cache is optional; when set, a link is
+// evicted from it once its metadata is stored so crawlers see the new preview.
+func NewWorker(fetcher *Fetcher, querier sqlc.Querier, cache *redis.Client, logger *slog.Logger, queueSize, concurrency int) *Worker {
+	return &Worker{
+		fetcher:     fetcher,
+		querier:     querier,
+		cache:       cache,
+		logger:      logger,
+		jobs:        make(chan job, queueSize),
+		concurrency: concurrency,
+	}
+}
+
+// Enqueue schedules a metadata fetch for a link. cacheKey is the link's
+// redirect cache key, see cache.LinkKey. It never blocks; when the queue is
+// full the link is skipped and false is returned.
+func (w *Worker) Enqueue(id int64, cacheKey, url string) bool {
+	select {
+	case w.jobs <- job{id: id, cacheKey: cacheKey, url: url}:
+		return true
+	default:
+		metrics.MetadataFetches.WithLabelValues("dropped").Inc()
+		return false
+	}
+}
+
+// Run processes queued links until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-w.jobs:
+					w.process(ctx, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) process(ctx context.Context, j job) {
+	params := sqlc.UpdateURLMetadataParams{ID: j.id}
+
+	m, err := w.fetcher.Fetch(ctx, j.url)
+	if err != nil {
+		metrics.MetadataFetches.WithLabelValues("error").Inc()
+		w.logger.Info("could not fetch destination metadata", "id", j.id, "url", j.url, "err", err)
+		params.MetadataError = pgtype.Text{String: err.Error(), Valid: true}
+	} else {
+		metrics.MetadataFetches.WithLabelValues("ok").Inc()
+	}
+
+	params.Metadata, err = json.Marshal(m)
+	if err != nil {
+		w.logger.Error("could not encode metadata", "id", j.id, "err", err)
+		return
+	}
+	if err := w.querier.UpdateURLMetadata(ctx, params); err != nil {
+		w.logger.Error("could not store metadata", "id", j.id, "err", err)
+		return
+	}
+
+	if w.cache != nil {
+		if err := w.cache.Del(ctx, j.cacheKey).Err(); err != nil {
+			w.logger.Warn("could not evict link from cache", "key", j.cacheKey, "err", err)
+		}
+	}
+}
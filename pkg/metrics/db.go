@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	name  string
+	start time.Time
+}
+
+// QueryTracer is a pgx tracer that records query latencies labelled with the
+// sqlc query name.
+type QueryTracer struct{}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{
+		name:  QueryName(data.SQL),
+		start: time.Now(),
+	})
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	qs, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+	DBQueryDuration.WithLabelValues(qs.name, status).Observe(time.Since(qs.start).Seconds())
+}
+
+// QueryName extracts the query name from the "-- name: X :kind" comment sqlc
+// prefixes every generated query with. Other statements are reported as "other".
+func QueryName(sql string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(sql, prefix) {
+		return "other"
+	}
+
+	fields := strings.Fields(sql[len(prefix):])
+	if len(fields) == 0 {
+		return "other"
+	}
+	return fields[0]
+}
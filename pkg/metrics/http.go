@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware records request counts and latencies for the wrapped handler.
+// Requests are labelled with the ServeMux pattern that matched them, so
+// "/{short_code}" is reported once instead of once per code. They are
+// recorded even if the handler panics, as when it aborts a response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			// ServeMux sets the pattern on the request while routing it.
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(rec.status)
+
+			HTTPRequests.WithLabelValues(route, r.Method, status).Inc()
+			HTTPDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /links/{code}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("code") == "missing" {
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("GET /aborted", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		panic(http.ErrAbortHandler)
+	})
+	h := Middleware(mux)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		status string
+	}{
+		{name: "Test a request labelled by its route", method: http.MethodGet, path: "/links/abc123", route: "GET /links/{code}", status: "200"},
+		{name: "Test the status written by the handler", method: http.MethodGet, path: "/links/missing", route: "GET /links/{code}", status: "404"},
+		{name: "Test an unmatched request", method: http.MethodGet, path: "/nowhere/abc123", route: "unmatched", status: "404"},
+		{name: "Test a method not allowed", method: http.MethodPost, path: "/links/abc123", route: "unmatched", status: "405"},
+		{name: "Test a handler that panics", method: http.MethodGet, path: "/aborted", route: "GET /aborted", status: "503"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := HTTPRequests.WithLabelValues(tc.route, tc.method, tc.status)
+			before := testutil.ToFloat64(requests)
+
+			func() {
+				defer func() { recover() }()
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+			}()
+
+			assert.Equal(t, before+1, testutil.ToFloat64(requests))
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "veritas"
+
+var (
+	// HTTPRequests counts handled HTTP requests by route pattern, method and status code.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests handled.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes HTTP request latencies by route pattern, method and status code.
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latencies in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// CacheLookups counts short code cache lookups by result (hit, miss or error).
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Total number of short code cache lookups by result.",
+	}, []string{"result"})
+
+	// DBQueryDuration observes database query latencies by sqlc query name.
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latencies in seconds by sqlc query name.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query", "status"})
+
+	// NATSPublishFailures counts events that could not be published to NATS.
+	NATSPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "nats",
+		Name:      "publish_failures_total",
+		Help:      "Total number of failed NATS publishes by subject.",
+	}, []string{"subject"})
+
+	// EventsSpooled counts events written to disk because NATS was unavailable.
+	EventsSpooled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "spooled_total",
+		Help:      "Total number of events spooled to disk by subject.",
+	}, []string{"subject"})
+
+	// EventsDropped counts events lost because the spool was full or failed.
+	EventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "dropped_total",
+		Help:      "Total number of events that could not be spooled by subject.",
+	}, []string{"subject"})
+
+	// EventsSpoolBytes reports the size of the event spool on disk.
+	EventsSpoolBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "spool_bytes",
+		Help:      "Size of the on-disk event spool in bytes.",
+	})
+
+	// ConsumerPending reports messages received by a subscription but not yet processed.
+	ConsumerPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "consumer_pending_messages",
+		Help:      "Number of messages buffered by the analytics consumer and not yet processed.",
+	}, []string{"subject"})
+
+	// ConsumerErrors counts events the analytics consumer failed to process.
+	ConsumerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "processing_errors_total",
+		Help:      "Total number of analytics events that failed processing by stage.",
+	}, []string{"subject", "stage"})
+
+	// ConsumerDuplicates counts click events the analytics consumer had
+	// already recorded, delivered again.
+	ConsumerDuplicates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "duplicate_events_total",
+		Help:      "Total number of analytics events already recorded and ignored.",
+	}, []string{"subject"})
+
+	// ConsumerProcessed counts events the analytics consumer handled successfully.
+	ConsumerProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "events_processed_total",
+		Help:      "Total number of analytics events processed.",
+	}, []string{"subject"})
+
+	// ClickRollupRuns counts runs of the click rollups by result: ok, skipped
+	// while another replica held the lock, or error.
+	ClickRollupRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "rollup_runs_total",
+		Help:      "Total number of click rollup runs by result.",
+	}, []string{"result"})
+
+	// ClickRollupRows counts the rollup rows written by granularity.
+	ClickRollupRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "rollup_rows_total",
+		Help:      "Total number of click rollup rows written by granularity.",
+	}, []string{"granularity"})
+
+	// LiveSubscriptions is the number of clients streaming live clicks.
+	LiveSubscriptions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "live_subscriptions",
+		Help:      "Number of clients streaming live clicks.",
+	})
+
+	// LiveDisconnects counts live streams ended by the service, because the
+	// client fell behind or the service shut down.
+	LiveDisconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "analytics",
+		Name:      "live_disconnects_total",
+		Help:      "Total number of live click streams ended by the service by reason.",
+	}, []string{"reason"})
+
+	// RateLimitDecisions counts rate limiter decisions by route, client tier and
+	// result (allowed, limited or error).
+	RateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "decisions_total",
+		Help:      "Total number of rate limit decisions by route, tier and result.",
+	}, []string{"route", "tier", "result"})
+
+	// BlockedURLs counts destinations rejected or disabled by the reputation
+	// checker, by stage (create or rescan) and the list that matched.
+	BlockedURLs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reputation",
+		Name:      "blocked_total",
+		Help:      "Total number of destinations blocked by the reputation checker.",
+	}, []string{"stage", "list"})
+
+	// MetadataFetches counts destination metadata fetches by result (ok,
+	// error, or dropped when the queue was full).
+	MetadataFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "metadata",
+		Name:      "fetches_total",
+		Help:      "Total number of destination metadata fetches by result.",
+	}, []string{"result"})
+
+	// QRCodes counts QR codes served by format and whether they came from
+	// the cache.
+	QRCodes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "qr",
+		Name:      "codes_total",
+		Help:      "Total number of QR codes served by format and cache result.",
+	}, []string{"format", "cache"})
+
+	// WebhookDeliveries counts webhook delivery attempts by result
+	// (succeeded, retried or dead).
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Total number of webhook delivery attempts by result.",
+	}, []string{"result"})
+
+	// WebhookEvents counts events matched to webhook endpoints by type and
+	// whether they were queued, dropped by sampling or already queued.
+	WebhookEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "events_total",
+		Help:      "Total number of events matched to webhook endpoints by type and result.",
+	}, []string{"type", "result"})
+
+	// OutboxEventsPublished counts events the outbox relay published.
+	OutboxEventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "events_published_total",
+		Help:      "Total number of outbox events published to NATS by subject.",
+	}, []string{"subject"})
+
+	// LinksCreated counts short links created.
+	LinksCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "links",
+		Name:      "created_total",
+		Help:      "Total number of short links created.",
+	})
+)
+
+// Handler returns the HTTP handler serving metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
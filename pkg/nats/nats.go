@@ -1,8 +1,10 @@
 package nats
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -30,3 +32,44 @@ func ConnectNATS(natsURL string) (*nats.Conn, error) {
 
 	return nil, fmt.Errorf("failed to connect to nats after %d attempts: %w", maxRetries, err)
 }
+
+// ConnectInBackground returns a connection to natsURL that is established,
+// and re-established after outages, in the background, so that callers can
+// start while the server is down. Publishing fails while it is not
+// connected instead of buffering in the client.
+func ConnectInBackground(natsURL string, logger *slog.Logger) (*nats.Conn, error) {
+	nc, err := nats.Connect(natsURL,
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectBufSize(-1),
+		nats.ConnectHandler(func(*nats.Conn) { logger.Info("connected to nats server") }),
+		nats.ReconnectHandler(func(*nats.Conn) { logger.Info("reconnected to nats server") }),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("disconnected from nats server", "err", err)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	return nc, nil
+}
+
+// Drain flushes pending publishes, lets subscriptions finish processing the
+// messages they already received, and closes nc. It waits until the connection
+// is closed or ctx ends, whichever comes first.
+func Drain(ctx context.Context, nc *nats.Conn) error {
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(*nats.Conn) { close(closed) })
+
+	if err := nc.Drain(); err != nil {
+		return fmt.Errorf("failed to drain nats connection: %w", err)
+	}
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		nc.Close()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,267 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxDocumentBytes bounds JWKS and discovery documents.
+	maxDocumentBytes = 1 << 20
+	// minRefreshInterval limits how often tokens signed with unknown keys can
+	// make the KeySet refetch its document.
+	minRefreshInterval = 30 * time.Second
+)
+
+// ErrUnknownKey is returned when no key of the set matches a token's kid.
+var ErrUnknownKey = errors.New("oidc: no key matches the token's kid")
+
+// KeySet holds the public keys an issuer signs tokens with. Keys are loaded
+// from a JWKS document, cached for a TTL and refetched early when a token
+// names a key the set does not know, which is how key rotation shows up.
+type KeySet struct {
+	issuer string
+	source string
+	ttl    time.Duration
+	client *http.Client
+	// minRefresh is minRefreshInterval, shortened in tests.
+	minRefresh time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// NewKeySet returns a KeySet reading the JWKS document at source, an http(s)
+// or file URL. When source is empty, its URL is discovered from the issuer's
+// OpenID configuration.
+func NewKeySet(issuer, source string, ttl time.Duration) *KeySet {
+	return &KeySet{
+		issuer:     issuer,
+		source:     source,
+		ttl:        ttl,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		minRefresh: minRefreshInterval,
+	}
+}
+
+// Key returns the key with the given kid.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) >= s.ttl
+	if ok && !stale {
+		return key, nil
+	}
+	if s.keys == nil || stale || time.Since(s.lastAttempt) >= s.minRefresh {
+		if err := s.refresh(ctx); err != nil {
+			// Keep serving the keys loaded last while the issuer is unreachable.
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		key, ok = s.keys[kid]
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refresh reloads the keys. s.mu must be held.
+func (s *KeySet) refresh(ctx context.Context) error {
+	s.lastAttempt = time.Now()
+
+	if s.source == "" {
+		source, err := s.discover(ctx)
+		if err != nil {
+			return err
+		}
+		s.source = source
+	}
+
+	b, err := s.read(ctx, s.source)
+	if err != nil {
+		return fmt.Errorf("oidc: could not load JWKS: %w", err)
+	}
+	keys, err := ParseJWKS(b)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+// discover reads the jwks_uri from the issuer's OpenID configuration.
+func (s *KeySet) discover(ctx context.Context) (string, error) {
+	b, err := s.read(ctx, strings.TrimSuffix(s.issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", fmt.Errorf("oidc: could not discover JWKS URL: %w", err)
+	}
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("oidc: invalid OpenID configuration: %w", err)
+	}
+	if doc.Issuer != s.issuer {
+		return "", fmt.Errorf("oidc: OpenID configuration is for issuer %q", doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("oidc: OpenID configuration has no jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
+
+func (s *KeySet) read(ctx context.Context, source string) ([]byte, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		f, err := os.Open(u.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxDocumentBytes))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDocumentBytes))
+}
+
+// jwk holds the members of a JSON Web Key needed for RSA, EC and Ed25519
+// signature keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JWKS document into its signature keys by kid. Keys of
+// other uses or unsupported types are skipped.
+func ParseJWKS(b []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("oidc: invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("oidc: JWKS key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey returns nil for key types that cannot verify supported tokens.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const audience = "veritas-api"
+
+func newValidator(iss *oidctest.Issuer, source string) *Validator {
+	return NewValidator(NewKeySet(iss.URL, source, time.Hour), Config{
+		Issuer:          iss.URL,
+		Audience:        audience,
+		UserClaim:       "email",
+		WorkspacesClaim: "veritas_workspaces",
+		Leeway:          time.Minute,
+	})
+}
+
+func TestValidate(t *testing.T) {
+	iss := oidctest.NewIssuer(t, audience)
+	v := newValidator(iss, iss.JWKSURL())
+	ctx := context.Background()
+
+	testCases := []struct {
+		name     string
+		claims   map[string]any
+		expected Identity
+		wantErr  bool
+	}{
+		{
+			name:     "Test a valid token",
+			claims:   map[string]any{"email": "alice@example.com"},
+			expected: Identity{User: "alice@example.com", Workspaces: map[string]string{}},
+		},
+		{
+			name: "Test workspace roles as an object",
+			claims: map[string]any{
+				"email":              "alice@example.com",
+				"veritas_workspaces": map[string]any{"growth": "Editor", "support": 3},
+			},
+			expected: Identity{User: "alice@example.com", Workspaces: map[string]string{"growth": "editor"}},
+		},
+		{
+			name: "Test workspace roles as a list",
+			claims: map[string]any{
+				"email":              "alice@example.com",
+				"veritas_workspaces": []any{"growth:admin", "broken", "support:viewer"},
+			},
+			expected: Identity{User: "alice@example.com", Workspaces: map[string]string{"growth": "admin", "support": "viewer"}},
+		},
+		{name: "Test a missing user claim", claims: map[string]any{}, wantErr: true},
+		{name: "Test another audience", claims: map[string]any{"email": "a@example.com", "aud": "other"}, wantErr: true},
+		{name: "Test another issuer", claims: map[string]any{"email": "a@example.com", "iss": "https://evil.example"}, wantErr: true},
+		{
+			name:    "Test an expired token",
+			claims:  map[string]any{"email": "a@example.com", "exp": time.Now().Add(-2 * time.Minute).Unix()},
+			wantErr: true,
+		},
+		{
+			name:     "Test expiry within the leeway",
+			claims:   map[string]any{"email": "a@example.com", "exp": time.Now().Add(-30 * time.Second).Unix()},
+			expected: Identity{User: "a@example.com", Workspaces: map[string]string{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := v.Validate(ctx, iss.Token(t, "subject", tc.claims))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, id)
+		})
+	}
+}
+
+func TestValidateRejectsForgedTokens(t *testing.T) {
+	iss := oidctest.NewIssuer(t, audience)
+	other := oidctest.NewIssuer(t, audience)
+	v := newValidator(iss, iss.JWKSURL())
+	ctx := context.Background()
+
+	// Signed by another issuer's key under a kid this issuer also uses.
+	_, err := v.Validate(ctx, other.Token(t, "subject", map[string]any{"email": "a@example.com", "iss": iss.URL}))
+	assert.Error(t, err)
+
+	token := iss.Token(t, "subject", map[string]any{"email": "a@example.com"})
+	_, err = v.Validate(ctx, token[:len(token)-4]+"AAAA")
+	assert.Error(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	iss := oidctest.NewIssuer(t, audience)
+	v := newValidator(iss, iss.JWKSURL())
+	ctx := context.Background()
+
+	_, err := v.Validate(ctx, iss.Token(t, "subject", map[string]any{"email": "a@example.com"}))
+	require.NoError(t, err)
+	_, err = v.Validate(ctx, iss.Token(t, "subject", map[string]any{"email": "a@example.com"}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, iss.JWKSRequests(), "keys are cached")
+
+	iss.RotateKey(t)
+	rotated := iss.Token(t, "subject", map[string]any{"email": "a@example.com"})
+
+	// Unknown kids only trigger a refetch once the refresh interval passed.
+	_, err = v.Validate(ctx, rotated)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	v.keys.minRefresh = 0
+	_, err = v.Validate(ctx, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, 2, iss.JWKSRequests())
+}
+
+func TestKeySetSources(t *testing.T) {
+	iss := oidctest.NewIssuer(t, audience)
+	token := iss.Token(t, "subject", map[string]any{"email": "a@example.com"})
+	ctx := context.Background()
+
+	t.Run("Test a JWKS file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		iss.WriteJWKS(t, path)
+
+		_, err := newValidator(iss, "file://"+path).Validate(ctx, token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test discovery", func(t *testing.T) {
+		_, err := newValidator(iss, "").Validate(ctx, token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Test an unreachable JWKS", func(t *testing.T) {
+		_, err := newValidator(iss, iss.URL+"/missing").Validate(ctx, token)
+		assert.True(t, IsKeyError(err), "%v", err)
+	})
+}
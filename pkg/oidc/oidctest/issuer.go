@@ -0,0 +1,151 @@
+// Package oidctest provides a local OpenID Connect issuer for tests and
+// development, standing in for the real identity provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer serves an OpenID configuration and JWKS document over HTTP and
+// signs tokens with its current RSA key.
+type Issuer struct {
+	// URL is the issuer identifier, the base URL of its HTTP server.
+	URL string
+	// Audience is put in the aud claim of issued tokens.
+	Audience string
+
+	server *httptest.Server
+
+	mu   sync.Mutex
+	keys []*rsa.PrivateKey
+	kids []string
+	// requests counts JWKS fetches.
+	requests int
+}
+
+// NewIssuer starts an Issuer with one signing key. It is stopped when the
+// test finishes.
+func NewIssuer(t testing.TB, audience string) *Issuer {
+	t.Helper()
+
+	iss := &Issuer{Audience: audience}
+	iss.RotateKey(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   iss.URL,
+			"jwks_uri": iss.JWKSURL(),
+		})
+	})
+	mux.HandleFunc("GET /jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		iss.mu.Lock()
+		iss.requests++
+		iss.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(iss.JWKS())
+	})
+	iss.server = httptest.NewServer(mux)
+	iss.URL = iss.server.URL
+	t.Cleanup(iss.server.Close)
+	return iss
+}
+
+// JWKSURL returns the URL of the issuer's JWKS document.
+func (iss *Issuer) JWKSURL() string {
+	return iss.URL + "/jwks.json"
+}
+
+// JWKSRequests returns how many times the JWKS document has been fetched.
+func (iss *Issuer) JWKSRequests() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	return iss.requests
+}
+
+// RotateKey adds a new signing key. Tokens are signed with it from now on,
+// and the previous keys stay published.
+func (iss *Issuer) RotateKey(t testing.TB) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.keys = append(iss.keys, key)
+	iss.kids = append(iss.kids, "key-"+strconv.Itoa(len(iss.keys)))
+}
+
+// JWKS returns the JWKS document with all keys.
+func (iss *Issuer) JWKS() []byte {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	keys := make([]map[string]string, 0, len(iss.keys))
+	for i, key := range iss.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": iss.kids[i],
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	b, _ := json.Marshal(map[string]any{"keys": keys})
+	return b
+}
+
+// WriteJWKS writes the JWKS document to path, for file based configuration.
+func (iss *Issuer) WriteJWKS(t testing.TB, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, iss.JWKS(), 0o600); err != nil {
+		t.Fatalf("write JWKS: %v", err)
+	}
+}
+
+// Token returns a token for subject signed with the current key. It is valid
+// for an hour; claims are added to, and override, the standard ones.
+func (iss *Issuer) Token(t testing.TB, subject string, claims map[string]any) string {
+	t.Helper()
+
+	now := time.Now()
+	c := jwt.MapClaims{
+		"iss": iss.URL,
+		"aud": iss.Audience,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+
+	iss.mu.Lock()
+	key, kid := iss.keys[len(iss.keys)-1], iss.kids[len(iss.kids)-1]
+	iss.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
@@ -0,0 +1,136 @@
+// Package oidc authenticates API callers by the JWT access and ID tokens of
+// an OpenID Connect provider. Tokens are checked against the provider's
+// published keys, its issuer and the API's audience, and their claims are
+// mapped to the identity and workspace roles used by the authz layer.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods are the algorithms tokens may be signed with. Symmetric and
+// unsigned tokens are never accepted.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Config describes the tokens a Validator accepts.
+type Config struct {
+	// Issuer must equal the iss claim.
+	Issuer string
+	// Audience must be one of the aud claim's values.
+	Audience string
+	// UserClaim names the claim that identifies the caller, e.g. sub or email.
+	UserClaim string
+	// WorkspacesClaim optionally names a claim granting workspace roles,
+	// either as an object of slug to role or as a list of "slug:role".
+	WorkspacesClaim string
+	// Leeway is the clock skew tolerated on exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// Identity is the caller a token was issued to.
+type Identity struct {
+	// User is the value of the user claim.
+	User string
+	// Workspaces holds the roles the token grants, by workspace slug. Role
+	// names are not checked here; the authz layer ignores unknown ones.
+	Workspaces map[string]string
+}
+
+// Validator checks tokens and maps their claims.
+type Validator struct {
+	keys   *KeySet
+	cfg    Config
+	parser *jwt.Parser
+}
+
+// NewValidator returns a Validator checking tokens against keys and cfg.
+func NewValidator(keys *KeySet, cfg Config) *Validator {
+	return &Validator{
+		keys: keys,
+		cfg:  cfg,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+			jwt.WithLeeway(cfg.Leeway),
+		),
+	}
+}
+
+// Validate verifies a compact JWT and returns the identity it carries.
+func (v *Validator) Validate(ctx context.Context, raw string) (Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return Identity{}, fmt.Errorf("oidc: invalid token: %w", err)
+	}
+
+	user, _ := claims[v.cfg.UserClaim].(string)
+	if user = strings.TrimSpace(user); user == "" {
+		return Identity{}, fmt.Errorf("oidc: token has no %s claim", v.cfg.UserClaim)
+	}
+
+	id := Identity{User: user}
+	if v.cfg.WorkspacesClaim != "" {
+		id.Workspaces = workspaceRoles(claims[v.cfg.WorkspacesClaim])
+	}
+	return id, nil
+}
+
+// workspaceRoles reads the workspaces claim. Malformed entries are skipped
+// rather than failing the whole token.
+func workspaceRoles(claim any) map[string]string {
+	roles := map[string]string{}
+	add := func(slug, role string) {
+		slug, role = strings.TrimSpace(slug), strings.ToLower(strings.TrimSpace(role))
+		if slug != "" && role != "" {
+			roles[slug] = role
+		}
+	}
+
+	switch c := claim.(type) {
+	case map[string]any:
+		for slug, role := range c {
+			if role, ok := role.(string); ok {
+				add(slug, role)
+			}
+		}
+	case []any:
+		for _, entry := range c {
+			if entry, ok := entry.(string); ok {
+				if slug, role, ok := strings.Cut(entry, ":"); ok {
+					add(slug, role)
+				}
+			}
+		}
+	case string:
+		for _, entry := range strings.Fields(c) {
+			if slug, role, ok := strings.Cut(entry, ":"); ok {
+				add(slug, role)
+			}
+		}
+	}
+	return roles
+}
+
+// IsKeyError reports whether err means the token's signing key could not be
+// resolved, as opposed to the token itself being invalid.
+func IsKeyError(err error) bool {
+	return errors.Is(err, jwt.ErrTokenUnverifiable) && !errors.Is(err, ErrUnknownKey)
+}
@@ -0,0 +1,171 @@
+// Package outbox publishes events reliably with a transactional outbox. An
+// event is written to the outbox_events table in the same transaction as the
+// change it describes, so it exists exactly when the change was committed,
+// and a Relay publishes it on NATS afterwards, deleting it once the server
+// has acknowledged it. Events are published at least once: consumers drop
+// duplicates by their event IDs, as the webhooks worker does by queueing an
+// event once per endpoint.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// pollInterval is how often the outbox is checked for events the Relay
+	// was not notified of, such as those written by other replicas.
+	pollInterval = time.Second
+	// batchSize is the number of events published per transaction.
+	batchSize = 100
+	// flushTimeout bounds the wait for NATS to acknowledge a batch.
+	flushTimeout = 5 * time.Second
+)
+
+// Store is the part of sqlc.Querier that writes events.
+type Store interface {
+	CreateOutboxEvent(ctx context.Context, arg sqlc.CreateOutboxEventParams) error
+}
+
+// Add writes msg to the outbox, to be published on subject. q should be
+// bound to the transaction of the change msg describes. The trace context
+// of ctx is stored with it, so consumers join the trace of the request.
+func Add(ctx context.Context, q Store, subject string, msg proto.Message) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not marshal %s event: %w", subject, err)
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	headers, err := json.Marshal(carrier)
+	if err != nil {
+		return err
+	}
+	return q.CreateOutboxEvent(ctx, sqlc.CreateOutboxEventParams{Subject: subject, Payload: payload, Headers: headers})
+}
+
+// Relay publishes the events of the outbox on NATS.
+type Relay struct {
+	db     *pgxpool.Pool
+	nc     *nats.Conn
+	logger *slog.Logger
+	wake   chan struct{}
+}
+
+// NewRelay returns a Relay publishing the events stored in db on nc.
+func NewRelay(db *pgxpool.Pool, nc *nats.Conn, logger *slog.Logger) *Relay {
+	return &Relay{db: db, nc: nc, logger: logger, wake: make(chan struct{}, 1)}
+}
+
+// Notify tells the Relay events were committed, so they are published
+// without waiting for the next poll. It does nothing on a nil Relay.
+func (r *Relay) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes events as they are committed until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+
+		for {
+			n, err := r.Publish(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.logger.Error("failed to publish outbox events", "err", err)
+				}
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+	}
+}
+
+// Publish publishes the oldest events of the outbox and deletes them, in
+// one transaction. If publishing fails nothing is deleted and the events are
+// published again on the next attempt. It returns the number of events
+// published.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	var published []sqlc.OutboxEvent
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		q := sqlc.New(tx)
+		rows, err := q.ClaimOutboxEvents(ctx, batchSize)
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			if err := r.publish(ctx, row); err != nil {
+				return err
+			}
+			ids[i] = row.ID
+		}
+		// Only delete events NATS has received.
+		if err := r.nc.FlushTimeout(flushTimeout); err != nil {
+			return fmt.Errorf("could not flush outbox events: %w", err)
+		}
+		if err := q.DeleteOutboxEvents(ctx, ids); err != nil {
+			return err
+		}
+		published = rows
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range published {
+		metrics.OutboxEventsPublished.WithLabelValues(row.Subject).Inc()
+	}
+	return len(published), nil
+}
+
+func (r *Relay) publish(ctx context.Context, row sqlc.OutboxEvent) error {
+	var carrier propagation.MapCarrier
+	if err := json.Unmarshal(row.Headers, &carrier); err == nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	}
+
+	msg := &nats.Msg{Subject: row.Subject, Data: row.Payload, Header: nats.Header{}}
+	_, span := telemetry.StartPublish(ctx, msg)
+	defer span.End()
+
+	if err := r.nc.PublishMsg(msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		metrics.NATSPublishFailures.WithLabelValues(row.Subject).Inc()
+		return fmt.Errorf("could not publish outbox event %d: %w", row.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeStore struct {
+	events []sqlc.CreateOutboxEventParams
+}
+
+func (s *fakeStore) CreateOutboxEvent(_ context.Context, arg sqlc.CreateOutboxEventParams) error {
+	s.events = append(s.events, arg)
+	return nil
+}
+
+func TestAdd(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	store := &fakeStore{}
+	event := events.LinkCreated(&eventsv1.Link{Id: 1, ShortCode: "abc", WorkspaceId: 7})
+	require.NoError(t, Add(ctx, store, events.SubjectLinkCreated, event))
+
+	require.Len(t, store.events, 1)
+	assert.Equal(t, events.SubjectLinkCreated, store.events[0].Subject)
+
+	got := &eventsv1.LinkCreated{}
+	require.NoError(t, proto.Unmarshal(store.events[0].Payload, got))
+	assert.True(t, proto.Equal(event, got))
+
+	var headers map[string]string
+	require.NoError(t, json.Unmarshal(store.events[0].Headers, &headers))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", headers["traceparent"])
+}
@@ -0,0 +1,167 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/metrics"
+)
+
+// ErrClosed is returned by Buffered.Publish once the publisher is closed.
+var ErrClosed = errors.New("publisher is closed")
+
+// BufferConfig configures a Buffered publisher.
+type BufferConfig struct {
+	// Size is the number of messages held in memory on their way to the
+	// wrapped publisher.
+	Size int
+	// SpoolDir is the directory messages are spooled to while the wrapped
+	// publisher is unavailable.
+	SpoolDir string
+	// SpoolMaxBytes bounds the spool. Messages are dropped once it is full.
+	SpoolMaxBytes int64
+	// RetryInterval is how often spooled messages are replayed.
+	RetryInterval time.Duration
+}
+
+// Buffered publishes through another publisher in the background, so
+// Publish never blocks on it. Messages it rejects are spooled to disk and
+// replayed, in order, once it accepts messages again. New messages are
+// spooled too while older ones wait, and when the in-memory buffer is full.
+type Buffered struct {
+	next   EventPublisher
+	spool  *spool
+	queue  chan *nats.Msg
+	retry  time.Duration
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBuffered returns a Buffered publisher for next. Messages spooled by a
+// previous process are replayed once Run starts.
+func NewBuffered(next EventPublisher, cfg BufferConfig, logger *slog.Logger) (*Buffered, error) {
+	s, err := openSpool(cfg.SpoolDir, cfg.SpoolMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	metrics.EventsSpoolBytes.Set(float64(s.bytes()))
+	return &Buffered{
+		next:   next,
+		spool:  s,
+		queue:  make(chan *nats.Msg, cfg.Size),
+		retry:  cfg.RetryInterval,
+		logger: logger,
+	}, nil
+}
+
+// Publish queues msg. It only returns an error when msg could be neither
+// queued nor spooled.
+func (b *Buffered) Publish(_ context.Context, msg *nats.Msg) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	select {
+	case b.queue <- msg:
+		return nil
+	default:
+		return b.store(msg)
+	}
+}
+
+// Run hands queued messages to the wrapped publisher and replays the spool
+// every RetryInterval until ctx is cancelled.
+func (b *Buffered) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.retry)
+	defer ticker.Stop()
+
+	b.replay(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-b.queue:
+			b.forward(ctx, msg)
+		case <-ticker.C:
+			b.replay(ctx)
+		}
+	}
+}
+
+// Close stops accepting messages and hands the queued ones to the wrapped
+// publisher, spooling those it rejects for the next start.
+func (b *Buffered) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	for {
+		select {
+		case msg := <-b.queue:
+			b.forward(ctx, msg)
+		default:
+			return b.spool.close()
+		}
+	}
+}
+
+// forward publishes msg unless older messages are still spooled, and spools
+// it if that fails.
+func (b *Buffered) forward(ctx context.Context, msg *nats.Msg) {
+	if b.spool.empty() {
+		err := b.next.Publish(ctx, msg)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, ErrUnavailable) {
+			metrics.NATSPublishFailures.WithLabelValues(msg.Subject).Inc()
+			b.logger.Error("failed to publish event, spooling it", "subject", msg.Subject, "err", err)
+		}
+	}
+	b.store(msg)
+}
+
+func (b *Buffered) store(msg *nats.Msg) error {
+	err := b.spool.append(msg)
+	if err != nil {
+		metrics.EventsDropped.WithLabelValues(msg.Subject).Inc()
+		b.logger.Error("failed to spool event, dropping it", "subject", msg.Subject, "err", err)
+		return err
+	}
+	metrics.EventsSpooled.WithLabelValues(msg.Subject).Inc()
+	metrics.EventsSpoolBytes.Set(float64(b.spool.bytes()))
+	return nil
+}
+
+// replay publishes spooled messages until the spool is empty or the wrapped
+// publisher rejects one.
+func (b *Buffered) replay(ctx context.Context) {
+	replayed := 0
+	defer func() {
+		metrics.EventsSpoolBytes.Set(float64(b.spool.bytes()))
+		if replayed > 0 {
+			b.logger.Info("replayed spooled events", "count", replayed)
+		}
+	}()
+
+	for !b.spool.empty() {
+		n, err := b.spool.replay(func(msg *nats.Msg) error {
+			return b.next.Publish(ctx, msg)
+		})
+		replayed += n
+		if err != nil {
+			if !errors.Is(err, ErrUnavailable) {
+				b.logger.Error("failed to replay spooled events", "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package publisher abstracts how services publish events, so handlers do
+// not depend on NATS being configured or reachable. NATS publishes on a
+// connection, Memory keeps messages for tests and Noop discards them.
+// Buffered wraps another publisher so callers never wait for it: messages
+// are handed over in the background and spooled to disk while it is
+// unavailable.
+package publisher
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrUnavailable is returned when a message cannot be published right now,
+// e.g. while the NATS connection is down.
+var ErrUnavailable = errors.New("publisher is unavailable")
+
+// EventPublisher publishes messages on their subject.
+type EventPublisher interface {
+	Publish(ctx context.Context, msg *nats.Msg) error
+}
+
+// NATS publishes on a NATS connection.
+type NATS struct {
+	nc *nats.Conn
+}
+
+// NewNATS returns a publisher for nc.
+func NewNATS(nc *nats.Conn) *NATS {
+	return &NATS{nc: nc}
+}
+
+// Publish publishes msg, or returns ErrUnavailable when the connection is
+// not established. Messages are not buffered by the client while it
+// reconnects.
+func (p *NATS) Publish(_ context.Context, msg *nats.Msg) error {
+	if !p.nc.IsConnected() {
+		return ErrUnavailable
+	}
+	return p.nc.PublishMsg(msg)
+}
+
+// Memory keeps published messages in memory.
+type Memory struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+// Publish records msg.
+func (p *Memory) Publish(_ context.Context, msg *nats.Msg) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+// Messages returns the messages published so far.
+func (p *Memory) Messages() []*nats.Msg {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.msgs)
+}
+
+// Noop discards messages.
+type Noop struct{}
+
+// Publish does nothing.
+func (Noop) Publish(context.Context, *nats.Msg) error {
+	return nil
+}
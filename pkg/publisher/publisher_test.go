@@ -0,0 +1,156 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flaky publishes to a Memory publisher while it is up.
+type flaky struct {
+	Memory
+	mu   sync.Mutex
+	down bool
+}
+
+func (p *flaky) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+func (p *flaky) Publish(ctx context.Context, msg *nats.Msg) error {
+	p.mu.Lock()
+	down := p.down
+	p.mu.Unlock()
+	if down {
+		return ErrUnavailable
+	}
+	return p.Memory.Publish(ctx, msg)
+}
+
+func message(i int) *nats.Msg {
+	msg := &nats.Msg{Subject: "veritas.test", Data: []byte(fmt.Sprintf("event %d", i)), Header: nats.Header{}}
+	msg.Header.Set("traceparent", fmt.Sprintf("trace-%d", i))
+	return msg
+}
+
+func payloads(msgs []*nats.Msg) []string {
+	var out []string
+	for _, m := range msgs {
+		out = append(out, string(m.Data))
+	}
+	return out
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, 1<<20)
+	require.NoError(t, err)
+	for i := range 3 {
+		require.NoError(t, s.append(message(i)))
+	}
+	require.NoError(t, s.close())
+
+	// Spooled messages survive a restart and keep their headers.
+	s, err = openSpool(dir, 1<<20)
+	require.NoError(t, err)
+	assert.False(t, s.empty())
+
+	var got []*nats.Msg
+	fail := true
+	n, err := s.replay(func(msg *nats.Msg) error {
+		if len(got) == 1 && fail {
+			fail = false
+			return ErrUnavailable
+		}
+		got = append(got, msg)
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, n)
+
+	// The rejected message is replayed again, and nothing twice.
+	n, err = s.replay(func(msg *nats.Msg) error {
+		got = append(got, msg)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"event 0", "event 1", "event 2"}, payloads(got))
+	assert.Equal(t, "trace-2", got[2].Header.Get("traceparent"))
+	assert.True(t, s.empty())
+	assert.Zero(t, s.bytes())
+}
+
+func TestSpoolIsBounded(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 200)
+	require.NoError(t, err)
+
+	require.NoError(t, s.append(message(0)))
+	require.NoError(t, s.append(message(1)))
+	assert.ErrorIs(t, s.append(message(2)), ErrSpoolFull)
+	assert.LessOrEqual(t, s.bytes(), int64(200))
+}
+
+func TestBuffered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	next := &flaky{down: true}
+	b, err := NewBuffered(next, BufferConfig{Size: 10, SpoolDir: t.TempDir(), SpoolMaxBytes: 1 << 20, RetryInterval: 10 * time.Millisecond},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	go b.Run(ctx)
+
+	// Publishing never fails while NATS is down; events go to the spool.
+	for i := range 5 {
+		require.NoError(t, b.Publish(ctx, message(i)))
+	}
+	require.Eventually(t, func() bool { return b.spool.bytes() > 0 && len(b.queue) == 0 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, next.Messages())
+
+	// Once it is back they are replayed in order, followed by new ones.
+	next.setDown(false)
+	require.Eventually(t, b.spool.empty, time.Second, 5*time.Millisecond)
+	require.NoError(t, b.Publish(ctx, message(5)))
+	require.Eventually(t, func() bool { return len(next.Messages()) == 6 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"event 0", "event 1", "event 2", "event 3", "event 4", "event 5"}, payloads(next.Messages()))
+
+	cancel()
+	require.NoError(t, b.Close(context.Background()))
+	assert.ErrorIs(t, b.Publish(context.Background(), message(6)), ErrClosed)
+}
+
+func TestBufferedCloseSpoolsQueuedMessages(t *testing.T) {
+	dir := t.TempDir()
+	next := &flaky{down: true}
+	b, err := NewBuffered(next, BufferConfig{Size: 10, SpoolDir: dir, SpoolMaxBytes: 1 << 20, RetryInterval: time.Hour},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	// Run never started, so the messages are still queued when closing.
+	require.NoError(t, b.Publish(context.Background(), message(0)))
+	require.NoError(t, b.Publish(context.Background(), message(1)))
+	require.NoError(t, b.Close(context.Background()))
+
+	// The next process replays them.
+	next.setDown(false)
+	b, err = NewBuffered(next, BufferConfig{Size: 10, SpoolDir: dir, SpoolMaxBytes: 1 << 20, RetryInterval: time.Hour},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	require.Eventually(t, func() bool { return len(next.Messages()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"event 0", "event 1"}, payloads(next.Messages()))
+}
@@ -0,0 +1,248 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	segmentSuffix = ".spool"
+	// maxSegmentBytes is the size segments are rotated at.
+	maxSegmentBytes = 16 << 20
+)
+
+// ErrSpoolFull is returned when a message does not fit in the spool.
+var ErrSpoolFull = errors.New("spool is full")
+
+// record is a spooled message, one JSON object per line of a segment.
+type record struct {
+	Subject string      `json:"subject"`
+	Header  nats.Header `json:"header,omitempty"`
+	Data    []byte      `json:"data"`
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// spool is a bounded queue of messages on disk. Messages are appended to
+// numbered segment files, which are replayed oldest first and removed once
+// every message in them was published. The spool survives restarts; a
+// message torn by a crash is dropped when it is read.
+type spool struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	mu       sync.Mutex
+	segments []segment
+	size     int64
+	// w appends to the last segment. It is nil when that segment is being
+	// replayed or a write to it failed, and the next append starts a new one.
+	w *os.File
+}
+
+// openSpool opens the spool in dir, creating dir if needed. Segments left by
+// a previous process are kept for replay.
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("could not create spool directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read spool directory: %w", err)
+	}
+
+	s := &spool{dir: dir, maxBytes: maxBytes, segmentBytes: min(max(maxBytes/8, 1), maxSegmentBytes)}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentSuffix)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.segments = append(s.segments, segment{seq: seq, size: info.Size()})
+		s.size += info.Size()
+	}
+	slices.SortFunc(s.segments, func(a, b segment) int { return cmp.Compare(a.seq, b.seq) })
+	return s, nil
+}
+
+func (s *spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+// append adds msg to the end of the spool.
+func (s *spool) append(msg *nats.Msg) error {
+	line, err := json.Marshal(record{Subject: msg.Subject, Header: msg.Header, Data: msg.Data})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size+int64(len(line)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+	if s.w == nil || s.segments[len(s.segments)-1].size >= s.segmentBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.w.Write(line)
+	last := &s.segments[len(s.segments)-1]
+	last.size += int64(n)
+	s.size += int64(n)
+	if err != nil {
+		// The segment may end in a partial line now; later messages go to
+		// a new one so that only this message is lost.
+		s.w.Close()
+		s.w = nil
+		return fmt.Errorf("could not write to spool: %w", err)
+	}
+	return nil
+}
+
+// rotate starts a new segment. s.mu must be held.
+func (s *spool) rotate() error {
+	if s.w != nil {
+		s.w.Close()
+		s.w = nil
+	}
+	var seq uint64 = 1
+	if len(s.segments) > 0 {
+		seq = s.segments[len(s.segments)-1].seq + 1
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("could not create spool segment: %w", err)
+	}
+	s.w = f
+	s.segments = append(s.segments, segment{seq: seq})
+	return nil
+}
+
+// empty reports whether the spool holds no messages.
+func (s *spool) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) == 0
+}
+
+// bytes returns the size of the spool on disk.
+func (s *spool) bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// replay calls fn with the messages of the oldest segment in order. The
+// segment is removed when fn accepted all of them; otherwise it is cut down
+// to the messages from the one fn rejected, and fn's error is returned. It
+// returns the number of messages fn accepted. Only one replay may run at a
+// time, but messages can be appended concurrently.
+func (s *spool) replay(fn func(*nats.Msg) error) (int, error) {
+	s.mu.Lock()
+	if len(s.segments) == 0 {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	seg := s.segments[0]
+	if len(s.segments) == 1 && s.w != nil {
+		// Appends continue in a new segment while this one is replayed.
+		s.w.Close()
+		s.w = nil
+	}
+	s.mu.Unlock()
+
+	path := s.path(seg.seq)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("could not read spool segment: %w", err)
+	}
+
+	published := 0
+	r := bufio.NewReader(bytes.NewReader(data))
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Empty, or a partial line left by a failed write.
+			break
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// Skip a corrupt message rather than blocking the spool on it.
+			offset += int64(len(line))
+			continue
+		}
+		if err := fn(&nats.Msg{Subject: rec.Subject, Header: rec.Header, Data: rec.Data}); err != nil {
+			return published, s.truncate(seg, data[offset:], err)
+		}
+		offset += int64(len(line))
+		published++
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return published, fmt.Errorf("could not remove spool segment: %w", err)
+	}
+	s.mu.Lock()
+	s.segments = slices.Delete(s.segments, 0, 1)
+	s.size -= seg.size
+	s.mu.Unlock()
+	return published, nil
+}
+
+// truncate replaces seg with the messages in rest, which were not replayed,
+// and returns cause.
+func (s *spool) truncate(seg segment, rest []byte, cause error) error {
+	path := s.path(seg.seq)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, rest, 0o640); err != nil {
+		return errors.Join(cause, fmt.Errorf("could not rewrite spool segment: %w", err))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Join(cause, fmt.Errorf("could not rewrite spool segment: %w", err))
+	}
+
+	s.mu.Lock()
+	s.segments[0].size = int64(len(rest))
+	s.size -= seg.size - int64(len(rest))
+	s.mu.Unlock()
+	return cause
+}
+
+// close closes the segment being appended to.
+func (s *spool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	s.w = nil
+	return err
+}
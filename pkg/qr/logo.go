@@ -0,0 +1,50 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // registers the JPEG decoder
+	_ "image/png"  // registers the PNG decoder
+)
+
+const (
+	// MaxLogoBytes bounds uploaded logo files.
+	MaxLogoBytes = 256 << 10
+	// maxLogoDimension bounds the width and height of a logo in pixels.
+	maxLogoDimension = 1024
+)
+
+// Logo is an image drawn in the middle of a QR code.
+type Logo struct {
+	Image       image.Image
+	ContentType string
+	// Data is the encoded file, embedded as-is in SVG output.
+	Data []byte
+}
+
+// DecodeLogo checks that data is a PNG or JPEG image of reasonable size and
+// decodes it.
+func DecodeLogo(data []byte) (*Logo, error) {
+	if len(data) > MaxLogoBytes {
+		return nil, fmt.Errorf("logo must be at most %d bytes", MaxLogoBytes)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("logo must be a PNG or JPEG image")
+	}
+	if format != "png" && format != "jpeg" {
+		return nil, errors.New("logo must be a PNG or JPEG image")
+	}
+	if cfg.Width > maxLogoDimension || cfg.Height > maxLogoDimension {
+		return nil, fmt.Errorf("logo must be at most %dx%d pixels", maxLogoDimension, maxLogoDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode logo: %w", err)
+	}
+	return &Logo{Image: img, ContentType: "image/" + format, Data: data}, nil
+}
@@ -0,0 +1,292 @@
+// Package qr renders QR codes of short links as PNG or SVG, with custom
+// colors, margins and an optional logo in the middle.
+package qr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+
+	// logoScale is the share of the code's width a logo may cover. Level H
+	// restores up to 30% of the modules, so this leaves room for wear.
+	logoScale = 0.22
+)
+
+// levels maps the error correction letters used in the API to go-qrcode's
+// recovery levels.
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options controls how a code is rendered.
+type Options struct {
+	Format string
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Level is the error correction level: L, M, Q or H.
+	Level string
+	// Margin is the quiet zone around the code, in modules.
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// DefaultOptions returns black-on-white PNG options.
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       DefaultSize,
+		Level:      "M",
+		Margin:     DefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// ParseOptions reads options from query parameters: format, size, ecc,
+// margin, fg and bg. Missing parameters keep their defaults.
+func ParseOptions(q url.Values) (Options, error) {
+	o := DefaultOptions()
+
+	if v := q.Get("format"); v != "" {
+		o.Format = strings.ToLower(v)
+		if o.Format != FormatPNG && o.Format != FormatSVG {
+			return o, fmt.Errorf("format must be %s or %s", FormatPNG, FormatSVG)
+		}
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < MinSize || size > MaxSize {
+			return o, fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+		}
+		o.Size = size
+	}
+	if v := q.Get("ecc"); v != "" {
+		o.Level = strings.ToUpper(v)
+		if _, ok := levels[o.Level]; !ok {
+			return o, errors.New("ecc must be one of L, M, Q or H")
+		}
+	}
+	if v := q.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > MaxMargin {
+			return o, fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+		}
+		o.Margin = margin
+	}
+	var err error
+	if v := q.Get("fg"); v != "" {
+		if o.Foreground, err = ParseColor(v); err != nil {
+			return o, fmt.Errorf("fg: %w", err)
+		}
+	}
+	if v := q.Get("bg"); v != "" {
+		if o.Background, err = ParseColor(v); err != nil {
+			return o, fmt.Errorf("bg: %w", err)
+		}
+	}
+	if o.Foreground == o.Background {
+		return o, errors.New("fg and bg must differ")
+	}
+	return o, nil
+}
+
+// ParseColor parses a hex color of the form RGB, RRGGBB or RRGGBBAA, with or
+// without a leading '#'.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	// color.RGBA is alpha-premultiplied.
+	a := uint16(b[3])
+	return color.RGBA{
+		R: uint8(uint16(b[0]) * a / 0xff),
+		G: uint8(uint16(b[1]) * a / 0xff),
+		B: uint8(uint16(b[2]) * a / 0xff),
+		A: b[3],
+	}, nil
+}
+
+// Key identifies the rendered output of content with these options. Callers
+// add whatever else affects the image, such as the logo's version.
+func (o Options) Key(content string, extra ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%d\x00%s\x00%s", content, o.Format, o.Size, o.Level, o.Margin,
+		hexColor(o.Foreground), hexColor(o.Background))
+	for _, e := range extra {
+		fmt.Fprintf(h, "\x00%s", e)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ContentType returns the media type of the rendered format.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes content as a QR code. A logo, when given, is drawn in the
+// middle of the code; the error correction level is then raised to H so the
+// modules it hides can be recovered.
+func Render(content string, o Options, logo *Logo) ([]byte, error) {
+	level := levels[o.Level]
+	if logo != nil {
+		level = qrcode.Highest
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	// Every module gets the same whole number of pixels so the code stays
+	// crisp; the leftover pixels are split around it.
+	modules := len(bitmap) + 2*o.Margin
+	scale := o.Size / modules
+	if scale < 1 {
+		return nil, fmt.Errorf("size %d is too small for a code of %d modules", o.Size, modules)
+	}
+
+	if o.Format == FormatSVG {
+		return renderSVG(bitmap, o, logo), nil
+	}
+	return renderPNG(bitmap, o, scale, logo)
+}
+
+func renderPNG(bitmap [][]bool, o Options, scale int, logo *Logo) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, o.Size, o.Size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: o.Background}, image.Point{}, draw.Src)
+
+	offset := (o.Size-scale*(len(bitmap)+2*o.Margin))/2 + scale*o.Margin
+	fg := &image.Uniform{C: o.Foreground}
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				r := image.Rect(offset+x*scale, offset+y*scale, offset+(x+1)*scale, offset+(y+1)*scale)
+				draw.Draw(img, r, fg, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if logo != nil {
+		width := len(bitmap) * scale
+		box := logoBox(width, logo.Image.Bounds())
+		box = box.Add(image.Pt(offset, offset))
+		// Clear the modules behind the logo, with a one-module border.
+		draw.Draw(img, box.Inset(-scale), &image.Uniform{C: o.Background}, image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(img, box, logo.Image, logo.Image.Bounds(), draw.Over, nil)
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, o Options, logo *Logo) []byte {
+	n := len(bitmap) + 2*o.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" %s/>`, n, n, svgFill(o.Background))
+	fmt.Fprintf(&buf, `<path %s d="`, svgFill(o.Foreground))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+o.Margin, y+o.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if logo != nil {
+		// Work in hundredths of a module so the logo box keeps its precision.
+		const unit = 100
+		box := logoBox(len(bitmap)*unit, logo.Image.Bounds()).Add(image.Pt(o.Margin*unit, o.Margin*unit))
+		bg := box.Inset(-unit)
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`,
+			svgNum(bg.Min.X, unit), svgNum(bg.Min.Y, unit), svgNum(bg.Dx(), unit), svgNum(bg.Dy(), unit), svgFill(o.Background))
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" href="data:%s;base64,%s"/>`,
+			svgNum(box.Min.X, unit), svgNum(box.Min.Y, unit), svgNum(box.Dx(), unit), svgNum(box.Dy(), unit),
+			logo.ContentType, base64.StdEncoding.EncodeToString(logo.Data))
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// logoBox returns the rectangle a logo of the given bounds occupies in a code
+// width pixels wide: centered, at most logoScale of the width on its longer
+// side, keeping its aspect ratio.
+func logoBox(width int, bounds image.Rectangle) image.Rectangle {
+	max := int(float64(width) * logoScale)
+	w, h := max, max
+	if bounds.Dx() > bounds.Dy() {
+		h = max * bounds.Dy() / bounds.Dx()
+	} else if bounds.Dy() > bounds.Dx() {
+		w = max * bounds.Dx() / bounds.Dy()
+	}
+	x, y := (width-w)/2, (width-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
+func svgFill(c color.RGBA) string {
+	if c.A == 0xff {
+		return fmt.Sprintf(`fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	}
+	if c.A == 0 {
+		return `fill="none"`
+	}
+	// Undo the premultiplication for SVG's straight alpha.
+	r, g, b := uint16(c.R)*0xff/uint16(c.A), uint16(c.G)*0xff/uint16(c.A), uint16(c.B)*0xff/uint16(c.A)
+	return fmt.Sprintf(`fill="#%02x%02x%02x" fill-opacity="%.3f"`, r, g, b, float64(c.A)/0xff)
+}
+
+func svgNum(v, unit int) string {
+	return strconv.FormatFloat(float64(v)/float64(unit), 'f', -1, 64)
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
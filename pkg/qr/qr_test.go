@@ -0,0 +1,156 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, o Options)
+	}{
+		{
+			name:  "Test defaults",
+			query: "",
+			check: func(t *testing.T, o Options) { assert.Equal(t, DefaultOptions(), o) },
+		},
+		{
+			name:  "Test all parameters",
+			query: "format=SVG&size=512&ecc=h&margin=0&fg=%23ff0000&bg=00f",
+			check: func(t *testing.T, o Options) {
+				assert.Equal(t, FormatSVG, o.Format)
+				assert.Equal(t, 512, o.Size)
+				assert.Equal(t, "H", o.Level)
+				assert.Equal(t, 0, o.Margin)
+				assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, o.Foreground)
+				assert.Equal(t, color.RGBA{B: 0xff, A: 0xff}, o.Background)
+			},
+		},
+		{
+			name:  "Test a transparent background",
+			query: "bg=ffffff00",
+			check: func(t *testing.T, o Options) { assert.Equal(t, color.RGBA{}, o.Background) },
+		},
+		{name: "Test an unknown format", query: "format=gif", wantErr: true},
+		{name: "Test a size that is too small", query: "size=10", wantErr: true},
+		{name: "Test an unknown level", query: "ecc=X", wantErr: true},
+		{name: "Test a negative margin", query: "margin=-1", wantErr: true},
+		{name: "Test an invalid color", query: "fg=zzz", wantErr: true},
+		{name: "Test equal colors", query: "fg=fff&bg=ffffff", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			o, err := ParseOptions(q)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, o)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	o := DefaultOptions()
+	other := o
+	other.Size = 512
+
+	assert.Equal(t, o.Key("https://s/a"), o.Key("https://s/a"))
+	assert.NotEqual(t, o.Key("https://s/a"), o.Key("https://s/b"))
+	assert.NotEqual(t, o.Key("https://s/a"), other.Key("https://s/a"))
+	assert.NotEqual(t, o.Key("https://s/a", "1"), o.Key("https://s/a", "2"))
+}
+
+func TestRenderPNG(t *testing.T) {
+	o := DefaultOptions()
+	o.Foreground = color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}
+
+	b, err := Render("https://sho.rt/abc", o, nil)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, o.Size, o.Size), img.Bounds())
+
+	// The URL needs a version 2 code of 25 modules. The corner lies in the
+	// quiet zone; the finder pattern starts right after it.
+	assertColor(t, o.Background, img.At(0, 0))
+	scale := o.Size / (25 + 2*o.Margin)
+	offset := (o.Size-scale*(25+2*o.Margin))/2 + scale*o.Margin
+	assertColor(t, o.Background, img.At(offset-1, offset-1))
+	assertColor(t, o.Foreground, img.At(offset, offset))
+}
+
+func TestRenderSVG(t *testing.T) {
+	o := DefaultOptions()
+	o.Format = FormatSVG
+
+	b, err := Render("https://sho.rt/abc", o, nil)
+	require.NoError(t, err)
+
+	svg := string(b)
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, `width="256" height="256" viewBox="0 0 33 33"`)
+	assert.Contains(t, svg, `fill="#000000"`)
+	assert.NotContains(t, svg, "<image")
+}
+
+func TestRenderWithLogo(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			src.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	logo, err := DecodeLogo(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", logo.ContentType)
+
+	o := DefaultOptions()
+	b, err := Render("https://sho.rt/abc", o, logo)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assertColor(t, color.RGBA{R: 0xff, A: 0xff}, img.At(o.Size/2, o.Size/2))
+
+	o.Format = FormatSVG
+	b, err = Render("https://sho.rt/abc", o, logo)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `href="data:image/png;base64,`)
+
+	_, err = DecodeLogo([]byte("not an image"))
+	assert.Error(t, err)
+}
+
+func TestRenderTooSmall(t *testing.T) {
+	o := DefaultOptions()
+	o.Size = MinSize
+	o.Margin = MaxMargin
+
+	_, err := Render(strings.Repeat("https://sho.rt/abc", 20), o, nil)
+	assert.Error(t, err)
+}
+
+func assertColor(t *testing.T, expected color.RGBA, actual color.Color) {
+	t.Helper()
+	assert.Equal(t, expected, color.RGBAModel.Convert(actual))
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcra implements the generic cell rate algorithm. The key stores the
+// theoretical arrival time (TAT) of the next request; a request is allowed
+// when admitting it does not push the TAT further than the burst allowance
+// ahead of now. Redis' own clock is used so that all replicas agree.
+//
+// KEYS[1]: limiter key
+// ARGV: burst, rate, period in seconds
+// Returns: allowed (0/1), remaining, retry_after and reset_after in seconds.
+var gcra = redis.NewScript(`
+local key = KEYS[1]
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+local emission_interval = period / rate
+local burst_offset = emission_interval * burst
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local tat = tonumber(redis.call("GET", key))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+
+if diff < 0 then
+  return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", key, tostring(new_tat), "EX", math.ceil(reset_after))
+
+return {1, math.floor(diff / emission_interval), "0", tostring(reset_after)}
+`)
+
+// Result is the outcome of a rate limit decision.
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// Limiter enforces limits shared by every replica through Redis.
+type Limiter struct {
+	rdb    *redis.Client
+	policy *Policy
+	prefix string
+}
+
+// New returns a Limiter storing its state in rdb.
+func New(rdb *redis.Client, policy *Policy) *Limiter {
+	return &Limiter{rdb: rdb, policy: policy, prefix: "ratelimit:"}
+}
+
+// Policy returns the limits the Limiter enforces.
+func (l *Limiter) Policy() *Policy {
+	return l.policy
+}
+
+// Allow records a request for key against limit and reports whether it may proceed.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := gcra.Run(ctx, l.rdb, []string{l.prefix + key},
+		limit.Burst, limit.Rate, limit.Period.Seconds(),
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("could not evaluate rate limit: %w", err)
+	}
+
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply: %v", values)
+	}
+
+	retryAfter, err := parseSeconds(values[2])
+	if err != nil {
+		return Result{}, err
+	}
+	resetAfter, err := parseSeconds(values[3])
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+
+	return Result{
+		Allowed:    allowed == 1,
+		Limit:      limit,
+		Remaining:  int(remaining),
+		RetryAfter: retryAfter,
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+func parseSeconds(v interface{}) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected rate limit duration %v", v)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected rate limit duration %q: %w", s, err)
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/api/middleware"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/utils"
+)
+
+// Middleware limits requests to route per client. Clients sending a known API
+// key are limited per key according to its tier, all others per IP address in
+// the anonymous tier. When Redis is unavailable requests are let through.
+func (l *Limiter) Middleware(route string, trustedProxies []netip.Prefix, logger *slog.Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tier, key := AnonymousTier, "ip:"+utils.ClientIP(r, trustedProxies)
+			if apiKey := r.Header.Get(middleware.APIKeyHeader); apiKey != "" {
+				if t, id, ok := l.policy.Tier(apiKey); ok {
+					tier, key = t, "key:"+id
+				}
+			}
+
+			limit, ok := l.policy.Limit(route, tier)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := l.Allow(r.Context(), route+":"+key, limit)
+			if err != nil {
+				// Fail open: an unavailable limiter must not take the service down with it.
+				metrics.RateLimitDecisions.WithLabelValues(route, tier, "error").Inc()
+				middleware.LoggerFromContext(r.Context(), logger).Warn("rate limiter unavailable, allowing request", "route", route, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w, res)
+			if !res.Allowed {
+				metrics.RateLimitDecisions.WithLabelValues(route, tier, "limited").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				utils.RespondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+
+			metrics.RateLimitDecisions.WithLabelValues(route, tier, "allowed").Inc()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setHeaders writes the RateLimit-* headers from the IETF httpapi draft.
+func setHeaders(w http.ResponseWriter, res Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(res.Limit.Rate)+";w="+strconv.Itoa(ceilSeconds(res.Limit.Period))+";burst="+strconv.Itoa(res.Limit.Burst))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
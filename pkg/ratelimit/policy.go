@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnonymousTier is the tier of clients identified by IP address rather than
+// by a known API key.
+const AnonymousTier = "anonymous"
+
+// Limit allows Rate requests per Period, with bursts of up to Burst requests.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s burst %d", l.Rate, l.Period, l.Burst)
+}
+
+// Policy maps routes and client tiers to limits, and API keys to tiers.
+type Policy struct {
+	limits map[string]Limit
+	tiers  map[string]string
+}
+
+// ParsePolicy parses rules of the form "route:tier=rate/period[/burst]", e.g.
+// "create:anonymous=10/1m", and API keys of the form "key=tier". A route and
+// tier combination without a rule is not limited.
+func ParsePolicy(rules, apiKeys []string) (*Policy, error) {
+	p := &Policy{
+		limits: make(map[string]Limit),
+		tiers:  make(map[string]string),
+	}
+
+	for _, rule := range rules {
+		target, spec, ok := strings.Cut(rule, "=")
+		route, tier, ok2 := strings.Cut(target, ":")
+		if !ok || !ok2 || route == "" || tier == "" {
+			return nil, fmt.Errorf("rate limit rule %q: expected route:tier=rate/period", rule)
+		}
+
+		limit, err := parseLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit rule %q: %w", rule, err)
+		}
+		p.limits[target] = limit
+	}
+
+	for _, entry := range apiKeys {
+		key, tier, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || tier == "" {
+			return nil, fmt.Errorf("rate limit api key entry: expected key=tier")
+		}
+		p.tiers[hashKey(key)] = tier
+	}
+
+	return p, nil
+}
+
+// Tier returns the tier of an API key and a stable identifier for it that does
+// not reveal the key itself. Unknown keys return ok == false.
+func (p *Policy) Tier(apiKey string) (tier, id string, ok bool) {
+	id = hashKey(apiKey)
+	tier, ok = p.tiers[id]
+	return tier, id, ok
+}
+
+// Limit returns the limit for a route and tier.
+func (p *Policy) Limit(route, tier string) (Limit, bool) {
+	limit, ok := p.limits[route+":"+tier]
+	return limit, ok
+}
+
+func parseLimit(spec string) (Limit, error) {
+	parts := strings.Split(spec, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Limit{}, fmt.Errorf("expected rate/period[/burst]")
+	}
+
+	rate, err := strconv.Atoi(parts[0])
+	if err != nil || rate <= 0 {
+		return Limit{}, fmt.Errorf("rate must be a positive integer")
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("period must be a positive duration")
+	}
+
+	burst := rate
+	if len(parts) == 3 {
+		burst, err = strconv.Atoi(parts[2])
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("burst must be a positive integer")
+		}
+	}
+
+	return Limit{Rate: rate, Period: period, Burst: burst}, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy(
+		[]string{"create:anonymous=10/1m/20", "redirect:standard=3000/1m"},
+		[]string{"s3cr3t=standard"},
+	)
+	require.NoError(t, err)
+
+	limit, ok := p.Limit("create", AnonymousTier)
+	require.True(t, ok)
+	assert.Equal(t, Limit{Rate: 10, Period: time.Minute, Burst: 20}, limit)
+
+	limit, ok = p.Limit("redirect", "standard")
+	require.True(t, ok)
+	assert.Equal(t, 3000, limit.Burst, "burst defaults to the rate")
+
+	_, ok = p.Limit("redirect", AnonymousTier)
+	assert.False(t, ok, "routes without a rule are not limited")
+
+	tier, id, ok := p.Tier("s3cr3t")
+	require.True(t, ok)
+	assert.Equal(t, "standard", tier)
+	assert.NotContains(t, id, "s3cr3t")
+
+	_, _, ok = p.Tier("unknown")
+	assert.False(t, ok)
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rules   []string
+		apiKeys []string
+	}{
+		{name: "missing tier", rules: []string{"create=10/1m"}},
+		{name: "missing period", rules: []string{"create:anonymous=10"}},
+		{name: "zero rate", rules: []string{"create:anonymous=0/1m"}},
+		{name: "bad period", rules: []string{"create:anonymous=10/minute"}},
+		{name: "bad burst", rules: []string{"create:anonymous=10/1m/x"}},
+		{name: "api key without tier", apiKeys: []string{"s3cr3t"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParsePolicy(tc.rules, tc.apiKeys)
+			assert.Error(t, err)
+		})
+	}
+}
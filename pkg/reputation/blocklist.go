@@ -0,0 +1,120 @@
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Blocklist blocks URLs by host. Each line of its file is one entry:
+//
+//	evil.example        # this host only
+//	*.evil.example      # the domain and all of its subdomains
+//	0.0.0.0 evil.example  # hosts-file lines are accepted too
+//
+// Blank lines and text after '#' are ignored.
+type Blocklist struct {
+	file watchedFile
+	name string
+
+	mu      sync.RWMutex
+	hosts   map[string]struct{}
+	domains map[string]struct{}
+}
+
+// OpenBlocklist loads a blocklist file.
+func OpenBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{file: watchedFile{path: path}, name: filepath.Base(path)}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload parses the file again if it changed since it was last loaded.
+func (b *Blocklist) Reload() error {
+	modTime, changed, err := b.file.changed()
+	if err != nil || !changed {
+		return err
+	}
+
+	f, err := os.Open(b.file.path)
+	if err != nil {
+		return fmt.Errorf("could not open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	hosts, domains, err := parseBlocklist(f)
+	if err != nil {
+		return fmt.Errorf("could not parse blocklist %s: %w", b.file.path, err)
+	}
+
+	b.mu.Lock()
+	b.hosts, b.domains = hosts, domains
+	b.mu.Unlock()
+	b.file.loaded(modTime)
+	return nil
+}
+
+// Check blocks the URL if its host, or a domain it belongs to, is listed.
+func (b *Blocklist) Check(_ context.Context, rawURL string) (Verdict, error) {
+	u, err := Canonicalize(rawURL)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, ok := b.hosts[u.Host]; ok {
+		return Verdict{Blocked: true, List: b.name, Match: u.Host}, nil
+	}
+	for domain := u.Host; domain != ""; {
+		if _, ok := b.domains[domain]; ok {
+			return Verdict{Blocked: true, List: b.name, Match: "*." + domain}, nil
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok || u.IsIP {
+			break
+		}
+		domain = parent
+	}
+	return Verdict{}, nil
+}
+
+func parseBlocklist(r io.Reader) (hosts, domains map[string]struct{}, err error) {
+	hosts = make(map[string]struct{})
+	domains = make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		entry := fields[0]
+		if len(fields) > 1 {
+			// hosts-file format: the address comes first.
+			if _, err := netip.ParseAddr(entry); err == nil {
+				entry = fields[1]
+			}
+		}
+
+		if domain, ok := strings.CutPrefix(entry, "*."); ok {
+			host, _ := canonicalHost(domain)
+			domains[escape(host)] = struct{}{}
+			continue
+		}
+		host, _ := canonicalHost(entry)
+		hosts[escape(host)] = struct{}{}
+	}
+	return hosts, domains, scanner.Err()
+}
@@ -0,0 +1,281 @@
+package reputation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// controlChars are removed from URLs before anything else is done to them.
+var controlChars = strings.NewReplacer("\t", "", "\r", "", "\n", "")
+
+// CanonicalURL is a URL normalized the way Safe Browsing lists expect, so that
+// trivially different spellings of a URL produce the same lookup expressions.
+type CanonicalURL struct {
+	Scheme string
+	Host   string
+	Path   string
+	// Query includes the leading "?" when the URL had one, even if empty.
+	Query string
+	// IsIP is set when Host is an IPv4 or IPv6 literal.
+	IsIP bool
+}
+
+func (u CanonicalURL) String() string {
+	return u.Scheme + "://" + u.Host + u.Path + u.Query
+}
+
+// Canonicalize normalizes rawURL following the Safe Browsing URL
+// canonicalization rules: control characters and the fragment are dropped,
+// percent-escapes are decoded repeatedly, the host is lower-cased and
+// stripped of stray dots, ports and credentials, numeric IPv4 spellings are
+// turned into dotted quads, "." and ".." path segments are resolved, and
+// finally unsafe characters are escaped again.
+func Canonicalize(rawURL string) (CanonicalURL, error) {
+	s := strings.TrimSpace(controlChars.Replace(rawURL))
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		s = s[:i]
+	}
+	s = unescapeAll(s)
+
+	scheme := "http"
+	if i := strings.Index(s, "://"); i > 0 && isScheme(s[:i]) {
+		scheme, s = strings.ToLower(s[:i]), s[i+3:]
+	}
+
+	authority := s
+	rest := ""
+	if i := strings.IndexAny(s, "/?"); i >= 0 {
+		authority, rest = s[:i], s[i:]
+	}
+
+	path, query := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		path, query = rest[:i], rest[i:]
+	}
+
+	host, isIP := canonicalHost(authority)
+	if host == "" {
+		return CanonicalURL{}, fmt.Errorf("url %q has no host", rawURL)
+	}
+
+	return CanonicalURL{
+		Scheme: scheme,
+		Host:   escape(host),
+		Path:   escape(canonicalPath(path)),
+		Query:  escape(query),
+		IsIP:   isIP,
+	}, nil
+}
+
+// Expressions returns the host suffix / path prefix combinations that are
+// hashed and looked up in a hash-prefix list, most specific first. At most
+// five hosts and six paths are combined.
+func (u CanonicalURL) Expressions() []string {
+	hosts := []string{u.Host}
+	if !u.IsIP {
+		labels := strings.Split(u.Host, ".")
+		if len(labels) > 5 {
+			labels = labels[len(labels)-5:]
+		}
+		for i := 0; i < len(labels)-1; i++ {
+			if suffix := strings.Join(labels[i:], "."); suffix != u.Host {
+				hosts = append(hosts, suffix)
+			}
+		}
+	}
+
+	var paths []string
+	if u.Query != "" {
+		paths = append(paths, u.Path+u.Query)
+	}
+	paths = append(paths, u.Path)
+	prefix := "/"
+	paths = append(paths, prefix)
+	dir := strings.Trim(u.Path[:strings.LastIndexByte(u.Path, '/')+1], "/")
+	if dir != "" {
+		for i, seg := range strings.Split(dir, "/") {
+			if i == 3 {
+				break
+			}
+			prefix += seg + "/"
+			paths = append(paths, prefix)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var exprs []string
+	for _, h := range hosts {
+		for _, p := range paths {
+			expr := h + p
+			if !seen[expr] {
+				seen[expr] = true
+				exprs = append(exprs, expr)
+			}
+		}
+	}
+	return exprs
+}
+
+func isScheme(s string) bool {
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeAll decodes percent-escapes until the string no longer changes.
+// Malformed escapes are left alone.
+func unescapeAll(s string) string {
+	for {
+		next := unescapeOnce(s)
+		if next == s {
+			return s
+		}
+		s = next
+	}
+}
+
+func unescapeOnce(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			n, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			b.WriteByte(byte(n))
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// escape percent-escapes control characters, space, bytes outside ASCII, '#'
+// and '%'.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= 0x20 || c >= 0x7f || c == '#' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func canonicalHost(authority string) (string, bool) {
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		authority = authority[i+1:]
+	}
+
+	if strings.HasPrefix(authority, "[") {
+		if i := strings.IndexByte(authority, ']'); i > 0 {
+			return lowerASCII(authority[:i+1]), true
+		}
+	}
+	if i := strings.LastIndexByte(authority, ':'); i >= 0 {
+		authority = authority[:i]
+	}
+
+	host := lowerASCII(strings.Trim(authority, "."))
+	for strings.Contains(host, "..") {
+		host = strings.ReplaceAll(host, "..", ".")
+	}
+
+	if ip, ok := parseIPv4(host); ok {
+		return ip, true
+	}
+	return host, false
+}
+
+// lowerASCII lower-cases ASCII letters only, leaving other bytes untouched
+// even when they are not valid UTF-8.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// parseIPv4 accepts the loose IPv4 spellings browsers do, such as
+// "3279880203", "0xc3.0x7f.0.11" or "0303.0177.0.013", and returns the
+// dotted-quad form.
+func parseIPv4(host string) (string, bool) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return "", false
+	}
+
+	var ip uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 0, 32)
+		if err != nil || strings.Contains(part, "_") {
+			return "", false
+		}
+		if i < len(parts)-1 {
+			if n > 0xff {
+				return "", false
+			}
+			ip = ip<<8 | n
+			continue
+		}
+		// The last part fills all remaining bytes.
+		remaining := uint(4 - i)
+		if n >= 1<<(8*remaining) {
+			return "", false
+		}
+		ip = ip<<(8*remaining) | n
+	}
+
+	return fmt.Sprintf("%d.%d.%d.%d", byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)), true
+}
+
+// canonicalPath resolves "." and ".." segments and collapses repeated slashes.
+func canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")[1:]
+	var out []string
+	trailing := false
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, seg)
+			continue
+		}
+		if last {
+			trailing = true
+		}
+	}
+
+	p := "/" + strings.Join(out, "/")
+	if trailing && len(out) > 0 {
+		p += "/"
+	}
+	return p
+}
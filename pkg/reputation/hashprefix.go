@@ -0,0 +1,132 @@
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	minPrefixLen = 4
+	maxPrefixLen = sha256.Size
+)
+
+// HashPrefixDB is a local Safe-Browsing-style list of SHA-256 hash prefixes
+// of canonical URL expressions (see CanonicalURL.Expressions). Each line of
+// its file holds one hex-encoded prefix of 4 to 32 bytes; blank lines and
+// text after '#' are ignored.
+//
+// There is no full-hash confirmation round trip, so any prefix match blocks
+// the URL. Lists should use long prefixes or full hashes to keep false
+// positives rare.
+type HashPrefixDB struct {
+	file watchedFile
+	name string
+
+	mu       sync.RWMutex
+	prefixes map[string]struct{}
+	// lengths holds the distinct prefix lengths in the list.
+	lengths []int
+}
+
+// OpenHashPrefixDB loads a hash-prefix file.
+func OpenHashPrefixDB(path string) (*HashPrefixDB, error) {
+	db := &HashPrefixDB{file: watchedFile{path: path}, name: filepath.Base(path)}
+	if err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Reload parses the file again if it changed since it was last loaded.
+func (db *HashPrefixDB) Reload() error {
+	modTime, changed, err := db.file.changed()
+	if err != nil || !changed {
+		return err
+	}
+
+	f, err := os.Open(db.file.path)
+	if err != nil {
+		return fmt.Errorf("could not open hash prefix list: %w", err)
+	}
+	defer f.Close()
+
+	prefixes, lengths, err := parseHashPrefixes(f)
+	if err != nil {
+		return fmt.Errorf("could not parse hash prefix list %s: %w", db.file.path, err)
+	}
+
+	db.mu.Lock()
+	db.prefixes, db.lengths = prefixes, lengths
+	db.mu.Unlock()
+	db.file.loaded(modTime)
+	return nil
+}
+
+// Check blocks the URL if the hash of any of its expressions starts with a
+// listed prefix.
+func (db *HashPrefixDB) Check(_ context.Context, rawURL string) (Verdict, error) {
+	u, err := Canonicalize(rawURL)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, expr := range u.Expressions() {
+		sum := sha256.Sum256([]byte(expr))
+		for _, n := range db.lengths {
+			if _, ok := db.prefixes[string(sum[:n])]; ok {
+				return Verdict{Blocked: true, List: db.name, Match: expr}, nil
+			}
+		}
+	}
+	return Verdict{}, nil
+}
+
+// HashExpression returns the hex-encoded SHA-256 of a URL expression, the
+// form entries of a hash-prefix file are written in.
+func HashExpression(expr string) string {
+	sum := sha256.Sum256([]byte(expr))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseHashPrefixes(r io.Reader) (map[string]struct{}, []int, error) {
+	prefixes := make(map[string]struct{})
+	seen := make(map[int]bool)
+	var lengths []int
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		prefix, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if len(prefix) < minPrefixLen || len(prefix) > maxPrefixLen {
+			return nil, nil, fmt.Errorf("line %d: prefix must be %d to %d bytes", n, minPrefixLen, maxPrefixLen)
+		}
+
+		prefixes[string(prefix)] = struct{}{}
+		if !seen[len(prefix)] {
+			seen[len(prefix)] = true
+			lengths = append(lengths, len(prefix))
+		}
+	}
+	sort.Ints(lengths)
+	return prefixes, lengths, scanner.Err()
+}
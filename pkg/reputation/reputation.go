@@ -0,0 +1,117 @@
+// Package reputation decides whether a destination URL is known to be
+// malicious. All checks run against local data files so that link creation
+// never depends on a third-party service being reachable.
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Verdict is the outcome of a reputation check.
+type Verdict struct {
+	Blocked bool `json:"blocked"`
+	// List names the list that matched, e.g. the blocklist file name.
+	List string `json:"list,omitempty"`
+	// Match is the host or expression that matched.
+	Match string `json:"match,omitempty"`
+}
+
+// Reason describes a blocked verdict for logs and the disabled_note column.
+func (v Verdict) Reason() string {
+	return fmt.Sprintf("malicious destination: %s matched %s", v.Match, v.List)
+}
+
+// Checker looks up the reputation of a URL.
+type Checker interface {
+	Check(ctx context.Context, rawURL string) (Verdict, error)
+}
+
+// Reloader is implemented by checkers backed by files that can change while
+// the service runs.
+type Reloader interface {
+	Reload() error
+}
+
+// Checkers runs several checkers in order and returns the first blocked verdict.
+type Checkers []Checker
+
+func (cs Checkers) Check(ctx context.Context, rawURL string) (Verdict, error) {
+	for _, c := range cs {
+		v, err := c.Check(ctx, rawURL)
+		if err != nil {
+			return Verdict{}, err
+		}
+		if v.Blocked {
+			return v, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// Reload reloads every checker that supports it.
+func (cs Checkers) Reload() error {
+	var errs []error
+	for _, c := range cs {
+		if r, ok := c.(Reloader); ok {
+			errs = append(errs, r.Reload())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Open builds a checker from a host blocklist file and any number of
+// hash-prefix files. It returns nil when no files are configured.
+func Open(blocklistFile string, hashPrefixFiles []string) (Checker, error) {
+	var cs Checkers
+	if blocklistFile != "" {
+		b, err := OpenBlocklist(blocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, b)
+	}
+	for _, path := range hashPrefixFiles {
+		db, err := OpenHashPrefixDB(path)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, db)
+	}
+
+	if len(cs) == 0 {
+		return nil, nil
+	}
+	return cs, nil
+}
+
+// watchedFile remembers the modification time of a data file so it is only
+// parsed again after it changed.
+type watchedFile struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// changed reports whether the file was modified since it was last loaded.
+func (f *watchedFile) changed() (time.Time, bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("could not stat %s: %w", f.path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return info.ModTime(), f.modTime.IsZero() || info.ModTime().After(f.modTime), nil
+}
+
+// loaded records the modification time of the version that was parsed.
+func (f *watchedFile) loaded(modTime time.Time) {
+	f.mu.Lock()
+	f.modTime = modTime
+	f.mu.Unlock()
+}
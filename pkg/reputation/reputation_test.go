@@ -0,0 +1,102 @@
+package reputation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestBlocklist(t *testing.T) {
+	path := writeFile(t, "hosts.txt", `
+# phishing hosts
+login.evil.example
+*.malware.example   # whole domain
+0.0.0.0 ads.tracker.example
+`)
+	b, err := OpenBlocklist(path)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		url     string
+		blocked bool
+	}{
+		{"https://login.evil.example/account", true},
+		{"https://LOGIN.EVIL.EXAMPLE.:443/", true},
+		{"https://evil.example/", false},
+		{"https://other.login.evil.example/", false},
+		{"http://malware.example/", true},
+		{"http://a.b.malware.example/x", true},
+		{"http://notmalware.example/", false},
+		{"http://ads.tracker.example/pixel.gif", true},
+		{"https://example.com/", false},
+	}
+	for _, tc := range testCases {
+		v, err := b.Check(context.Background(), tc.url)
+		require.NoError(t, err)
+		assert.Equal(t, tc.blocked, v.Blocked, tc.url)
+	}
+}
+
+func TestHashPrefixDB(t *testing.T) {
+	full := HashExpression("evil.example/phish/")
+	path := writeFile(t, "prefixes.txt", full+"\n"+HashExpression("bad.example/")[:8]+"\n")
+	db, err := OpenHashPrefixDB(path)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		url     string
+		blocked bool
+	}{
+		{"http://evil.example/phish/", true},
+		{"http://www.evil.example/phish/login.php?x=1", true},
+		{"http://evil.example/%70hish/", true},
+		{"http://evil.example/", false},
+		{"http://bad.example/anything", true},
+		{"http://sub.bad.example/", true},
+		{"http://good.example/", false},
+	}
+	for _, tc := range testCases {
+		v, err := db.Check(context.Background(), tc.url)
+		require.NoError(t, err)
+		assert.Equal(t, tc.blocked, v.Blocked, tc.url)
+	}
+}
+
+func TestHashPrefixDBRejectsBadEntries(t *testing.T) {
+	_, err := OpenHashPrefixDB(writeFile(t, "short.txt", "abcd\n"))
+	assert.Error(t, err)
+
+	_, err = OpenHashPrefixDB(writeFile(t, "nothex.txt", "not a hash\n"))
+	assert.Error(t, err)
+}
+
+func TestReloadPicksUpChanges(t *testing.T) {
+	path := writeFile(t, "hosts.txt", "one.example\n")
+	b, err := OpenBlocklist(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("two.example\n"), 0o644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	require.NoError(t, Checkers{b}.Reload())
+
+	v, err := b.Check(context.Background(), "http://two.example/")
+	require.NoError(t, err)
+	assert.True(t, v.Blocked)
+
+	v, err = b.Check(context.Background(), "http://one.example/")
+	require.NoError(t, err)
+	assert.False(t, v.Blocked)
+}
@@ -0,0 +1,125 @@
+package reputation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/cache"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultRescanBatchSize = 500
+
+// DisabledReason is stored in urls.disabled_reason for links disabled by a rescan.
+const DisabledReason = "malicious"
+
+// Rescanner periodically checks existing links again so that destinations
+// listed after a link was created get disabled.
+type Rescanner struct {
+	Checker Checker
+	Querier sqlc.Querier
+	// Cache is optional; when set, disabled links are evicted from it so the
+	// redirector stops serving them immediately.
+	Cache    *redis.Client
+	Logger   *slog.Logger
+	Interval time.Duration
+	// BatchSize is the number of links read per query. Defaults to 500.
+	BatchSize int32
+	// Disable disables a link a scan found blocked, returning the number of
+	// rows changed. Defaults to Querier.DisableURL; services override it to
+	// record the change in the same transaction, e.g. as an event.
+	Disable func(ctx context.Context, link sqlc.ListEnabledURLsRow, arg sqlc.DisableURLParams) (int64, error)
+}
+
+// Run rescans every Interval until ctx is cancelled. Lists that changed on
+// disk are reloaded before each pass.
+func (s *Rescanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if r, ok := s.Checker.(Reloader); ok {
+			if err := r.Reload(); err != nil {
+				s.Logger.Error("failed to reload reputation lists, using the previous version", "err", err)
+			}
+		}
+
+		start := time.Now()
+		disabled, err := s.Scan(ctx)
+		if err != nil {
+			s.Logger.Error("reputation rescan failed", "err", err, "disabled", disabled)
+			continue
+		}
+		s.Logger.Info("reputation rescan finished", "disabled", disabled, "duration", time.Since(start))
+	}
+}
+
+// Scan checks every enabled link once and disables the ones that are now
+// blocked. It returns the number of links disabled.
+func (s *Rescanner) Scan(ctx context.Context) (int, error) {
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRescanBatchSize
+	}
+
+	disable := s.Disable
+	if disable == nil {
+		disable = func(ctx context.Context, _ sqlc.ListEnabledURLsRow, arg sqlc.DisableURLParams) (int64, error) {
+			return s.Querier.DisableURL(ctx, arg)
+		}
+	}
+
+	disabled := 0
+	var afterID int64
+	for {
+		links, err := s.Querier.ListEnabledURLs(ctx, sqlc.ListEnabledURLsParams{ID: afterID, Limit: batchSize})
+		if err != nil {
+			return disabled, err
+		}
+
+		for _, link := range links {
+			afterID = link.ID
+
+			verdict, err := s.Checker.Check(ctx, link.OriginalUrl)
+			if err != nil {
+				s.Logger.Warn("reputation check failed", "id", link.ID, "err", err)
+				continue
+			}
+			if !verdict.Blocked {
+				continue
+			}
+
+			_, err = disable(ctx, link, sqlc.DisableURLParams{
+				ID:             link.ID,
+				DisabledReason: pgtype.Text{String: DisabledReason, Valid: true},
+				DisabledNote:   pgtype.Text{String: verdict.Reason(), Valid: true},
+			})
+			if err != nil {
+				return disabled, err
+			}
+			disabled++
+			metrics.BlockedURLs.WithLabelValues("rescan", verdict.List).Inc()
+			s.Logger.Warn("disabled link with malicious destination", "id", link.ID, "short_code", link.ShortCode, "list", verdict.List, "match", verdict.Match)
+
+			if s.Cache != nil {
+				if err := s.Cache.Del(ctx, cache.LinkKey(link.Domain.String, link.ShortCode)).Err(); err != nil {
+					s.Logger.Error("failed to evict disabled link from cache", "short_code", link.ShortCode, "err", err)
+				}
+			}
+		}
+
+		if len(links) < int(batchSize) {
+			return disabled, nil
+		}
+	}
+}
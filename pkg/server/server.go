@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Config holds the listen addresses and timeouts of an HTTP server.
+type Config struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// InternalAddr is where the Internal handler of the server is served.
+	InternalAddr string
+	// DrainDelay is how long the server keeps serving after readiness starts
+	// failing, giving Kubernetes time to remove the pod from its endpoints.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests get to finish.
+	ShutdownTimeout time.Duration
+}
+
+// Closer releases a dependency once the HTTP server has stopped.
+type Closer struct {
+	Name  string
+	Close func(ctx context.Context) error
+}
+
+// Server is an http.Server that shuts down gracefully when its context ends.
+type Server struct {
+	cfg    Config
+	http   *http.Server
+	logger *slog.Logger
+
+	// OnShutdown runs as soon as shutdown starts, before the drain delay.
+	// Services use it to make readiness probes fail.
+	OnShutdown func()
+	// Closers run in order after the HTTP server has drained.
+	Closers []Closer
+	// Internal, when set, is served on InternalAddr. Services serve their
+	// metrics and health probes on it, away from the public routes.
+	Internal http.Handler
+}
+
+// New creates a Server serving handler with the timeouts from cfg.
+func New(cfg Config, handler http.Handler, logger *slog.Logger) *Server {
+	s := &Server{cfg: cfg, logger: logger}
+	s.http = s.newHTTPServer(cfg.Addr, handler)
+	return s
+}
+
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelError),
+	}
+}
+
+// Run serves until ctx is cancelled, then fails readiness, waits for the drain
+// delay, lets in-flight requests finish within the shutdown timeout and finally
+// runs the closers in order. The internal server keeps answering probes until
+// the public one has drained. It returns the first error encountered.
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 2)
+	go func() {
+		s.logger.Info("starting server", "addr", s.cfg.Addr)
+		serveErr <- s.http.ListenAndServe()
+	}()
+	var internal *http.Server
+	if s.Internal != nil {
+		internal = s.newHTTPServer(s.cfg.InternalAddr, s.Internal)
+		go func() {
+			s.logger.Info("starting internal server", "addr", s.cfg.InternalAddr)
+			serveErr <- internal.ListenAndServe()
+		}()
+	}
+
+	var errs []error
+
+	select {
+	case err := <-serveErr:
+		// A listener failed before any shutdown was requested.
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("server error: %w", err))
+		}
+		s.http.Close()
+	case <-ctx.Done():
+		s.logger.Info("shutdown requested, draining connections", "drain_delay", s.cfg.DrainDelay)
+
+		if s.OnShutdown != nil {
+			s.OnShutdown()
+		}
+		time.Sleep(s.cfg.DrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+		defer cancel()
+
+		if err := s.http.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("http shutdown: %w", err))
+		}
+	}
+	if internal != nil {
+		internal.Close()
+	}
+
+	errs = append(errs, s.close()...)
+	return errors.Join(errs...)
+}
+
+func (s *Server) close() []error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, c := range s.Closers {
+		if err := c.Close(ctx); err != nil {
+			s.logger.Error("failed to close dependency", "name", c.Name, "err", err)
+			errs = append(errs, fmt.Errorf("close %s: %w", c.Name, err))
+			continue
+		}
+		s.logger.Info("closed dependency", "name", c.Name)
+	}
+	return errs
+}
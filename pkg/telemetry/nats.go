@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var messagingSystemNATS = semconv.MessagingSystemKey.String("nats")
+
+// StartPublish starts a producer span for msg and injects its trace context
+// into the message headers, so consumers can continue the trace.
+func StartPublish(ctx context.Context, msg *nats.Msg) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "publish "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			messagingSystemNATS,
+			semconv.MessagingDestinationName(msg.Subject),
+			attribute.Int("messaging.message.body.size", len(msg.Data)),
+		),
+	)
+
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+
+	return ctx, span
+}
+
+// StartConsume extracts the trace context carried in msg headers and starts a
+// consumer span that joins the publisher's trace.
+func StartConsume(ctx context.Context, msg *nats.Msg) (context.Context, trace.Span) {
+	if msg.Header != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+	}
+
+	return Tracer().Start(ctx, "process "+msg.Subject,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			messagingSystemNATS,
+			semconv.MessagingDestinationName(msg.Subject),
+		),
+	)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParsePrefixes parses CIDR strings such as "10.0.0.0/8". Bare addresses are
+// accepted as single-host prefixes.
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For is
+// only honoured when the request came from a trusted proxy, and is walked from
+// the right so that a client cannot spoof its address by sending the header
+// itself: the first hop that is not a trusted proxy is the client.
+func ClientIP(r *http.Request, trusted []netip.Prefix) string {
+	remote := remoteAddr(r.RemoteAddr)
+	if !remote.IsValid() {
+		return r.RemoteAddr
+	}
+	if !isTrusted(remote, trusted) {
+		return remote.String()
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = addr.Unmap()
+		if !isTrusted(addr, trusted) {
+			return addr.String()
+		}
+		remote = addr
+	}
+	return remote.String()
+}
+
+// ClientCountry returns the country code a trusted proxy or CDN put in
+// header, such as Cloudflare's CF-IPCountry, upper-cased. It returns an empty
+// string when header is empty, the request did not come from a trusted proxy
+// or the value is not a two-letter code; "XX", which Cloudflare sends for
+// unknown countries, counts as none.
+func ClientCountry(r *http.Request, header string, trusted []netip.Prefix) string {
+	if header == "" || !isTrusted(remoteAddr(r.RemoteAddr), trusted) {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(header)))
+	if len(country) != 2 || country == "XX" {
+		return ""
+	}
+	for _, c := range country {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+	}
+	return country
+}
+
+func remoteAddr(hostport string) netip.Addr {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
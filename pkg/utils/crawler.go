@@ -0,0 +1,35 @@
+package utils
+
+import "strings"
+
+// socialCrawlers are User-Agent fragments of the bots that social networks
+// and chat apps send to build link previews.
+var socialCrawlers = []string{
+	"slackbot",
+	"slack-imgproxy",
+	"twitterbot",
+	"facebookexternalhit",
+	"facebot",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"skypeuripreview",
+	"pinterestbot",
+	"redditbot",
+	"mastodon",
+	"bluesky cardyb",
+	"embedly",
+	"iframely",
+}
+
+// IsSocialCrawler reports whether userAgent belongs to a link preview bot.
+func IsSocialCrawler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, crawler := range socialCrawlers {
+		if strings.Contains(ua, crawler) {
+			return true
+		}
+	}
+	return false
+}
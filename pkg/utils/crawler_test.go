@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSocialCrawler(t *testing.T) {
+	testCases := []struct {
+		name      string
+		userAgent string
+		expected  bool
+	}{
+		{
+			name:      "Slack",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			expected:  true,
+		},
+		{
+			name:      "Facebook",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			expected:  true,
+		},
+		{
+			name:      "X",
+			userAgent: "Twitterbot/1.0",
+			expected:  true,
+		},
+		{
+			name:      "Browser",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36",
+			expected:  false,
+		},
+		{
+			name:      "Empty",
+			userAgent: "",
+			expected:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsSocialCrawler(tc.userAgent))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package webhooks delivers link events to the HTTP endpoints workspaces
+// register. The Worker consumes the link lifecycle events and clicks
+// published on NATS, matches them to the endpoints subscribed to them,
+// stores one delivery per endpoint and POSTs it, signed with the endpoint's
+// secret, retrying with exponential backoff until it succeeds or runs out of
+// attempts.
+package webhooks
+
+import (
+	"crypto/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nouvadev/veritas/pkg/events"
+)
+
+// The events endpoints can subscribe to.
+const (
+	EventLinkCreated  = "link.created"
+	EventLinkUpdated  = "link.updated"
+	EventLinkDeleted  = "link.deleted"
+	EventLinkDisabled = "link.disabled"
+	EventLinkClicked  = "link.clicked"
+)
+
+// EventTypes lists every event type, in the order they are documented.
+var EventTypes = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkDisabled, EventLinkClicked}
+
+// IsEventType reports whether t is one of EventTypes.
+func IsEventType(t string) bool {
+	return slices.Contains(EventTypes, t)
+}
+
+// Event is the JSON body of a webhook request. Endpoints that batch events
+// receive an array of them.
+type Event struct {
+	// ID is unique per event, so receivers can drop redeliveries.
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	// Data is a Link for lifecycle events and a Click for link.clicked.
+	Data any `json:"data"`
+}
+
+// Link describes the link a lifecycle event is about, as it is after the
+// change.
+type Link struct {
+	ShortCode      string `json:"short_code"`
+	ShortURL       string `json:"short_url"`
+	Domain         string `json:"domain,omitempty"`
+	OriginalURL    string `json:"original_url"`
+	Title          string `json:"title,omitempty"`
+	Interstitial   bool   `json:"interstitial"`
+	Status         string `json:"status"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+}
+
+// Click describes a visit of a link. Client IP addresses are never sent.
+type Click struct {
+	ShortCode   string `json:"short_code"`
+	Domain      string `json:"domain,omitempty"`
+	OriginalURL string `json:"original_url"`
+	UserAgent   string `json:"user_agent,omitempty"`
+	IsBot       bool   `json:"is_bot"`
+	RequestID   string `json:"request_id,omitempty"`
+}
+
+// NewEvent returns an event of type t carrying data.
+func NewEvent(t string, data any) Event {
+	return Event{ID: events.NewID(), Type: t, CreatedAt: time.Now().UTC(), Data: data}
+}
+
+// NewSecret returns a random signing secret for an endpoint.
+func NewSecret() string {
+	return "whsec_" + strings.ToLower(rand.Text())
+}
@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of webhook requests.
+const (
+	// SignatureHeader carries the request's signature, see Sign.
+	SignatureHeader = "X-Veritas-Signature"
+	// EventHeader names the event type, or "batch" for batched requests.
+	EventHeader = "X-Veritas-Event"
+	// DeliveryHeader lists the IDs of the deliveries a request carries.
+	DeliveryHeader = "X-Veritas-Delivery"
+)
+
+// ErrInvalidSignature is returned by Verify for signatures that do not match
+// or are too old.
+var ErrInvalidSignature = errors.New("webhooks: invalid signature")
+
+// Sign returns the signature header of body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">". The
+// timestamp is signed too, so captured requests cannot be replayed later.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// Verify checks a signature header made by Sign, rejecting signatures older
+// than tolerance. It is what receivers are expected to do.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
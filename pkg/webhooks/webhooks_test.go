@@ -0,0 +1,358 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1"}`)
+	header := Sign("whsec_test", now, body)
+
+	testCases := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		valid  bool
+	}{
+		{name: "Test a valid signature", secret: "whsec_test", header: header, body: body, now: now, valid: true},
+		{name: "Test a signature within the tolerance", secret: "whsec_test", header: header, body: body, now: now.Add(4 * time.Minute), valid: true},
+		{name: "Test an expired signature", secret: "whsec_test", header: header, body: body, now: now.Add(6 * time.Minute)},
+		{name: "Test another secret", secret: "whsec_other", header: header, body: body, now: now},
+		{name: "Test a modified body", secret: "whsec_test", header: header, body: []byte(`{"id":"evt_2"}`), now: now},
+		{name: "Test a malformed header", secret: "whsec_test", header: "v1=abc", body: body, now: now},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.header, tc.body, tc.now, 5*time.Minute)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 8*time.Minute, Backoff(5))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+// fakeStore keeps webhooks and deliveries in memory. Every pending delivery
+// is due when claimed.
+type fakeStore struct {
+	mu         sync.Mutex
+	hooks      []sqlc.Webhook
+	deliveries []sqlc.WebhookDelivery
+	attempts   []sqlc.CreateWebhookAttemptParams
+}
+
+func (s *fakeStore) ListWebhooksForEvent(_ context.Context, arg sqlc.ListWebhooksForEventParams) ([]sqlc.Webhook, error) {
+	var hooks []sqlc.Webhook
+	for _, h := range s.hooks {
+		if h.WorkspaceID == arg.WorkspaceID && slices.Contains(h.Events, arg.Event) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+func (s *fakeStore) CreateWebhookDelivery(_ context.Context, arg sqlc.CreateWebhookDeliveryParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.WebhookID == arg.WebhookID && d.EventID == arg.EventID {
+			return 0, nil
+		}
+	}
+	s.deliveries = append(s.deliveries, sqlc.WebhookDelivery{
+		ID:            int64(len(s.deliveries) + 1),
+		WebhookID:     arg.WebhookID,
+		EventID:       arg.EventID,
+		EventType:     arg.EventType,
+		Payload:       arg.Payload,
+		Status:        StatusPending,
+		NextAttemptAt: arg.NextAttemptAt,
+	})
+	return 1, nil
+}
+
+func (s *fakeStore) ClaimWebhookDeliveries(_ context.Context, arg sqlc.ClaimWebhookDeliveriesParams) ([]sqlc.ClaimWebhookDeliveriesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []sqlc.ClaimWebhookDeliveriesRow
+	for i, d := range s.deliveries {
+		if d.Status != StatusPending || len(rows) == int(arg.MaxCount) {
+			continue
+		}
+		hook := s.hooks[slices.IndexFunc(s.hooks, func(h sqlc.Webhook) bool { return h.ID == d.WebhookID })]
+		s.deliveries[i].NextAttemptAt = arg.LeaseUntil
+		rows = append(rows, sqlc.ClaimWebhookDeliveriesRow{
+			ID:        d.ID,
+			WebhookID: d.WebhookID,
+			EventType: d.EventType,
+			Payload:   d.Payload,
+			Attempts:  d.Attempts,
+			Url:       hook.Url,
+			Secret:    hook.Secret,
+			BatchSize: hook.BatchSize,
+		})
+	}
+	return rows, nil
+}
+
+func (s *fakeStore) MarkWebhookDeliverySucceeded(_ context.Context, arg sqlc.MarkWebhookDeliverySucceededParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &s.deliveries[arg.ID-1]
+	d.Status, d.ResponseStatus = StatusSucceeded, arg.ResponseStatus
+	d.Attempts++
+	return nil
+}
+
+func (s *fakeStore) MarkWebhookDeliveryFailed(_ context.Context, arg sqlc.MarkWebhookDeliveryFailedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &s.deliveries[arg.ID-1]
+	d.Status, d.NextAttemptAt, d.ResponseStatus, d.LastError = arg.Status, arg.NextAttemptAt, arg.ResponseStatus, arg.LastError
+	d.Attempts++
+	return nil
+}
+
+func (s *fakeStore) CreateWebhookAttempt(_ context.Context, arg sqlc.CreateWebhookAttemptParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, arg)
+	return nil
+}
+
+func (s *fakeStore) DeleteFinishedWebhookDeliveries(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// receiver records the requests made to a webhook endpoint and answers them
+// with status.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.status
+	rc.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func newTestWorker(t *testing.T, store *fakeStore, status int) (*Worker, *receiver) {
+	t.Helper()
+	rc := &receiver{status: status}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	for i := range store.hooks {
+		store.hooks[i].Url = srv.URL
+	}
+
+	w := NewWorker(store, Config{Timeout: time.Second, MaxAttempts: 3, Concurrency: 2, AllowPrivateNetworks: true},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.random = func() float64 { return 0.5 }
+	return w, rc
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{hooks: []sqlc.Webhook{
+		{ID: 1, WorkspaceID: 7, Secret: "whsec_a", Events: []string{EventLinkCreated}, SampleRate: 1, BatchSize: 1},
+		{ID: 2, WorkspaceID: 8, Secret: "whsec_b", Events: []string{EventLinkCreated}, SampleRate: 1, BatchSize: 1},
+	}}
+	w, rc := newTestWorker(t, store, http.StatusNoContent)
+
+	ev := NewEvent(EventLinkCreated, Link{ShortCode: "abc", OriginalURL: "https://example.com"})
+	require.NoError(t, w.Enqueue(ctx, 7, ev))
+	// An event relayed again by the outbox is queued once.
+	require.NoError(t, w.Enqueue(ctx, 7, ev))
+	assert.Len(t, store.deliveries, 1)
+	n, err := w.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, rc.requests, 1)
+	req := rc.requests[0]
+	assert.Equal(t, EventLinkCreated, req.Header.Get(EventHeader))
+	assert.Equal(t, "1", req.Header.Get(DeliveryHeader))
+	assert.NoError(t, Verify("whsec_a", req.Header.Get(SignatureHeader), rc.bodies[0], time.Now(), time.Minute))
+
+	var got Event
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &got))
+	assert.Equal(t, ev.ID, got.ID)
+	assert.Equal(t, StatusSucceeded, store.deliveries[0].Status)
+	assert.Len(t, store.attempts, 1)
+}
+
+func TestDeliverRetriesUntilDead(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{hooks: []sqlc.Webhook{
+		{ID: 1, WorkspaceID: 7, Secret: "whsec_a", Events: []string{EventLinkDeleted}, SampleRate: 1, BatchSize: 1},
+	}}
+	w, rc := newTestWorker(t, store, http.StatusInternalServerError)
+
+	require.NoError(t, w.Enqueue(ctx, 7, NewEvent(EventLinkDeleted, Link{ShortCode: "abc"})))
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		_, err := w.DeliverDue(ctx)
+		require.NoError(t, err)
+
+		d := store.deliveries[0]
+		assert.Equal(t, int32(attempt), d.Attempts)
+		assert.Equal(t, int32(http.StatusInternalServerError), d.ResponseStatus.Int32)
+		if attempt < 3 {
+			assert.Equal(t, StatusPending, d.Status)
+			assert.WithinDuration(t, before.Add(Backoff(attempt)*11/10), d.NextAttemptAt, time.Second)
+		} else {
+			assert.Equal(t, StatusDead, d.Status)
+		}
+	}
+
+	// Dead deliveries are not sent again.
+	n, err := w.DeliverDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, rc.requests, 3)
+	assert.Len(t, store.attempts, 3)
+}
+
+func TestDeliverBatches(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{hooks: []sqlc.Webhook{
+		{ID: 1, WorkspaceID: 7, Secret: "whsec_a", Events: []string{EventLinkClicked}, SampleRate: 1, BatchSize: 2, BatchWindowSeconds: 60},
+	}}
+	w, rc := newTestWorker(t, store, http.StatusOK)
+
+	for range 3 {
+		require.NoError(t, w.Enqueue(ctx, 7, NewEvent(EventLinkClicked, Click{ShortCode: "abc"})))
+	}
+	// The events of a window are due together, at its end.
+	due := store.deliveries[0].NextAttemptAt
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), due, 31*time.Second)
+	for _, d := range store.deliveries {
+		assert.Equal(t, due, d.NextAttemptAt)
+	}
+
+	_, err := w.DeliverDue(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, rc.requests, 2)
+	var sizes []int
+	for i, req := range rc.requests {
+		assert.Equal(t, "batch", req.Header.Get(EventHeader))
+		var events []Event
+		require.NoError(t, json.Unmarshal(rc.bodies[i], &events))
+		sizes = append(sizes, len(events))
+	}
+	slices.Sort(sizes)
+	assert.Equal(t, []int{1, 2}, sizes)
+}
+
+func TestBatchDue(t *testing.T) {
+	hook := sqlc.Webhook{ID: 61, BatchSize: 10, BatchWindowSeconds: 60}
+	start := time.Date(2026, 10, 19, 12, 0, 1, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "Test the start of a window", now: start, want: start.Add(time.Minute)},
+		{name: "Test the middle of a window", now: start.Add(30 * time.Second), want: start.Add(time.Minute)},
+		{name: "Test the end of a window", now: start.Add(time.Minute - time.Nanosecond), want: start.Add(time.Minute)},
+		{name: "Test the next window", now: start.Add(time.Minute), want: start.Add(2 * time.Minute)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, batchDue(hook, tc.now))
+		})
+	}
+}
+
+func TestEnqueueSamplesClicks(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{hooks: []sqlc.Webhook{
+		{ID: 1, WorkspaceID: 7, Events: []string{EventLinkClicked, EventLinkCreated}, SampleRate: 0.25, BatchSize: 1},
+		{ID: 2, WorkspaceID: 7, Events: []string{EventLinkClicked}, SampleRate: 0.75, BatchSize: 1},
+	}}
+	w, _ := newTestWorker(t, store, http.StatusOK)
+
+	// The sample draws 0.5, so only the second webhook keeps the click;
+	// lifecycle events are never sampled.
+	require.NoError(t, w.Enqueue(ctx, 7, NewEvent(EventLinkClicked, Click{ShortCode: "abc"})))
+	require.NoError(t, w.Enqueue(ctx, 7, NewEvent(EventLinkCreated, Link{ShortCode: "abc"})))
+	require.NoError(t, w.Enqueue(ctx, 0, NewEvent(EventLinkCreated, Link{ShortCode: "public"})))
+
+	require.Len(t, store.deliveries, 2)
+	assert.Equal(t, int64(2), store.deliveries[0].WebhookID)
+	assert.Equal(t, EventLinkClicked, store.deliveries[0].EventType)
+	assert.Equal(t, int64(1), store.deliveries[1].WebhookID)
+	assert.Equal(t, EventLinkCreated, store.deliveries[1].EventType)
+}
+
+func TestDecodeLinkEvent(t *testing.T) {
+	link := &eventsv1.Link{ShortCode: "abc", WorkspaceId: 7, Status: "active"}
+	disabled := &eventsv1.Link{ShortCode: "abc", WorkspaceId: 7, Status: "disabled", DisabledReason: "abuse"}
+
+	testCases := []struct {
+		name     string
+		subject  string
+		event    proto.Message
+		wantType string
+	}{
+		{name: "Test a created link", subject: events.SubjectLinkCreated, event: events.LinkCreated(link), wantType: EventLinkCreated},
+		{name: "Test an updated link", subject: events.SubjectLinkUpdated, event: events.LinkUpdated(link, "title"), wantType: EventLinkUpdated},
+		{name: "Test a disabled link", subject: events.SubjectLinkUpdated, event: events.LinkUpdated(disabled, "status", "disabled_reason"), wantType: EventLinkDisabled},
+		{name: "Test a restored link", subject: events.SubjectLinkUpdated, event: events.LinkUpdated(link, "status", "disabled_reason"), wantType: EventLinkUpdated},
+		{name: "Test a deleted link", subject: events.SubjectLinkDeleted, event: events.LinkDeleted(link, events.DeletedByMember), wantType: EventLinkDeleted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := proto.Marshal(tc.event)
+			require.NoError(t, err)
+
+			ev, got, err := decodeLinkEvent(&nats.Msg{Subject: tc.subject, Data: data})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantType, ev.Type)
+			assert.True(t, strings.HasPrefix(ev.ID, "evt_"))
+			assert.Equal(t, int64(7), got.GetWorkspaceId())
+			assert.Equal(t, "abc", ev.Data.(Link).ShortCode)
+		})
+	}
+}
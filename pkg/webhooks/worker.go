@@ -0,0 +1,523 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
+	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	"github.com/nouvadev/veritas/pkg/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// queueGroup makes each event go to one worker when several run.
+	queueGroup = "veritas-webhooks"
+	// claimSize is the number of due deliveries claimed per query.
+	claimSize = 100
+	// clickHooksTTL is how long the endpoints subscribed to a workspace's
+	// clicks are cached, sparing a query per click.
+	clickHooksTTL = 30 * time.Second
+	// maxErrorLength bounds the error stored for a failed attempt, which
+	// includes the start of the endpoint's response.
+	maxErrorLength = 500
+	// baseBackoff and maxBackoff bound the delay before a retry.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	userAgent   = "Veritas-Webhooks/1.0"
+)
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// Store is the part of sqlc.Querier the Worker uses.
+type Store interface {
+	ListWebhooksForEvent(ctx context.Context, arg sqlc.ListWebhooksForEventParams) ([]sqlc.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg sqlc.CreateWebhookDeliveryParams) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg sqlc.ClaimWebhookDeliveriesParams) ([]sqlc.ClaimWebhookDeliveriesRow, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg sqlc.MarkWebhookDeliverySucceededParams) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg sqlc.MarkWebhookDeliveryFailedParams) error
+	CreateWebhookAttempt(ctx context.Context, arg sqlc.CreateWebhookAttemptParams) error
+	DeleteFinishedWebhookDeliveries(ctx context.Context, createdAt time.Time) (int64, error)
+}
+
+// Config configures a Worker.
+type Config struct {
+	// Timeout bounds a single request to an endpoint.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// PollInterval is how often due deliveries are looked for.
+	PollInterval time.Duration
+	// Concurrency is the number of requests made at a time.
+	Concurrency int
+	// Retention is how long finished deliveries and their attempts are
+	// kept. They are kept forever when it is 0.
+	Retention time.Duration
+	// AllowPrivateNetworks lets endpoints resolve to internal addresses.
+	// Only tests should set it.
+	AllowPrivateNetworks bool
+}
+
+// Worker turns events into deliveries and sends them.
+type Worker struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+	// random returns a number in [0, 1); it drives sampling and jitter.
+	random func() float64
+
+	mu         sync.Mutex
+	clickHooks map[int64]cachedHooks
+}
+
+type cachedHooks struct {
+	hooks   []sqlc.Webhook
+	expires time.Time
+}
+
+// NewWorker returns a Worker storing deliveries in store.
+func NewWorker(store Store, cfg Config, logger *slog.Logger) *Worker {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = metadata.DialControl
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   cfg.Timeout,
+			ResponseHeaderTimeout: cfg.Timeout,
+			MaxIdleConnsPerHost:   cfg.Concurrency,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		Timeout: cfg.Timeout,
+		// Endpoints must answer themselves; a redirect counts as a failure.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	return &Worker{
+		store:      store,
+		cfg:        cfg,
+		client:     client,
+		logger:     logger,
+		random:     rand.Float64,
+		clickHooks: map[int64]cachedHooks{},
+	}
+}
+
+// Subscribe consumes link lifecycle events and clicks from nc. Workers in
+// other replicas share the subscriptions, so each event is handled once.
+func (w *Worker) Subscribe(nc *nats.Conn) ([]*nats.Subscription, error) {
+	links, err := nc.QueueSubscribe(events.SubjectLinks, queueGroup, w.handleLinkEvent)
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to %s: %w", events.SubjectLinks, err)
+	}
+	clicks, err := nc.QueueSubscribe(events.SubjectRedirect, queueGroup, w.handleClick)
+	if err != nil {
+		links.Unsubscribe()
+		return nil, fmt.Errorf("could not subscribe to %s: %w", events.SubjectRedirect, err)
+	}
+	return []*nats.Subscription{links, clicks}, nil
+}
+
+func (w *Worker) handleLinkEvent(msg *nats.Msg) {
+	ctx, span := telemetry.StartConsume(context.Background(), msg)
+	defer span.End()
+
+	ev, link, err := decodeLinkEvent(msg)
+	if err == nil && link.GetWorkspaceId() != 0 {
+		err = w.Enqueue(ctx, link.GetWorkspaceId(), ev)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.logger.Error("failed to queue webhook deliveries", "subject", msg.Subject, "err", err)
+	}
+}
+
+// decodeLinkEvent turns a lifecycle event into the Event sent to endpoints.
+// Updates that disable a link are sent as link.disabled.
+func decodeLinkEvent(msg *nats.Msg) (Event, *eventsv1.Link, error) {
+	var (
+		ev   Event
+		link *eventsv1.Link
+	)
+	switch msg.Subject {
+	case events.SubjectLinkCreated:
+		m := &eventsv1.LinkCreated{}
+		if err := proto.Unmarshal(msg.Data, m); err != nil {
+			return Event{}, nil, err
+		}
+		ev, link = Event{ID: m.EventId, Type: EventLinkCreated, CreatedAt: m.OccurredAt.AsTime()}, m.Link
+	case events.SubjectLinkUpdated:
+		m := &eventsv1.LinkUpdated{}
+		if err := proto.Unmarshal(msg.Data, m); err != nil {
+			return Event{}, nil, err
+		}
+		ev, link = Event{ID: m.EventId, Type: EventLinkUpdated, CreatedAt: m.OccurredAt.AsTime()}, m.Link
+		if slices.Contains(m.ChangedFields, "status") && m.Link.GetStatus() == "disabled" {
+			ev.Type = EventLinkDisabled
+		}
+	case events.SubjectLinkDeleted:
+		m := &eventsv1.LinkDeleted{}
+		if err := proto.Unmarshal(msg.Data, m); err != nil {
+			return Event{}, nil, err
+		}
+		ev, link = Event{ID: m.EventId, Type: EventLinkDeleted, CreatedAt: m.OccurredAt.AsTime()}, m.Link
+	default:
+		return Event{}, nil, fmt.Errorf("unknown link event subject %s", msg.Subject)
+	}
+
+	ev.Data = Link{
+		ShortCode:      link.GetShortCode(),
+		ShortURL:       link.GetShortUrl(),
+		Domain:         link.GetDomain(),
+		OriginalURL:    link.GetOriginalUrl(),
+		Title:          link.GetTitle(),
+		Interstitial:   link.GetInterstitial(),
+		Status:         link.GetStatus(),
+		DisabledReason: link.GetDisabledReason(),
+	}
+	return ev, link, nil
+}
+
+func (w *Worker) handleClick(msg *nats.Msg) {
+	ctx, span := telemetry.StartConsume(context.Background(), msg)
+	defer span.End()
+
+	event := &eventsv1.RedirectEvent{}
+	if err := proto.Unmarshal(msg.Data, event); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.logger.Error("invalid redirect event", "err", err)
+		return
+	}
+	if event.WorkspaceId == 0 {
+		return
+	}
+
+	err := w.Enqueue(ctx, event.WorkspaceId, NewEvent(EventLinkClicked, Click{
+		ShortCode:   event.ShortCode,
+		Domain:      event.Domain,
+		OriginalURL: event.OriginalUrl,
+		UserAgent:   event.UserAgent,
+		IsBot:       event.IsBot,
+		RequestID:   event.RequestId,
+	}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.logger.Error("failed to queue webhook deliveries", "type", EventLinkClicked, "err", err)
+	}
+}
+
+// Enqueue stores a delivery of ev for every endpoint of the workspace
+// subscribed to its type. Clicks are sampled at each endpoint's rate, and
+// deliveries to batching endpoints wait for the end of their batch window.
+func (w *Worker) Enqueue(ctx context.Context, workspaceID int64, ev Event) error {
+	if workspaceID == 0 {
+		return nil
+	}
+	hooks, err := w.hooksFor(ctx, workspaceID, ev.Type)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, hook := range hooks {
+		if ev.Type == EventLinkClicked && w.random() >= hook.SampleRate {
+			metrics.WebhookEvents.WithLabelValues(ev.Type, "sampled_out").Inc()
+			continue
+		}
+		next := now
+		if hook.BatchSize > 1 {
+			next = batchDue(hook, now)
+		}
+		n, err := w.store.CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+			WebhookID:     hook.ID,
+			EventID:       ev.ID,
+			EventType:     ev.Type,
+			Payload:       payload,
+			NextAttemptAt: next,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// The outbox relayed the event again.
+			metrics.WebhookEvents.WithLabelValues(ev.Type, "duplicate").Inc()
+			continue
+		}
+		metrics.WebhookEvents.WithLabelValues(ev.Type, "queued").Inc()
+	}
+	return nil
+}
+
+// batchDue returns when an event queued at now for a batching hook is sent:
+// at the end of the hook's current batch window, so that every event of a
+// window goes out together. The windows of each hook are offset by its ID,
+// which spreads the batches of different hooks over time.
+func batchDue(hook sqlc.Webhook, now time.Time) time.Time {
+	window := time.Duration(hook.BatchWindowSeconds) * time.Second
+	if window <= 0 {
+		return now
+	}
+	offset := time.Duration(hook.ID) * time.Second % window
+	return now.Add(-offset).Truncate(window).Add(window + offset)
+}
+
+func (w *Worker) hooksFor(ctx context.Context, workspaceID int64, eventType string) ([]sqlc.Webhook, error) {
+	params := sqlc.ListWebhooksForEventParams{WorkspaceID: workspaceID, Event: eventType}
+	if eventType != EventLinkClicked {
+		return w.store.ListWebhooksForEvent(ctx, params)
+	}
+
+	w.mu.Lock()
+	cached, ok := w.clickHooks[workspaceID]
+	w.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.hooks, nil
+	}
+
+	hooks, err := w.store.ListWebhooksForEvent(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.clickHooks[workspaceID] = cachedHooks{hooks: hooks, expires: time.Now().Add(clickHooksTTL)}
+	w.mu.Unlock()
+	return hooks, nil
+}
+
+// Run sends due deliveries every PollInterval and prunes finished ones
+// hourly, until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	poll := time.NewTicker(w.cfg.PollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			for {
+				n, err := w.DeliverDue(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						w.logger.Error("failed to claim webhook deliveries", "err", err)
+					}
+					break
+				}
+				if n < claimSize {
+					break
+				}
+			}
+		case <-prune.C:
+			if w.cfg.Retention <= 0 {
+				continue
+			}
+			n, err := w.store.DeleteFinishedWebhookDeliveries(ctx, time.Now().Add(-w.cfg.Retention))
+			if err != nil {
+				w.logger.Error("failed to prune webhook deliveries", "err", err)
+			} else if n > 0 {
+				w.logger.Info("pruned webhook deliveries", "deleted", n)
+			}
+		}
+	}
+}
+
+// DeliverDue claims the deliveries that are due and sends them, grouping
+// the events of batching endpoints into one request. It returns the number
+// of deliveries claimed.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	rows, err := w.store.ClaimWebhookDeliveries(ctx, sqlc.ClaimWebhookDeliveriesParams{
+		MaxCount: claimSize,
+		// The lease outlasts any request, so claimed deliveries are only
+		// sent again if this worker stops before recording the result.
+		LeaseUntil: time.Now().Add(w.cfg.Timeout + time.Minute),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var batches [][]sqlc.ClaimWebhookDeliveriesRow
+	open := map[int64]int{}
+	for _, row := range rows {
+		if i, ok := open[row.WebhookID]; ok && len(batches[i]) < int(row.BatchSize) {
+			batches[i] = append(batches[i], row)
+			continue
+		}
+		open[row.WebhookID] = len(batches)
+		batches = append(batches, []sqlc.ClaimWebhookDeliveriesRow{row})
+	}
+
+	sem := make(chan struct{}, max(w.cfg.Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			w.deliver(ctx, batch)
+		}()
+	}
+	wg.Wait()
+	return len(rows), nil
+}
+
+// deliver sends the deliveries of one endpoint in a single request and
+// records the outcome for each of them.
+func (w *Worker) deliver(ctx context.Context, batch []sqlc.ClaimWebhookDeliveriesRow) {
+	hook := batch[0]
+	body := hook.Payload
+	eventType := hook.EventType
+	ids := make([]string, len(batch))
+	for i, d := range batch {
+		ids[i] = strconv.FormatInt(d.ID, 10)
+	}
+	if hook.BatchSize > 1 {
+		payloads := make([][]byte, len(batch))
+		for i, d := range batch {
+			payloads[i] = d.Payload
+		}
+		body = append(append([]byte("["), bytes.Join(payloads, []byte(","))...), ']')
+		eventType = "batch"
+	}
+
+	start := time.Now()
+	status, err := w.send(ctx, hook.Url, hook.Secret, eventType, strings.Join(ids, ","), body)
+	took := time.Since(start)
+	if ctx.Err() != nil {
+		// Shutting down: the lease runs out and the deliveries are sent again.
+		return
+	}
+
+	for _, d := range batch {
+		w.record(ctx, d, status, err, took)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, url, secret, eventType, deliveryIDs string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, deliveryIDs)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *Worker) record(ctx context.Context, d sqlc.ClaimWebhookDeliveriesRow, status int, sendErr error, took time.Duration) {
+	logger := w.logger.With("delivery", d.ID, "webhook", d.WebhookID)
+
+	responseStatus := pgtype.Int4{Int32: int32(status), Valid: status != 0}
+	var lastError pgtype.Text
+	if sendErr != nil {
+		msg := sendErr.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		lastError = pgtype.Text{String: strings.ToValidUTF8(msg, ""), Valid: true}
+	}
+
+	err := w.store.CreateWebhookAttempt(ctx, sqlc.CreateWebhookAttemptParams{
+		DeliveryID:     d.ID,
+		ResponseStatus: responseStatus,
+		Error:          lastError,
+		DurationMs:     int32(took.Milliseconds()),
+	})
+	if err != nil {
+		logger.Error("failed to log webhook attempt", "err", err)
+	}
+
+	if sendErr == nil {
+		metrics.WebhookDeliveries.WithLabelValues(StatusSucceeded).Inc()
+		err = w.store.MarkWebhookDeliverySucceeded(ctx, sqlc.MarkWebhookDeliverySucceededParams{ID: d.ID, ResponseStatus: responseStatus})
+	} else {
+		attempts := int(d.Attempts) + 1
+		params := sqlc.MarkWebhookDeliveryFailedParams{
+			ID:             d.ID,
+			Status:         StatusPending,
+			NextAttemptAt:  time.Now().Add(w.jitter(Backoff(attempts))),
+			ResponseStatus: responseStatus,
+			LastError:      lastError,
+		}
+		if attempts >= w.cfg.MaxAttempts {
+			params.Status = StatusDead
+			params.NextAttemptAt = time.Now()
+			metrics.WebhookDeliveries.WithLabelValues(StatusDead).Inc()
+			logger.Warn("webhook delivery is dead", "attempts", attempts, "err", sendErr)
+		} else {
+			metrics.WebhookDeliveries.WithLabelValues("retried").Inc()
+			logger.Info("webhook delivery failed, retrying", "attempts", attempts, "next_attempt_at", params.NextAttemptAt, "err", sendErr)
+		}
+		err = w.store.MarkWebhookDeliveryFailed(ctx, params)
+	}
+	if err != nil {
+		logger.Error("failed to record webhook delivery", "err", err)
+	}
+}
+
+// Backoff returns the delay before retrying a delivery that failed attempts
+// times: 30s doubling with every attempt, up to 6h.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// jitter adds up to 20% to d, so deliveries that failed together do not
+// all retry at the same moment.
+func (w *Worker) jitter(d time.Duration) time.Duration {
+	return d + time.Duration(w.random()*float64(d)/5)
+}
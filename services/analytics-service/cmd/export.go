@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nouvadev/veritas/pkg/analytics"
+	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/nouvadev/veritas/pkg/database"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/domains"
+	"github.com/nouvadev/veritas/pkg/export"
+)
+
+// defaultExportDays is the number of days exported when --from is not given.
+const defaultExportDays = 30
+
+// runExport implements `analytics export`: it writes the raw clicks or
+// rollups of links to a file or stdout, read from the database of the
+// service's configuration a batch at a time. DATABASE_URL may point at a
+// replica to keep exports off the primary.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML configuration file")
+	workspace := fs.String("workspace", "", "slug of the workspace whose links are exported")
+	var links []string
+	fs.Func("link", "short code of a link to export; repeat for a set of links", func(v string) error {
+		links = append(links, v)
+		return nil
+	})
+	domain := fs.String("domain", "", "custom domain of the --link short codes")
+	from := fs.String("from", "", "start of the range, a YYYY-MM-DD date or an RFC 3339 timestamp, included (default 30 days before --to)")
+	to := fs.String("to", "", "end of the range, a YYYY-MM-DD date included or an RFC 3339 timestamp excluded (default today)")
+	data := fs.String("data", string(analytics.DatasetClicks), "data to export: clicks or rollups")
+	granularity := fs.String("granularity", string(analytics.GranularityDay), "granularity of the rollups: minute, hour or day")
+	format := fs.String("format", string(export.FormatCSV), "file format: csv, ndjson or parquet")
+	output := fs.String("output", "", "file to write (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: analytics export (--workspace SLUG | --link CODE...) [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *workspace == "" && len(links) == 0 {
+		return errors.New("--workspace or --link is required")
+	}
+
+	e := analytics.Export{}
+	var err error
+	if e.Dataset, err = analytics.ParseDataset(*data); err != nil {
+		return err
+	}
+	if e.Granularity, err = analytics.ParseGranularity(*granularity); err != nil {
+		return err
+	}
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if e.From, e.To, err = exportRange(*from, *to); err != nil {
+		return err
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	cfg, err := config.Load(config.Options{
+		Service:             "analytics",
+		DefaultPort:         "8083",
+		DefaultInternalPort: "9083",
+		Required:            []string{"DATABASE_URL"},
+		Args:                configArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("loading configuration: %w", err)
+	}
+	dbpool, err := database.ConnectDB(cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer dbpool.Close()
+	queries := sqlc.New(dbpool)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *workspace != "" {
+		ws, err := queries.GetWorkspaceBySlug(ctx, *workspace)
+		if err != nil {
+			return fmt.Errorf("getting workspace %s: %w", *workspace, err)
+		}
+		e.WorkspaceID = ws.ID
+	}
+	if e.LinkIDs, err = exportLinks(ctx, queries, *domain, links, e.WorkspaceID); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	n, err := analytics.NewExporter(queries).Export(ctx, w, f, e)
+	if err == nil && file != nil {
+		err = file.Close()
+	}
+	if err != nil {
+		// Leave no incomplete file behind.
+		if file != nil {
+			os.Remove(file.Name())
+		}
+		return err
+	}
+	log.Printf("Exported %d rows", n)
+	return nil
+}
+
+// exportLinks returns the IDs of the links of the short codes on domain,
+// which must belong to workspaceID unless it is 0.
+func exportLinks(ctx context.Context, queries *sqlc.Queries, domain string, codes []string, workspaceID int64) ([]int64, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	var domainID pgtype.Int8
+	if domain != "" {
+		hostname, err := domains.NormalizeHost(domain)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %s: %w", domain, err)
+		}
+		d, err := queries.GetDomainByHostname(ctx, hostname)
+		if err != nil {
+			return nil, fmt.Errorf("getting domain %s: %w", hostname, err)
+		}
+		domainID = pgtype.Int8{Int64: d.ID, Valid: true}
+	}
+
+	ids := make([]int64, 0, len(codes))
+	for _, code := range codes {
+		link, err := queries.GetURLDetails(ctx, sqlc.GetURLDetailsParams{ShortCode: code, DomainID: domainID})
+		if err != nil {
+			return nil, fmt.Errorf("getting link %s: %w", code, err)
+		}
+		if workspaceID != 0 && link.WorkspaceID.Int64 != workspaceID {
+			return nil, fmt.Errorf("link %s is not in the workspace", code)
+		}
+		ids = append(ids, link.ID)
+	}
+	return ids, nil
+}
+
+// exportRange parses the --from and --to bounds of an export like the
+// ?from= and ?to= of the stats API, without bounding the range.
+func exportRange(from, to string) (time.Time, time.Time, error) {
+	end := analytics.Day(time.Now()).AddDate(0, 0, 1)
+	if to != "" {
+		t, day, err := parseExportBound(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --to: %w", err)
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -defaultExportDays)
+	if from != "" {
+		t, _, err := parseExportBound(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --from: %w", err)
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("--from must be before --to")
+	}
+	return start, end, nil
+}
+
+// parseExportBound parses a YYYY-MM-DD date, reporting true, or an RFC 3339
+// timestamp.
+func parseExportBound(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, errors.New("expected YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	return t.UTC(), false, nil
+}
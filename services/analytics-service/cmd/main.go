@@ -1,19 +1,91 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/analytics"
+	"github.com/nouvadev/veritas/pkg/api"
+	"github.com/nouvadev/veritas/pkg/cache"
+	"github.com/nouvadev/veritas/pkg/config"
+	"github.com/nouvadev/veritas/pkg/database"
+	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/events"
 	eventsv1 "github.com/nouvadev/veritas/pkg/gen/proto/proto/events/v1"
+	"github.com/nouvadev/veritas/pkg/metrics"
+	natsutil "github.com/nouvadev/veritas/pkg/nats"
+	"github.com/nouvadev/veritas/pkg/publisher"
+	"github.com/nouvadev/veritas/pkg/server"
+	"github.com/nouvadev/veritas/pkg/telemetry"
+	"go.opentelemetry.io/otel/codes"
 	"google.golang.org/protobuf/proto"
 )
 
+// queueGroup makes each click event go to one replica when several run.
+const queueGroup = "veritas-analytics"
+
 func main() {
+	// `analytics export` writes the clicks of links to a file and exits.
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			log.Fatalf("Error exporting clicks: %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(config.Options{
+		Service:             "analytics",
+		DefaultPort:         "8083",
+		DefaultInternalPort: "9083",
+		Required:            []string{"DATABASE_URL", "REDIS_URL"},
+		Args:                os.Args[1:],
+	})
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.WriteRedacted(os.Stdout); err != nil {
+			log.Fatalf("Error printing configuration: %v", err)
+		}
+		return
+	}
+
+	shutdownTracing, err := telemetry.Init(context.Background(), cfg.Tracing())
+	if err != nil {
+		log.Fatalf("Error initialising tracing: %v", err)
+	}
+
+	dbpool, err := database.ConnectDB(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	redisClient, err := cache.ConnectRedis(cfg.Redis.URL)
+	if err != nil {
+		log.Fatalf("Error connecting to redis: %v", err)
+	}
+	userAgents, err := analytics.OpenUserAgentParser(cfg.Analytics.UserAgentRulesFile)
+	if err != nil {
+		log.Fatalf("Error loading user agent rules: %v", err)
+	}
+	queries := sqlc.New(dbpool)
+	rollups := cfg.Analytics.Rollups()
+	// Recorded clicks are fanned out to the clients of the live streams.
+	live := analytics.NewLive(cfg.Analytics.LiveBuffer, cfg.Analytics.LiveMaxClients)
+	recorder := analytics.NewRecorder(queries, analytics.NewVisitors(redisClient, cfg.Analytics.VisitorRetention), userAgents, rollups.Lateness)
+
 	// Connect to NATS
-	natsURL := os.Getenv("NATS_URL")
+	natsURL := cfg.NATS.URL
 	if natsURL == "" {
 		natsURL = nats.DefaultURL
 	}
@@ -22,39 +94,131 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error connecting to NATS: %v", err)
 	}
-	defer nc.Close()
 
 	log.Println("Connected to NATS server at", natsURL)
 
-	// Subscribe to the subject
-	subject := "veritas.redirect.success"
-	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+	// Each click is recorded by one replica of the queue group, which then
+	// publishes it again for the live streams of every replica.
+	subject := events.SubjectRedirect
+	sub, err := nc.QueueSubscribe(subject, queueGroup, func(msg *nats.Msg) {
+		// Join the trace started by the redirect that published this event.
+		ctx, span := telemetry.StartConsume(context.Background(), msg)
+		defer span.End()
+
 		event := &eventsv1.RedirectEvent{}
 		if err := proto.Unmarshal(msg.Data, event); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			metrics.ConsumerErrors.WithLabelValues(msg.Subject, "unmarshal").Inc()
 			log.Printf("Error unmarshalling message: %v", err)
 			return
 		}
-		log.Printf(
-			"Received Event: ShortCode=%s, OriginalURL=%s, UserAgent=%s, IP=%s",
-			event.ShortCode,
-			event.OriginalUrl,
-			event.UserAgent,
-			event.IpAddress,
-		)
+
+		_, err := recorder.Record(ctx, event)
+		if errors.Is(err, analytics.ErrDuplicate) {
+			metrics.ConsumerDuplicates.WithLabelValues(msg.Subject).Inc()
+			return
+		}
+		if err != nil {
+			reason := "store"
+			switch {
+			case errors.Is(err, analytics.ErrNoLink):
+				reason = "no_link"
+			case errors.Is(err, analytics.ErrLate):
+				reason = "late"
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			metrics.ConsumerErrors.WithLabelValues(msg.Subject, reason).Inc()
+			log.Printf("Error recording click of %s: %v", event.ShortCode, err)
+			return
+		}
+
+		recorded := &nats.Msg{Subject: events.SubjectClickRecorded, Data: msg.Data}
+		_, pubSpan := telemetry.StartPublish(ctx, recorded)
+		if err := nc.PublishMsg(recorded); err != nil {
+			pubSpan.RecordError(err)
+			pubSpan.SetStatus(codes.Error, err.Error())
+			log.Printf("Error publishing recorded click of %s: %v", event.ShortCode, err)
+		}
+		pubSpan.End()
+		metrics.ConsumerProcessed.WithLabelValues(msg.Subject).Inc()
 	})
 	if err != nil {
 		log.Fatalf("Error subscribing to subject '%s': %v", subject, err)
 	}
-	defer sub.Unsubscribe()
 
 	log.Printf("Subscribed to subject '%s'", subject)
 
-	// Keep the service running
+	// Every replica streams every recorded click to its live clients.
+	_, err = nc.Subscribe(events.SubjectClickRecorded, func(msg *nats.Msg) {
+		event := &eventsv1.RedirectEvent{}
+		if err := proto.Unmarshal(msg.Data, event); err != nil {
+			metrics.ConsumerErrors.WithLabelValues(msg.Subject, "unmarshal").Inc()
+			log.Printf("Error unmarshalling message: %v", err)
+			return
+		}
+		live.Publish(recorder.Click(event))
+	})
+	if err != nil {
+		log.Fatalf("Error subscribing to subject '%s': %v", events.SubjectClickRecorded, err)
+	}
+
+	// Report how far the consumer is behind the messages NATS has delivered.
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			pending, _, err := sub.Pending()
+			if err != nil {
+				continue
+			}
+			metrics.ConsumerPending.WithLabelValues(subject).Set(float64(pending))
+		}
+	}()
+
+	// Serve the stats API, metrics and health probes over HTTP.
+	app := &config.AppConfig{
+		Config:  cfg,
+		Logger:  slog.Default(),
+		DB:      dbpool,
+		Querier: queries,
+		Cache:   redisClient,
+		NATS:    nc,
+		Events:  publisher.Noop{},
+		Tokens:  cfg.OIDC.Validator(),
+		Live:    live,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Roll up clicks into the buckets the stats API reads, and create and
+	// drop the partitions of clicks and rollups.
+	go analytics.NewRollups(dbpool, rollups, slog.Default()).Run(ctx)
+
+	// On shutdown, finish in-flight stats requests, then drain the
+	// subscription so events already delivered to us are recorded before the
+	// stores close.
+	srv := server.New(cfg.HTTP.Server(), api.AnalyticsRoutes(app), slog.Default())
+	srv.Internal = api.InternalRoutes(app)
+	// Live streams would hold the server open until the shutdown timeout, so
+	// they are ended first and clients reconnect to another replica.
+	srv.OnShutdown = func() {
+		app.Draining.Store(true)
+		live.Close()
+	}
+	srv.Closers = []server.Closer{
+		{Name: "nats", Close: func(ctx context.Context) error { return natsutil.Drain(ctx, nc) }},
+		{Name: "postgres", Close: func(context.Context) error { dbpool.Close(); return nil }},
+		{Name: "redis", Close: func(context.Context) error { return redisClient.Close() }},
+		{Name: "tracing", Close: shutdownTracing},
+	}
+
 	log.Println("Analytics service is running. Waiting for events...")
-	// Wait for a signal to exit
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("Analytics service error: %v", err)
+	}
 
 	log.Println("Shutting down analytics service.")
 }
@@ -2,13 +2,24 @@ module github.com/nouvadev/veritas/services/analytics-service
 
 go 1.24.4
 
-require github.com/nats-io/nats.go v1.36.0
-require google.golang.org/protobuf v1.34.2
+require (
+	github.com/nats-io/nats.go v1.36.0
+	github.com/nouvadev/veritas/pkg v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.35.0
+	google.golang.org/protobuf v1.36.5
+)
 
 require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/nats-io/nkeys v0.4.7 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
-	golang.org/x/crypto v0.25.0 // indirect
-	golang.org/x/sys v0.22.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+	go.opentelemetry.io/otel/trace v1.35.0 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 )
+
+replace github.com/nouvadev/veritas/pkg => ../../pkg
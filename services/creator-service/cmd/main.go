@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
 	"log/slog"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
+	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/api"
 	"github.com/nouvadev/veritas/pkg/api/handlers"
+	"github.com/nouvadev/veritas/pkg/cache"
 	"github.com/nouvadev/veritas/pkg/config"
 	"github.com/nouvadev/veritas/pkg/database"
 	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
+	"github.com/nouvadev/veritas/pkg/metadata"
+	natsutil "github.com/nouvadev/veritas/pkg/nats"
+	"github.com/nouvadev/veritas/pkg/outbox"
+	"github.com/nouvadev/veritas/pkg/publisher"
+	"github.com/nouvadev/veritas/pkg/ratelimit"
+	"github.com/nouvadev/veritas/pkg/reputation"
+	"github.com/nouvadev/veritas/pkg/server"
+	"github.com/nouvadev/veritas/pkg/telemetry"
+	"github.com/nouvadev/veritas/pkg/webhooks"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -22,47 +37,156 @@ func main() {
 		logger.Warn("Error loading .env file, continuing without it...")
 	}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		logger.Error("DATABASE_URL environment variable is not set")
+	cfg, err := config.Load(config.Options{
+		Service:             "creator",
+		DefaultPort:         "8081",
+		DefaultInternalPort: "9081",
+		Required:            []string{"DATABASE_URL"},
+		Args:                os.Args[1:],
+	})
+	if err != nil {
+		logger.Error("failed to load configuration", "err", err)
+		os.Exit(1)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.WriteRedacted(os.Stdout); err != nil {
+			logger.Error("failed to print configuration", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	shutdownTracing, err := telemetry.Init(context.Background(), cfg.Tracing())
+	if err != nil {
+		logger.Error("failed to initialise tracing", "err", err)
 		os.Exit(1)
 	}
 
-	dbpool, err := database.ConnectDB(databaseURL)
+	dbpool, err := database.ConnectDB(cfg.Database.URL)
 	if err != nil {
 		logger.Error("failed to connect to database", "err", err)
 		os.Exit(1)
 	}
-	defer dbpool.Close()
 
 	logger.Info("database connection pool established")
 
+	// Redis is optional for the creator: it backs the rate limiter and lets
+	// links disabled by the reputation rescan be evicted from the redirect cache.
+	var redisClient *redis.Client
+	if cfg.Redis.URL != "" {
+		redisClient, err = cache.ConnectRedis(cfg.Redis.URL)
+		if err != nil {
+			logger.Error("failed to connect to redis", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("redis connection established")
+	}
+
+	// NATS is optional too: it carries link events to the webhook worker and
+	// clicks from the redirector. Without it no webhooks are delivered, and
+	// link events wait in the outbox until the creator runs with NATS.
+	var natsConn *nats.Conn
+	if cfg.NATS.URL != "" {
+		natsConn, err = natsutil.ConnectNATS(cfg.NATS.URL)
+		if err != nil {
+			logger.Error("failed to connect to nats", "err", err)
+			os.Exit(1)
+		}
+	} else {
+		logger.Warn("NATS_URL is not set, link events are kept in the outbox and no webhooks are delivered")
+	}
+
 	queries := sqlc.New(dbpool)
 
 	app := &config.AppConfig{
+		Config:  cfg,
 		Logger:  logger,
 		DB:      dbpool,
 		Querier: queries,
+		Cache:   redisClient,
+		NATS:    natsConn,
+		Events:  publisher.Noop{},
+		Tokens:  cfg.OIDC.Validator(),
+	}
+
+	if err := setupRateLimiter(app); err != nil {
+		logger.Error("failed to set up rate limiter", "err", err)
+		os.Exit(1)
+	}
+
+	app.Reputation, err = reputation.Open(cfg.Reputation.BlocklistFile, cfg.Reputation.HashPrefixFiles)
+	if err != nil {
+		logger.Error("failed to load reputation lists", "err", err)
+		os.Exit(1)
 	}
 
-	PORT := os.Getenv("CREATOR_PORT")
-	if PORT == "" {
-		PORT = "8081"
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Metadata.Enabled {
+		app.Metadata = metadata.NewWorker(metadata.NewFetcher(cfg.Metadata.Fetcher()), queries, redisClient, logger,
+			cfg.Metadata.QueueSize, cfg.Metadata.Workers)
+		go app.Metadata.Run(ctx)
 	}
-	logger.Info("starting server", "addr", PORT)
 
-	mux := http.NewServeMux()
+	if app.Reputation != nil && cfg.Reputation.RescanInterval > 0 {
+		rescanner := &reputation.Rescanner{
+			Checker:  app.Reputation,
+			Querier:  queries,
+			Cache:    redisClient,
+			Logger:   logger,
+			Interval: cfg.Reputation.RescanInterval,
+			Disable:  handlers.RescanDisable(app),
+		}
+		go rescanner.Run(ctx)
+	}
 
-	h := handlers.NewHealthcheckHandler(app)
-	u := handlers.NewURLHandler(app)
+	if natsConn != nil {
+		app.Outbox = outbox.NewRelay(dbpool, natsConn, logger)
+		go app.Outbox.Run(ctx)
 
-	mux.HandleFunc("GET /api/healthcheck", h.HealthcheckHandler)
-	mux.HandleFunc("POST /api/create", u.CreateShortURL)
+		worker := webhooks.NewWorker(queries, cfg.Webhooks.Worker(), logger)
+		if _, err := worker.Subscribe(natsConn); err != nil {
+			logger.Error("failed to subscribe webhook worker", "err", err)
+			os.Exit(1)
+		}
+		go worker.Run(ctx)
+	}
 
-	err = http.ListenAndServe(":"+PORT, mux)
-	if err != nil {
+	srv := server.New(cfg.HTTP.Server(), api.CreateURLRoutes(app), logger)
+	srv.Internal = api.InternalRoutes(app)
+	srv.OnShutdown = func() { app.Draining.Store(true) }
+	// NATS drains first so events of in-flight requests are still published.
+	if natsConn != nil {
+		srv.Closers = append(srv.Closers, server.Closer{Name: "nats", Close: func(ctx context.Context) error { return natsutil.Drain(ctx, natsConn) }})
+	}
+	srv.Closers = append(srv.Closers, server.Closer{Name: "postgres", Close: func(context.Context) error { dbpool.Close(); return nil }})
+	if redisClient != nil {
+		srv.Closers = append(srv.Closers, server.Closer{Name: "redis", Close: func(context.Context) error { return redisClient.Close() }})
+	}
+	srv.Closers = append(srv.Closers, server.Closer{Name: "tracing", Close: shutdownTracing})
+
+	if err := srv.Run(ctx); err != nil {
 		logger.Error("server error", "err", err)
 		os.Exit(1)
 	}
+	logger.Info("server stopped")
+}
 
+// setupRateLimiter enables rate limiting when it is configured and Redis is available.
+func setupRateLimiter(app *config.AppConfig) error {
+	if !app.Config.RateLimit.Enabled {
+		return nil
+	}
+	if app.Cache == nil {
+		app.Logger.Warn("rate limiting is enabled but REDIS_URL is not set, requests will not be limited")
+		return nil
+	}
+
+	policy, err := ratelimit.ParsePolicy(app.Config.RateLimit.Rules, app.Config.RateLimit.APIKeys)
+	if err != nil {
+		return err
+	}
+	app.RateLimiter = ratelimit.New(app.Cache, policy)
+	return nil
 }
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
 	"log/slog"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
-	"github.com/nouvadev/veritas/pkg/api/handlers"
+	"github.com/nats-io/nats.go"
+	"github.com/nouvadev/veritas/pkg/api"
 	"github.com/nouvadev/veritas/pkg/cache"
 	"github.com/nouvadev/veritas/pkg/config"
 	"github.com/nouvadev/veritas/pkg/database"
 	sqlc "github.com/nouvadev/veritas/pkg/database/sqlc"
-	"github.com/nouvadev/veritas/pkg/nats"
+	"github.com/nouvadev/veritas/pkg/domains"
+	natsutil "github.com/nouvadev/veritas/pkg/nats"
+	"github.com/nouvadev/veritas/pkg/publisher"
+	"github.com/nouvadev/veritas/pkg/ratelimit"
+	"github.com/nouvadev/veritas/pkg/server"
+	"github.com/nouvadev/veritas/pkg/telemetry"
 )
 
 func main() {
@@ -24,74 +32,124 @@ func main() {
 		logger.Warn("Error loading .env file, continuing without it...")
 	}
 
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		logger.Error("DATABASE_URL environment variable is not set")
+	cfg, err := config.Load(config.Options{
+		Service:             "redirector",
+		DefaultPort:         "8082",
+		DefaultInternalPort: "9082",
+		Required:            []string{"DATABASE_URL", "REDIS_URL"},
+		Args:                os.Args[1:],
+	})
+	if err != nil {
+		logger.Error("failed to load configuration", "err", err)
 		os.Exit(1)
 	}
+	if cfg.PrintConfig {
+		if err := cfg.WriteRedacted(os.Stdout); err != nil {
+			logger.Error("failed to print configuration", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	dbpool, err := database.ConnectDB(dbURL)
+	shutdownTracing, err := telemetry.Init(context.Background(), cfg.Tracing())
 	if err != nil {
-		logger.Error("failed to connect to database", "err", err)
+		logger.Error("failed to initialise tracing", "err", err)
 		os.Exit(1)
 	}
-	logger.Info("database connection pool established")
 
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		logger.Error("REDIS_URL environment variable is not set")
+	dbpool, err := database.ConnectDB(cfg.Database.URL)
+	if err != nil {
+		logger.Error("failed to connect to database", "err", err)
 		os.Exit(1)
 	}
+	logger.Info("database connection pool established")
 
-	redisClient, err := cache.ConnectRedis(redisURL)
+	redisClient, err := cache.ConnectRedis(cfg.Redis.URL)
 	if err != nil {
 		logger.Error("failed to connect to redis", "err", err)
 		os.Exit(1)
 	}
-	defer redisClient.Close()
 
 	logger.Info("redis connection established")
 
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		logger.Error("NATS_URL environment variable is not set")
-		os.Exit(1)
-	}
-
-	natsConn, err := nats.ConnectNATS(natsURL)
-	if err != nil {
-		logger.Error("failed to connect to nats", "err", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Redirects never wait for NATS: click events are buffered, and spooled
+	// to disk while NATS is unreachable. Without NATS_URL they are dropped.
+	var (
+		natsConn *nats.Conn
+		events   *publisher.Buffered
+	)
+	if cfg.NATS.URL != "" {
+		natsConn, err = natsutil.ConnectInBackground(cfg.NATS.URL, logger)
+		if err != nil {
+			logger.Error("failed to connect to nats", "err", err)
+			os.Exit(1)
+		}
+		events, err = publisher.NewBuffered(publisher.NewNATS(natsConn), cfg.Events.Buffer(), logger)
+		if err != nil {
+			logger.Error("failed to open event spool", "err", err)
+			os.Exit(1)
+		}
+		go events.Run(ctx)
+	} else {
+		logger.Warn("NATS_URL is not set, click events are not published")
 	}
-	defer natsConn.Close()
 
 	queries := sqlc.New(dbpool)
 
 	app := &config.AppConfig{
+		Config:  cfg,
 		Logger:  logger,
 		DB:      dbpool,
 		Querier: queries,
 		Cache:   redisClient,
-		NATS:    natsConn,
+		Events:  publisher.Noop{},
+		Domains: domains.NewRegistry(queries, logger),
 	}
-
-	PORT := os.Getenv("REDIRECTOR_PORT")
-	if PORT == "" {
-		PORT = "8082"
+	if events != nil {
+		app.Events = events
 	}
-	logger.Info("starting server", "addr", PORT)
-
-	mux := http.NewServeMux()
 
-	h := handlers.NewHealthcheckHandler(app)
-	u := handlers.NewURLHandler(app)
+	if cfg.RateLimit.Enabled {
+		policy, err := ratelimit.ParsePolicy(cfg.RateLimit.Rules, cfg.RateLimit.APIKeys)
+		if err != nil {
+			logger.Error("failed to set up rate limiter", "err", err)
+			os.Exit(1)
+		}
+		app.RateLimiter = ratelimit.New(redisClient, policy)
+	}
 
-	mux.HandleFunc("GET /healthcheck", h.HealthcheckHandler)
-	mux.HandleFunc("GET /{short_code}", u.RedirectToOriginalURL)
+	// Custom domains are resolved from memory on every redirect. A failed
+	// first load only means they fall back to the default domain until the
+	// next refresh.
+	if err := app.Domains.Refresh(ctx); err != nil {
+		logger.Error("failed to load custom domains", "err", err)
+	}
+	go app.Domains.Run(ctx, cfg.Domains.RefreshInterval)
+
+	// Dependencies are closed in order once in-flight redirects have finished:
+	// buffered redirect events are flushed to NATS, or spooled for the next
+	// start, before the stores go away.
+	srv := server.New(cfg.HTTP.Server(), api.RedirectRoutes(app), logger)
+	srv.Internal = api.InternalRoutes(app)
+	srv.OnShutdown = func() { app.Draining.Store(true) }
+	if natsConn != nil {
+		srv.Closers = append(srv.Closers,
+			server.Closer{Name: "events", Close: events.Close},
+			server.Closer{Name: "nats", Close: func(ctx context.Context) error { return natsutil.Drain(ctx, natsConn) }},
+		)
+	}
+	srv.Closers = append(srv.Closers,
+		server.Closer{Name: "postgres", Close: func(context.Context) error { dbpool.Close(); return nil }},
+		server.Closer{Name: "redis", Close: func(context.Context) error { return redisClient.Close() }},
+		server.Closer{Name: "tracing", Close: shutdownTracing},
+	)
 
-	err = http.ListenAndServe(":"+PORT, mux)
-	if err != nil {
+	if err := srv.Run(ctx); err != nil {
 		logger.Error("server error", "err", err)
 		os.Exit(1)
 	}
+	logger.Info("server stopped")
 }
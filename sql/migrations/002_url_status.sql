@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls
+    ADD COLUMN disabled_at TIMESTAMPTZ,
+    ADD COLUMN disabled_reason TEXT;
+
+-- The reputation rescan walks enabled links in id order.
+CREATE INDEX IF NOT EXISTS idx_urls_enabled_id ON urls(id) WHERE disabled_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_urls_enabled_id;
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_at;
+-- +goose StatementEnd
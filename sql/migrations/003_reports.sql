@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- disabled_reason holds a category (malicious, abuse, legal); disabled_note explains it.
+ALTER TABLE urls ADD COLUMN disabled_note TEXT;
+
+CREATE TABLE reports (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    reporter_ip TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status_id ON reports(status, id);
+CREATE INDEX IF NOT EXISTS idx_reports_url_id ON reports(url_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reports;
+ALTER TABLE urls DROP COLUMN IF EXISTS disabled_note;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- interstitial links show a preview page before redirecting.
+ALTER TABLE urls
+    ADD COLUMN interstitial BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN title TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS interstitial;
+-- +goose StatementEnd
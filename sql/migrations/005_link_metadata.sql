@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- metadata holds what was extracted from the destination page: title,
+-- description, Open Graph and Twitter card tags and the favicon URL.
+ALTER TABLE urls
+    ADD COLUMN metadata JSONB,
+    ADD COLUMN metadata_error TEXT,
+    ADD COLUMN metadata_fetched_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS metadata_fetched_at,
+    DROP COLUMN IF EXISTS metadata_error,
+    DROP COLUMN IF EXISTS metadata;
+-- +goose StatementEnd
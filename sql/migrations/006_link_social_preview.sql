@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per-link overrides of what social networks show when the link is shared.
+ALTER TABLE urls
+    ADD COLUMN preview_title TEXT,
+    ADD COLUMN preview_description TEXT,
+    ADD COLUMN preview_image TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls
+    DROP COLUMN IF EXISTS preview_image,
+    DROP COLUMN IF EXISTS preview_description,
+    DROP COLUMN IF EXISTS preview_title;
+-- +goose StatementEnd
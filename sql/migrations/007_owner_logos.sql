@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- owner names the account a link was created with, taken from its API key.
+ALTER TABLE urls ADD COLUMN owner TEXT;
+
+-- Logos drawn in the middle of the QR codes of an owner's links.
+CREATE TABLE owner_logos (
+    owner TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    image BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS owner_logos;
+ALTER TABLE urls DROP COLUMN IF EXISTS owner;
+-- +goose StatementEnd
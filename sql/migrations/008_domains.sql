@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Custom short domains. A domain serves links once its owner has proven
+-- control of it through a DNS TXT challenge.
+CREATE TABLE domains (
+    id BIGSERIAL PRIMARY KEY,
+    hostname TEXT NOT NULL UNIQUE,
+    owner TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_owner ON domains(owner);
+
+-- Links on the default domain (BASE_URL) have no domain_id.
+ALTER TABLE urls ADD COLUMN domain_id BIGINT REFERENCES domains(id);
+
+-- Short codes are unique per domain rather than globally.
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_short_code_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_domain_short_code ON urls (COALESCE(domain_id, 0), short_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_urls_domain_short_code;
+ALTER TABLE urls ADD CONSTRAINT urls_short_code_key UNIQUE (short_code);
+ALTER TABLE urls DROP COLUMN IF EXISTS domain_id;
+DROP TABLE IF EXISTS domains;
+-- +goose StatementEnd
@@ -0,0 +1,83 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Workspaces are the tenants of a deployment. Links, domains and logos belong
+-- to a workspace, and its members act on them according to their role.
+CREATE TABLE workspaces (
+    id BIGSERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- member is the identity of a caller, the owner named by its API key.
+CREATE TABLE workspace_members (
+    workspace_id BIGINT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    member TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, member)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_member ON workspace_members(member);
+
+-- Every existing owner gets a workspace of its own, named after it.
+INSERT INTO workspaces (slug, name)
+SELECT owner, owner FROM (
+    SELECT owner FROM urls WHERE owner IS NOT NULL
+    UNION SELECT owner FROM domains
+    UNION SELECT owner FROM owner_logos
+) owners;
+
+INSERT INTO workspace_members (workspace_id, member, role)
+SELECT id, slug, 'owner' FROM workspaces;
+
+-- Links created without a workspace have none and stay public.
+ALTER TABLE urls ADD COLUMN workspace_id BIGINT REFERENCES workspaces(id);
+UPDATE urls u SET workspace_id = w.id FROM workspaces w WHERE w.slug = u.owner;
+CREATE INDEX IF NOT EXISTS idx_urls_workspace_id ON urls(workspace_id, id);
+
+-- domains.owner is kept as the member who registered the domain.
+ALTER TABLE domains ADD COLUMN workspace_id BIGINT REFERENCES workspaces(id);
+UPDATE domains d SET workspace_id = w.id FROM workspaces w WHERE w.slug = d.owner;
+ALTER TABLE domains ALTER COLUMN workspace_id SET NOT NULL;
+DROP INDEX IF EXISTS idx_domains_owner;
+CREATE INDEX IF NOT EXISTS idx_domains_workspace_id ON domains(workspace_id);
+
+CREATE TABLE workspace_logos (
+    workspace_id BIGINT PRIMARY KEY REFERENCES workspaces(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    image BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO workspace_logos (workspace_id, content_type, image, updated_at)
+SELECT w.id, l.content_type, l.image, l.updated_at
+FROM owner_logos l
+JOIN workspaces w ON w.slug = l.owner;
+
+DROP TABLE owner_logos;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE owner_logos (
+    owner TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    image BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO owner_logos (owner, content_type, image, updated_at)
+SELECT w.slug, l.content_type, l.image, l.updated_at
+FROM workspace_logos l
+JOIN workspaces w ON w.id = l.workspace_id;
+
+DROP TABLE IF EXISTS workspace_logos;
+DROP INDEX IF EXISTS idx_domains_workspace_id;
+CREATE INDEX IF NOT EXISTS idx_domains_owner ON domains(owner);
+ALTER TABLE domains DROP COLUMN IF EXISTS workspace_id;
+DROP INDEX IF EXISTS idx_urls_workspace_id;
+ALTER TABLE urls DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+-- +goose StatementEnd
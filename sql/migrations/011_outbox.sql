@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Events written in the same transaction as the change they describe, and
+-- deleted once the relay has published them on NATS. headers carries the
+-- trace context of the request that wrote the event.
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    subject TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    headers JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox_events;
+-- +goose StatementEnd
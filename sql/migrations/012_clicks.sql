@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per redirect, written by the analytics service. There is no
+-- foreign key to urls: clicks are written at redirect volume and are kept
+-- for reporting after their link is deleted.
+CREATE TABLE clicks (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL,
+    workspace_id BIGINT,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    request_id TEXT
+);
+
+CREATE INDEX clicks_url_id_clicked_at_idx ON clicks (url_id, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS clicks;
+-- +goose StatementEnd
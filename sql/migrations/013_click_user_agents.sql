@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- What the User-Agent of a click says about its client. Clicks recorded
+-- before these columns existed keep empty values.
+ALTER TABLE clicks
+    ADD COLUMN browser TEXT NOT NULL DEFAULT '',
+    ADD COLUMN browser_version TEXT NOT NULL DEFAULT '',
+    ADD COLUMN os TEXT NOT NULL DEFAULT '',
+    ADD COLUMN device_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bot TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS browser,
+    DROP COLUMN IF EXISTS browser_version,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS device_type,
+    DROP COLUMN IF EXISTS bot;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Where a click came from, worked out from its Referer header and UTM
+-- parameters. Clicks recorded before these columns existed keep empty
+-- values.
+ALTER TABLE clicks
+    ADD COLUMN referrer TEXT NOT NULL DEFAULT '',
+    ADD COLUMN source TEXT NOT NULL DEFAULT '',
+    ADD COLUMN medium TEXT NOT NULL DEFAULT '',
+    ADD COLUMN campaign TEXT NOT NULL DEFAULT '',
+    ADD COLUMN channel TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX clicks_workspace_id_clicked_at_idx ON clicks (workspace_id, clicked_at) WHERE workspace_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS clicks_workspace_id_clicked_at_idx;
+ALTER TABLE clicks
+    DROP COLUMN IF EXISTS referrer,
+    DROP COLUMN IF EXISTS source,
+    DROP COLUMN IF EXISTS medium,
+    DROP COLUMN IF EXISTS campaign,
+    DROP COLUMN IF EXISTS channel;
+-- +goose StatementEnd
@@ -0,0 +1,123 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Raw clicks are partitioned by UTC day so that clicks older than the raw
+-- retention window are dropped a partition at a time. The analytics service
+-- creates the partitions of the coming days and drops expired ones; this
+-- migration creates those holding the clicks recorded so far.
+ALTER TABLE clicks RENAME TO clicks_unpartitioned;
+ALTER SEQUENCE clicks_id_seq RENAME TO clicks_unpartitioned_id_seq;
+ALTER INDEX clicks_pkey RENAME TO clicks_unpartitioned_pkey;
+ALTER INDEX clicks_url_id_clicked_at_idx RENAME TO clicks_unpartitioned_url_id_clicked_at_idx;
+ALTER INDEX clicks_workspace_id_clicked_at_idx RENAME TO clicks_unpartitioned_workspace_id_clicked_at_idx;
+
+CREATE TABLE clicks (
+    id BIGSERIAL,
+    url_id BIGINT NOT NULL,
+    workspace_id BIGINT,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    request_id TEXT,
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    device_type TEXT NOT NULL DEFAULT '',
+    bot TEXT NOT NULL DEFAULT '',
+    referrer TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    medium TEXT NOT NULL DEFAULT '',
+    campaign TEXT NOT NULL DEFAULT '',
+    channel TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (id, clicked_at)
+) PARTITION BY RANGE (clicked_at);
+
+CREATE INDEX clicks_url_id_clicked_at_idx ON clicks (url_id, clicked_at);
+CREATE INDEX clicks_clicked_at_idx ON clicks (clicked_at);
+
+DO $$
+DECLARE
+    d DATE;
+BEGIN
+    FOR d IN
+        SELECT generate_series(
+            least(coalesce(min(clicked_at AT TIME ZONE 'UTC')::date, current_date), current_date),
+            current_date + 3,
+            interval '1 day'
+        )::date
+        FROM clicks_unpartitioned
+    LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF clicks FOR VALUES FROM (%L) TO (%L)',
+            'clicks_p' || to_char(d, 'YYYYMMDD'),
+            d::timestamp AT TIME ZONE 'UTC',
+            (d + 1)::timestamp AT TIME ZONE 'UTC'
+        );
+    END LOOP;
+END
+$$;
+
+INSERT INTO clicks SELECT * FROM clicks_unpartitioned;
+SELECT setval(pg_get_serial_sequence('clicks', 'id'), coalesce(max(id), 0) + 1, false) FROM clicks;
+DROP TABLE clicks_unpartitioned;
+
+-- Clicks counted per link, time bucket and value of a dimension, such as a
+-- browser or a referrer. The "total" dimension has a single empty value.
+-- Each granularity is partitioned by time and kept for its own retention.
+CREATE TABLE click_rollups (
+    granularity TEXT NOT NULL,
+    url_id BIGINT NOT NULL,
+    workspace_id BIGINT,
+    bucket TIMESTAMPTZ NOT NULL,
+    dimension TEXT NOT NULL,
+    value TEXT NOT NULL,
+    clicks BIGINT NOT NULL,
+    bot_clicks BIGINT NOT NULL,
+    PRIMARY KEY (granularity, url_id, dimension, value, bucket)
+) PARTITION BY LIST (granularity);
+
+CREATE TABLE click_rollups_minute PARTITION OF click_rollups FOR VALUES IN ('minute') PARTITION BY RANGE (bucket);
+CREATE TABLE click_rollups_hour PARTITION OF click_rollups FOR VALUES IN ('hour') PARTITION BY RANGE (bucket);
+CREATE TABLE click_rollups_day PARTITION OF click_rollups FOR VALUES IN ('day') PARTITION BY RANGE (bucket);
+
+CREATE INDEX click_rollups_workspace_idx ON click_rollups (granularity, workspace_id, dimension, bucket)
+    WHERE workspace_id IS NOT NULL;
+
+-- How far clicks have been rolled up.
+CREATE TABLE click_rollup_state (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    rolled_up_to TIMESTAMPTZ NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS click_rollup_state;
+DROP TABLE IF EXISTS click_rollups;
+
+CREATE TABLE clicks_unpartitioned (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL,
+    workspace_id BIGINT,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    request_id TEXT,
+    browser TEXT NOT NULL DEFAULT '',
+    browser_version TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    device_type TEXT NOT NULL DEFAULT '',
+    bot TEXT NOT NULL DEFAULT '',
+    referrer TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    medium TEXT NOT NULL DEFAULT '',
+    campaign TEXT NOT NULL DEFAULT '',
+    channel TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO clicks_unpartitioned SELECT * FROM clicks;
+SELECT setval(pg_get_serial_sequence('clicks_unpartitioned', 'id'), coalesce(max(id), 0) + 1, false) FROM clicks_unpartitioned;
+DROP TABLE clicks;
+
+ALTER TABLE clicks_unpartitioned RENAME TO clicks;
+ALTER SEQUENCE clicks_unpartitioned_id_seq RENAME TO clicks_id_seq;
+ALTER INDEX clicks_unpartitioned_pkey RENAME TO clicks_pkey;
+CREATE INDEX clicks_url_id_clicked_at_idx ON clicks (url_id, clicked_at);
+CREATE INDEX clicks_workspace_id_clicked_at_idx ON clicks (workspace_id, clicked_at) WHERE workspace_id IS NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Exports page through the raw clicks of a workspace in time order.
+CREATE INDEX clicks_workspace_id_clicked_at_idx ON clicks (workspace_id, clicked_at, id) WHERE workspace_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS clicks_workspace_id_clicked_at_idx;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Link events are relayed from the outbox at least once, so the same event
+-- may reach the webhooks consumer twice. It is queued once per endpoint.
+DELETE FROM webhook_deliveries d
+USING webhook_deliveries e
+WHERE d.webhook_id = e.webhook_id AND d.event_id = e.event_id AND d.id > e.id;
+
+ALTER TABLE webhook_deliveries ADD CONSTRAINT webhook_deliveries_webhook_id_event_id_key UNIQUE (webhook_id, event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS webhook_deliveries_webhook_id_event_id_key;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Click events are delivered at least once: the redirector replays spooled
+-- events and NATS may redeliver them. A click is stored once per request.
+DELETE FROM clicks c
+USING clicks d
+WHERE c.request_id = d.request_id AND c.clicked_at = d.clicked_at AND c.id > d.id;
+
+ALTER TABLE clicks ADD CONSTRAINT clicks_request_id_clicked_at_key UNIQUE (request_id, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE clicks DROP CONSTRAINT IF EXISTS clicks_request_id_clicked_at_key;
+-- +goose StatementEnd
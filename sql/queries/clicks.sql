@@ -0,0 +1,154 @@
+-- name: CreateClick :execrows
+-- A click delivered again, with the request ID it was stored with, is ignored.
+INSERT INTO clicks (
+    url_id, workspace_id, clicked_at, is_bot, request_id, browser, browser_version, os, device_type, bot,
+    referrer, source, medium, campaign, channel
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (request_id, clicked_at) DO NOTHING;
+
+-- name: TryLockClickRollups :one
+-- Takes the lock that keeps replicas from rolling up clicks at the same
+-- time, until the end of the transaction.
+SELECT pg_try_advisory_xact_lock(hashtext('click_rollups')) AS locked;
+
+-- name: GetClickRollupState :one
+SELECT rolled_up_to FROM click_rollup_state;
+
+-- name: SetClickRollupState :exec
+INSERT INTO click_rollup_state (rolled_up_to) VALUES ($1)
+ON CONFLICT (id) DO UPDATE SET rolled_up_to = EXCLUDED.rolled_up_to;
+
+-- name: RollupClicks :execrows
+-- Counts the raw clicks in [from_time, to_time) into the buckets of
+-- granularity per link and value of every dimension, replacing earlier
+-- counts of those buckets. from_time and to_time must be bucket boundaries.
+INSERT INTO click_rollups (granularity, url_id, workspace_id, bucket, dimension, value, clicks, bot_clicks)
+SELECT sqlc.arg(granularity)::text, c.url_id, max(c.workspace_id),
+    date_trunc(sqlc.arg(granularity)::text, c.clicked_at, 'UTC'),
+    d.dimension, d.value, count(*), count(*) FILTER (WHERE c.is_bot)
+FROM clicks c
+CROSS JOIN LATERAL (VALUES
+    ('total', ''),
+    ('browser', c.browser),
+    ('browser_version', concat_ws(' ', c.browser, NULLIF(c.browser_version, ''))),
+    ('os', c.os),
+    ('device_type', c.device_type),
+    ('bot', c.bot),
+    ('referrer', c.referrer),
+    ('source', c.source),
+    ('medium', c.medium),
+    ('source_medium', CASE WHEN c.source = '' THEN '' ELSE c.source || ' / ' || c.medium END),
+    ('campaign', c.campaign),
+    ('channel', c.channel)
+) AS d (dimension, value)
+WHERE c.clicked_at >= sqlc.arg(from_time) AND c.clicked_at < sqlc.arg(to_time)
+  AND (d.dimension <> 'bot' OR c.is_bot)
+GROUP BY 2, 4, 5, 6
+ON CONFLICT (granularity, url_id, dimension, value, bucket) DO UPDATE
+SET workspace_id = EXCLUDED.workspace_id, clicks = EXCLUDED.clicks, bot_clicks = EXCLUDED.bot_clicks;
+
+-- name: RollupClickRollups :execrows
+-- Adds up the rollups of a finer granularity in [from_time, to_time) into
+-- the buckets of granularity, like RollupClicks.
+INSERT INTO click_rollups (granularity, url_id, workspace_id, bucket, dimension, value, clicks, bot_clicks)
+SELECT sqlc.arg(granularity)::text, url_id, max(workspace_id),
+    date_trunc(sqlc.arg(granularity)::text, bucket, 'UTC'),
+    dimension, value, sum(clicks)::bigint, sum(bot_clicks)::bigint
+FROM click_rollups
+WHERE granularity = sqlc.arg(source_granularity) AND bucket >= sqlc.arg(from_time) AND bucket < sqlc.arg(to_time)
+GROUP BY 2, 4, 5, 6
+ON CONFLICT (granularity, url_id, dimension, value, bucket) DO UPDATE
+SET workspace_id = EXCLUDED.workspace_id, clicks = EXCLUDED.clicks, bot_clicks = EXCLUDED.bot_clicks;
+
+-- name: ListClickTotals :many
+-- The clicks of a link per bucket of granularity in [from_time, to_time).
+SELECT bucket, clicks, bot_clicks
+FROM click_rollups
+WHERE granularity = sqlc.arg(granularity) AND url_id = sqlc.arg(url_id) AND dimension = 'total'
+  AND bucket >= sqlc.arg(from_time) AND bucket < sqlc.arg(to_time)
+ORDER BY bucket;
+
+-- name: SumClicksByValue :many
+-- The clicks of a link in [from_time, to_time) per value of a dimension,
+-- most clicked first, read from the rollups of granularity. Without bots
+-- when exclude_bots is set.
+SELECT value, sum(clicks - CASE WHEN sqlc.arg(exclude_bots)::boolean THEN bot_clicks ELSE 0 END)::bigint AS clicks
+FROM click_rollups
+WHERE granularity = sqlc.arg(granularity) AND url_id = sqlc.arg(url_id) AND dimension = sqlc.arg(dimension)
+  AND bucket >= sqlc.arg(from_time) AND bucket < sqlc.arg(to_time)
+GROUP BY value
+HAVING sum(clicks - CASE WHEN sqlc.arg(exclude_bots)::boolean THEN bot_clicks ELSE 0 END) > 0
+ORDER BY clicks DESC, value
+LIMIT sqlc.arg(max_count);
+
+-- name: SumWorkspaceClicksByValue :many
+-- The clicks of the links of a workspace like SumClicksByValue.
+SELECT value, sum(clicks - CASE WHEN sqlc.arg(exclude_bots)::boolean THEN bot_clicks ELSE 0 END)::bigint AS clicks
+FROM click_rollups
+WHERE granularity = sqlc.arg(granularity) AND workspace_id = sqlc.arg(workspace_id) AND dimension = sqlc.arg(dimension)
+  AND bucket >= sqlc.arg(from_time) AND bucket < sqlc.arg(to_time)
+GROUP BY value
+HAVING sum(clicks - CASE WHEN sqlc.arg(exclude_bots)::boolean THEN bot_clicks ELSE 0 END) > 0
+ORDER BY clicks DESC, value
+LIMIT sqlc.arg(max_count);
+
+-- name: ExportClicks :many
+-- A page of the raw clicks of the links url_ids in [from_time, to_time),
+-- ordered by time and id, from after the click (after_time, after_id).
+SELECT c.id, c.clicked_at, c.url_id, COALESCE(u.short_code, '')::text AS short_code, COALESCE(d.hostname, '')::text AS domain,
+    c.is_bot, c.bot, c.browser, c.browser_version, c.os, c.device_type,
+    c.referrer, c.source, c.medium, c.campaign, c.channel
+FROM clicks c
+LEFT JOIN urls u ON u.id = c.url_id
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE c.url_id = ANY(sqlc.arg(url_ids)::bigint[])
+  AND c.clicked_at >= sqlc.arg(from_time) AND c.clicked_at < sqlc.arg(to_time)
+  AND (c.clicked_at, c.id) > (sqlc.arg(after_time)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY c.clicked_at, c.id
+LIMIT sqlc.arg(max_count);
+
+-- name: ExportWorkspaceClicks :many
+-- A page of the raw clicks of the links of a workspace like ExportClicks.
+SELECT c.id, c.clicked_at, c.url_id, COALESCE(u.short_code, '')::text AS short_code, COALESCE(d.hostname, '')::text AS domain,
+    c.is_bot, c.bot, c.browser, c.browser_version, c.os, c.device_type,
+    c.referrer, c.source, c.medium, c.campaign, c.channel
+FROM clicks c
+LEFT JOIN urls u ON u.id = c.url_id
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE c.workspace_id = sqlc.arg(workspace_id)
+  AND c.clicked_at >= sqlc.arg(from_time) AND c.clicked_at < sqlc.arg(to_time)
+  AND (c.clicked_at, c.id) > (sqlc.arg(after_time)::timestamptz, sqlc.arg(after_id)::bigint)
+ORDER BY c.clicked_at, c.id
+LIMIT sqlc.arg(max_count);
+
+-- name: ExportClickRollups :many
+-- A page of the rollups of granularity of the links url_ids in
+-- [from_time, to_time), in primary key order from after the rollup
+-- (after_url_id, after_dimension, after_value, after_bucket).
+SELECT r.url_id, COALESCE(u.short_code, '')::text AS short_code, COALESCE(d.hostname, '')::text AS domain,
+    r.dimension, r.value, r.bucket, r.clicks, r.bot_clicks
+FROM click_rollups r
+LEFT JOIN urls u ON u.id = r.url_id
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE r.granularity = sqlc.arg(granularity) AND r.url_id = ANY(sqlc.arg(url_ids)::bigint[])
+  AND r.bucket >= sqlc.arg(from_time) AND r.bucket < sqlc.arg(to_time)
+  AND (r.url_id, r.dimension, r.value, r.bucket) >
+    (sqlc.arg(after_url_id)::bigint, sqlc.arg(after_dimension)::text, sqlc.arg(after_value)::text, sqlc.arg(after_bucket)::timestamptz)
+ORDER BY r.url_id, r.dimension, r.value, r.bucket
+LIMIT sqlc.arg(max_count);
+
+-- name: ExportWorkspaceClickRollups :many
+-- A page of the rollups of the links of a workspace like
+-- ExportClickRollups.
+SELECT r.url_id, COALESCE(u.short_code, '')::text AS short_code, COALESCE(d.hostname, '')::text AS domain,
+    r.dimension, r.value, r.bucket, r.clicks, r.bot_clicks
+FROM click_rollups r
+LEFT JOIN urls u ON u.id = r.url_id
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE r.granularity = sqlc.arg(granularity) AND r.workspace_id = sqlc.arg(workspace_id)
+  AND r.bucket >= sqlc.arg(from_time) AND r.bucket < sqlc.arg(to_time)
+  AND (r.url_id, r.dimension, r.value, r.bucket) >
+    (sqlc.arg(after_url_id)::bigint, sqlc.arg(after_dimension)::text, sqlc.arg(after_value)::text, sqlc.arg(after_bucket)::timestamptz)
+ORDER BY r.url_id, r.dimension, r.value, r.bucket
+LIMIT sqlc.arg(max_count);
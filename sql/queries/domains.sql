@@ -0,0 +1,16 @@
+-- name: CreateDomain :one
+INSERT INTO domains (hostname, owner, verification_token, workspace_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetDomainByHostname :one
+SELECT * FROM domains WHERE hostname = $1;
+
+-- name: ListDomainsByWorkspace :many
+SELECT * FROM domains WHERE workspace_id = $1 ORDER BY hostname;
+
+-- name: ListVerifiedDomains :many
+SELECT id, hostname FROM domains WHERE verified_at IS NOT NULL;
+
+-- name: MarkDomainVerified :one
+UPDATE domains SET verified_at = now() WHERE id = $1 RETURNING verified_at;
@@ -0,0 +1,16 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (subject, payload, headers) VALUES ($1, $2, $3);
+
+-- name: ClaimOutboxEvents :many
+-- The oldest events, locked until the transaction ends so relays in other
+-- replicas skip them.
+SELECT * FROM outbox_events
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteOutboxEvents :exec
+DELETE FROM outbox_events WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: CountOutboxEvents :one
+SELECT count(*) FROM outbox_events;
@@ -0,0 +1,20 @@
+-- name: CreateReport :one
+INSERT INTO reports (url_id, reason, details, reporter_ip)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: ListReports :many
+SELECT r.id, u.short_code, u.original_url, r.reason, r.details, r.reporter_ip, r.status,
+       r.created_at, r.resolved_at, u.disabled_at, d.hostname AS domain
+FROM reports r
+JOIN urls u ON u.id = r.url_id
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE r.status = $1 AND r.id < $2
+ORDER BY r.id DESC
+LIMIT $3;
+
+-- name: ResolveReport :execrows
+UPDATE reports SET status = $2, resolved_at = now() WHERE id = $1 AND status = 'open';
+
+-- name: ResolveReportsForURL :exec
+UPDATE reports SET status = $2, resolved_at = now() WHERE url_id = $1 AND status = 'open';
@@ -1,11 +1,51 @@
 -- name: CreateURL :one
-INSERT INTO urls (original_url) VALUES ($1) RETURNING id;
+INSERT INTO urls (original_url, interstitial, title, preview_title, preview_description, preview_image, owner, domain_id, workspace_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id;
 
 -- name: UpdateShortCode :exec
 UPDATE urls SET short_code = $1 WHERE id = $2;
 
 -- name: GetURLByShortCode :one
-SELECT original_url FROM urls WHERE short_code = $1;
+SELECT id, original_url, interstitial,
+       COALESCE(title, metadata->>'og_title', metadata->>'title') AS title,
+       disabled_at, disabled_reason, metadata, preview_title, preview_description, preview_image, workspace_id
+FROM urls WHERE short_code = $1 AND domain_id IS NOT DISTINCT FROM $2;
+
+-- name: GetURLDetails :one
+SELECT * FROM urls WHERE short_code = $1 AND domain_id IS NOT DISTINCT FROM $2;
+
+-- name: UpdateURL :one
+UPDATE urls SET original_url = $2, title = $3, interstitial = $4
+WHERE id = $1
+RETURNING *;
 
 -- name: DeleteURL :exec
-DELETE FROM urls WHERE id = $1;
\ No newline at end of file
+DELETE FROM urls WHERE id = $1;
+
+-- name: ListEnabledURLs :many
+SELECT u.id, u.short_code, u.original_url, u.title, u.interstitial, u.owner, d.hostname AS domain, u.workspace_id
+FROM urls u
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE u.disabled_at IS NULL AND u.short_code IS NOT NULL AND u.id > $1
+ORDER BY u.id
+LIMIT $2;
+
+-- name: ListURLsByWorkspace :many
+SELECT u.id, u.short_code, u.original_url, u.title, u.owner, u.created_at, u.disabled_at, d.hostname AS domain
+FROM urls u
+LEFT JOIN domains d ON d.id = u.domain_id
+WHERE u.workspace_id = $1 AND u.id < $2
+ORDER BY u.id DESC
+LIMIT $3;
+
+-- name: DisableURL :execrows
+UPDATE urls SET disabled_at = now(), disabled_reason = $2, disabled_note = $3
+WHERE id = $1 AND disabled_at IS NULL;
+
+-- name: RestoreURL :execrows
+UPDATE urls SET disabled_at = NULL, disabled_reason = NULL, disabled_note = NULL
+WHERE id = $1 AND disabled_at IS NOT NULL;
+
+-- name: UpdateURLMetadata :exec
+UPDATE urls SET metadata = $2, metadata_error = $3, metadata_fetched_at = now() WHERE id = $1;
\ No newline at end of file
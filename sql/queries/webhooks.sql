@@ -0,0 +1,82 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (workspace_id, url, secret, events, sample_rate, batch_size, batch_window_seconds, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id = $1 AND workspace_id = $2;
+
+-- name: ListWebhooksByWorkspace :many
+SELECT * FROM webhooks WHERE workspace_id = $1 ORDER BY id;
+
+-- name: ListWebhooksForEvent :many
+SELECT * FROM webhooks
+WHERE workspace_id = sqlc.arg(workspace_id) AND sqlc.arg(event)::text = ANY(events)
+ORDER BY id;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2;
+
+-- name: CreateWebhookDelivery :execrows
+-- An event already queued for the endpoint, relayed again, is ignored.
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+-- Due deliveries are leased until lease_until, so a worker that dies while
+-- sending them only delays their next attempt.
+WITH due AS (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at, id
+    LIMIT sqlc.arg(max_count)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg(lease_until)
+FROM due, webhooks w
+WHERE d.id = due.id AND w.id = d.webhook_id
+RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret, w.batch_size;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, last_attempt_at = now(), delivered_at = now(),
+    response_status = $2, last_error = NULL
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+-- status is pending while attempts remain and dead afterwards.
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, last_attempt_at = now(), next_attempt_at = $3,
+    response_status = $4, last_error = $5
+WHERE id = $1;
+
+-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (delivery_id, response_status, error, duration_ms)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_id, event_type, status, attempts, next_attempt_at, last_attempt_at,
+       response_status, last_error, delivered_at, created_at
+FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg(webhook_id) AND id < sqlc.arg(before)
+  AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status))
+ORDER BY id DESC
+LIMIT sqlc.arg(max_count);
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2;
+
+-- name: ListWebhookAttempts :many
+SELECT * FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id;
+
+-- name: RedeliverWebhookDelivery :execrows
+-- Failed and delivered events start over with a fresh set of attempts; the
+-- log of earlier attempts is kept.
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+WHERE id = $1 AND webhook_id = $2 AND status <> 'pending';
+
+-- name: DeleteFinishedWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries WHERE status <> 'pending' AND created_at < $1;
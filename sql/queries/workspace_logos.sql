@@ -0,0 +1,11 @@
+-- name: GetWorkspaceLogo :one
+SELECT * FROM workspace_logos WHERE workspace_id = $1;
+
+-- name: UpsertWorkspaceLogo :exec
+INSERT INTO workspace_logos (workspace_id, content_type, image)
+VALUES ($1, $2, $3)
+ON CONFLICT (workspace_id) DO UPDATE
+SET content_type = EXCLUDED.content_type, image = EXCLUDED.image, updated_at = now();
+
+-- name: DeleteWorkspaceLogo :execrows
+DELETE FROM workspace_logos WHERE workspace_id = $1;
@@ -0,0 +1,58 @@
+-- name: CreateWorkspace :one
+-- The creator becomes the first owner of the workspace.
+WITH w AS (
+    INSERT INTO workspaces (slug, name) VALUES ($1, $2) RETURNING *
+), m AS (
+    INSERT INTO workspace_members (workspace_id, member, role)
+    SELECT id, $3, 'owner' FROM w
+)
+SELECT * FROM w;
+
+-- name: GetMembership :one
+SELECT w.id, w.slug, w.name, m.member, m.role
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE w.slug = $1 AND m.member = $2;
+
+-- name: GetMembershipByID :one
+SELECT w.id, w.slug, w.name, m.member, m.role
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE w.id = $1 AND m.member = $2;
+
+-- name: GetWorkspaceBySlug :one
+SELECT * FROM workspaces WHERE slug = $1;
+
+-- name: GetWorkspaceByID :one
+SELECT * FROM workspaces WHERE id = $1;
+
+-- name: ListWorkspacesByMember :many
+SELECT w.id, w.slug, w.name, w.created_at, m.role
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE m.member = $1
+ORDER BY w.slug;
+
+-- name: GetWorkspaceMember :one
+SELECT * FROM workspace_members WHERE workspace_id = $1 AND member = $2;
+
+-- name: ListWorkspaceMembers :many
+SELECT * FROM workspace_members WHERE workspace_id = $1 ORDER BY member;
+
+-- name: UpsertWorkspaceMember :execrows
+-- The last owner of a workspace cannot be demoted.
+INSERT INTO workspace_members (workspace_id, member, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (workspace_id, member) DO UPDATE
+SET role = EXCLUDED.role
+WHERE workspace_members.role <> 'owner' OR EXCLUDED.role = 'owner'
+   OR (SELECT count(*) FROM workspace_members o
+       WHERE o.workspace_id = EXCLUDED.workspace_id AND o.role = 'owner') > 1;
+
+-- name: DeleteWorkspaceMember :execrows
+-- The last owner of a workspace cannot be removed.
+DELETE FROM workspace_members m
+WHERE m.workspace_id = $1 AND m.member = $2
+  AND (m.role <> 'owner'
+       OR (SELECT count(*) FROM workspace_members o
+           WHERE o.workspace_id = $1 AND o.role = 'owner') > 1);